@@ -0,0 +1,114 @@
+// Package diagnostics defines how the packages of this module
+// (fontconfig, pango) report the problems which are not
+// returned as errors, like a missing font for a script or an invalid
+// font file found while scanning.
+//
+// By default, nothing is reported: a `Sink` may be set per package
+// (see for instance pango.SetDiagnostics), or per object (fontconfig.Config or fcfonts.FontMap),
+// which then takes precedence over the package setting.
+package diagnostics
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Category identifies the kind of a problem.
+type Category string
+
+const (
+	// An invalid value is ignored (type mismatch, invalid language tag, font size...).
+	InvalidValue Category = "invalid-value"
+	// A problem was found in a configuration.
+	InvalidConfig Category = "invalid-config"
+	// A configuration rule is not supported and ignored.
+	UnsupportedRule Category = "unsupported-rule"
+	// A font file could not be loaded.
+	InvalidFont Category = "invalid-font"
+	// A font directory is missing.
+	MissingFontDir Category = "missing-font-dir"
+	// No font was found, typically for a script.
+	MissingFont Category = "missing-font"
+	// A text could not be shaped correctly.
+	ShapingFailure Category = "shaping-failure"
+	// The API was not used as expected.
+	InvalidArgument Category = "invalid-argument"
+	// An unexpected internal state was detected, and fixed.
+	Internal Category = "internal"
+)
+
+// Diagnostic is a problem reported to a `Sink`.
+// Only `Package`, `Category` and `Message` are always set.
+type Diagnostic struct {
+	Package  string // the package reporting the problem, like "fontconfig" or "pango"
+	Category Category
+	Message  string // human friendly description
+
+	Script string // the script involved, if any
+	Font   string // the font involved (description or family), if any
+	File   string // the file involved (font or configuration file), if any
+}
+
+// String returns a log friendly description of the problem.
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s: %s", d.Package, d.Category, d.Message)
+	for _, field := range [...][2]string{{"script", d.Script}, {"font", d.Font}, {"file", d.File}} {
+		if field[1] != "" {
+			fmt.Fprintf(&b, " (%s: %s)", field[0], field[1])
+		}
+	}
+	return b.String()
+}
+
+// Sink receives diagnostics. Implementations must be
+// safe for concurrent use.
+type Sink interface {
+	Report(d Diagnostic)
+}
+
+// Func is a convenience `Sink` calling the function.
+type Func func(d Diagnostic)
+
+func (f Func) Report(d Diagnostic) { f(d) }
+
+type logger struct{ l *log.Logger }
+
+func (l logger) Report(d Diagnostic) { l.l.Println(d) }
+
+// Logger returns a `Sink` writing the diagnostics to `l`,
+// or to the standard logger if `l` is nil.
+func Logger(l *log.Logger) Sink {
+	if l == nil {
+		l = log.Default()
+	}
+	return logger{l}
+}
+
+// Var stores a `Sink`, and is safe for concurrent use.
+// It is used for package level settings.
+// The zero value is ready to use, and reports nothing.
+type Var struct {
+	v atomic.Value // sinkHolder
+}
+
+// atomic.Value requires a consistent concrete type
+type sinkHolder struct{ sink Sink }
+
+// Set replaces the stored sink. `sink` may be nil to disable reporting.
+func (v *Var) Set(sink Sink) { v.v.Store(sinkHolder{sink}) }
+
+// Get returns the stored sink, or nil.
+func (v *Var) Get() Sink {
+	h, _ := v.v.Load().(sinkHolder)
+	return h.sink
+}
+
+// Report sends `d` to the stored sink, if any.
+func (v *Var) Report(d Diagnostic) {
+	if sink := v.Get(); sink != nil {
+		sink.Report(d)
+	}
+}
@@ -0,0 +1,50 @@
+package diagnostics
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestVar(t *testing.T) {
+	var v Var
+	v.Report(Diagnostic{Message: "ignored"}) // no sink
+
+	var (
+		mu  sync.Mutex
+		got []Diagnostic
+	)
+	v.Set(Func(func(d Diagnostic) {
+		mu.Lock()
+		got = append(got, d)
+		mu.Unlock()
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v.Report(Diagnostic{Package: "test", Category: MissingFont, Script: "Latin"})
+		}()
+	}
+	wg.Wait()
+	if len(got) != 10 {
+		t.Fatalf("expected 10 diagnostics, got %d", len(got))
+	}
+
+	var buf bytes.Buffer
+	v.Set(Logger(log.New(&buf, "", 0)))
+	v.Report(Diagnostic{Package: "test", Category: InvalidFont, Message: "invalid file", File: "a.ttf"})
+	if exp := "test: invalid-font: invalid file (file: a.ttf)\n"; buf.String() != exp {
+		t.Fatalf("expected %q, got %q", exp, buf.String())
+	}
+
+	v.Set(nil)
+	v.Report(Diagnostic{Message: "ignored"})
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Fatal("unexpected report")
+	}
+}
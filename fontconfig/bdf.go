@@ -0,0 +1,720 @@
+package fontconfig
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/bitmap"
+)
+
+// This file implements a parser for the BDF bitmap font format,
+// described in https://www.adobe.com/content/dam/acom/en/devnet/font/pdfs/5005.BDF_Spec.pdf.
+// As the bdftopcf tool does, the BDF fonts are converted
+// to the PCF format, so that they are handled by the `bitmap` package,
+// and described by the same patterns as PCF fonts.
+
+const bdfHeader = "STARTFONT"
+
+// implementation limits protecting against malicious files
+const (
+	bdfMaxGlyphs     = 65536
+	bdfMaxProperties = 512
+	bdfMaxGlyphSize  = 1024 // in pixels, for width and height
+)
+
+type bdfProperty struct {
+	name  string
+	value bitmap.Property
+}
+
+type bdfGlyph struct {
+	name     string
+	encoding int32 // -1 for unencoded glyphs
+	sWidth   int32
+	dWidth   int16
+	// bounding box
+	width, height, xOff, yOff int16
+
+	bitmap []byte // rows padded to the byte, most significant bit first
+}
+
+type bdfFont struct {
+	name                  string
+	pointSize, xRes, yRes int32
+	bbWidth, bbHeight     int16
+	bbXOff, bbYOff        int16
+	properties            []bdfProperty
+	glyphs                []bdfGlyph
+}
+
+func (f *bdfFont) intProperty(name string) (int32, bool) {
+	for _, prop := range f.properties {
+		if prop.name == name {
+			v, ok := prop.value.(bitmap.Int)
+			return int32(v), ok
+		}
+	}
+	return 0, false
+}
+
+func (f *bdfFont) hasProperty(name string) bool {
+	for _, prop := range f.properties {
+		if prop.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadBDF implements fonts.FontLoader for BDF files,
+// optionnaly gzip compressed.
+func loadBDF(file fonts.Resource) (fonts.Faces, error) {
+	pcf, err := readBDF(file)
+	if err != nil {
+		return nil, err
+	}
+	return bitmap.Load(bytes.NewReader(pcf))
+}
+
+// readBDF parses a BDF file and returns its PCF equivalent.
+func readBDF(file fonts.Resource) ([]byte, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var r io.Reader
+	r, err := gzip.NewReader(file)
+	if err != nil { // not a gzip file: read from the plain file
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		r = file
+	}
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(bdfHeader))
+	if err != nil || string(header) != bdfHeader {
+		return nil, errors.New("not a BDF file")
+	}
+
+	font, err := parseBDF(br)
+	if err != nil {
+		return nil, err
+	}
+	return font.toPCF(), nil
+}
+
+// bdfScanner reads the lines of a BDF file,
+// skipping comments and empty lines
+type bdfScanner struct {
+	src    *bufio.Scanner
+	lineNb int
+
+	keyword string
+	args    string
+}
+
+// next returns false at the end of the input
+func (sc *bdfScanner) next() bool {
+	for sc.src.Scan() {
+		sc.lineNb++
+		line := strings.TrimSpace(sc.src.Text())
+		if line == "" {
+			continue
+		}
+		sc.keyword, sc.args = line, ""
+		if i := strings.IndexAny(line, " \t"); i != -1 {
+			sc.keyword, sc.args = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if sc.keyword == "COMMENT" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (sc *bdfScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid BDF file (line %d): %s", sc.lineNb, fmt.Sprintf(format, args...))
+}
+
+// checkBox validates a bounding box (width, height, x offset, y offset),
+// so that it may be safely stored as int16
+func (sc *bdfScanner) checkBox(vs []int32) error {
+	if vs[0] < 0 || vs[0] > bdfMaxGlyphSize || vs[1] < 0 || vs[1] > bdfMaxGlyphSize {
+		return sc.errorf("unsupported glyph size %dx%d", vs[0], vs[1])
+	}
+	if vs[2] < -bdfMaxGlyphSize || vs[2] > bdfMaxGlyphSize || vs[3] < -bdfMaxGlyphSize || vs[3] > bdfMaxGlyphSize {
+		return sc.errorf("unsupported glyph offset %d %d", vs[2], vs[3])
+	}
+	return nil
+}
+
+// ints parses the arguments of the current line
+// as `n` integers.
+func (sc *bdfScanner) ints(n int) ([]int32, error) {
+	fields := strings.Fields(sc.args)
+	if len(fields) < n {
+		return nil, sc.errorf("expected %d arguments for %s, got %q", n, sc.keyword, sc.args)
+	}
+	out := make([]int32, n)
+	for i := range out {
+		v, err := strconv.ParseInt(fields[i], 10, 32)
+		if err != nil {
+			return nil, sc.errorf("invalid argument for %s: %s", sc.keyword, err)
+		}
+		out[i] = int32(v)
+	}
+	return out, nil
+}
+
+func parseBDF(r io.Reader) (*bdfFont, error) {
+	sc := bdfScanner{src: bufio.NewScanner(r)}
+	if !sc.next() || sc.keyword != bdfHeader {
+		return nil, errors.New("not a BDF file")
+	}
+
+	var (
+		out     bdfFont
+		hasSize bool
+	)
+	for sc.next() {
+		switch sc.keyword {
+		case "FONT":
+			out.name = sc.args
+		case "SIZE":
+			vs, err := sc.ints(3)
+			if err != nil {
+				return nil, err
+			}
+			out.pointSize, out.xRes, out.yRes = vs[0], vs[1], vs[2]
+			hasSize = true
+		case "FONTBOUNDINGBOX":
+			vs, err := sc.ints(4)
+			if err != nil {
+				return nil, err
+			}
+			if err := sc.checkBox(vs); err != nil {
+				return nil, err
+			}
+			out.bbWidth, out.bbHeight, out.bbXOff, out.bbYOff = int16(vs[0]), int16(vs[1]), int16(vs[2]), int16(vs[3])
+		case "STARTPROPERTIES":
+			if err := out.parseProperties(&sc); err != nil {
+				return nil, err
+			}
+		case "CHARS":
+			vs, err := sc.ints(1)
+			if err != nil {
+				return nil, err
+			}
+			if vs[0] < 0 || vs[0] > bdfMaxGlyphs {
+				return nil, sc.errorf("number of glyphs (%d) exceeds implementation limit (%d)", vs[0], bdfMaxGlyphs)
+			}
+			out.glyphs = make([]bdfGlyph, 0, vs[0])
+		case "STARTCHAR":
+			if len(out.glyphs) >= bdfMaxGlyphs {
+				return nil, sc.errorf("number of glyphs exceeds implementation limit (%d)", bdfMaxGlyphs)
+			}
+			glyph, err := out.parseGlyph(&sc)
+			if err != nil {
+				return nil, err
+			}
+			out.glyphs = append(out.glyphs, glyph)
+		case "ENDFONT":
+			if !hasSize {
+				return nil, sc.errorf("missing SIZE")
+			}
+			if len(out.glyphs) == 0 {
+				return nil, sc.errorf("no glyphs")
+			}
+			return &out, nil
+		}
+		// other keywords (METRICSSET, SWIDTH, ...) are ignored
+	}
+	if err := sc.src.Err(); err != nil {
+		return nil, err
+	}
+	return nil, sc.errorf("missing ENDFONT")
+}
+
+// parseBDFPropertyValue interprets quoted values as strings (atoms)
+// and other values as integers
+func parseBDFPropertyValue(s string) (bitmap.Property, bool) {
+	if strings.HasPrefix(s, `"`) {
+		if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+			return nil, false
+		}
+		return bitmap.Atom(strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)), true
+	}
+	v, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return nil, false
+	}
+	return bitmap.Int(v), true
+}
+
+func (f *bdfFont) parseProperties(sc *bdfScanner) error {
+	for sc.next() {
+		if sc.keyword == "ENDPROPERTIES" {
+			return nil
+		}
+		if len(f.properties) >= bdfMaxProperties {
+			return sc.errorf("number of properties exceeds implementation limit (%d)", bdfMaxProperties)
+		}
+		value, ok := parseBDFPropertyValue(sc.args)
+		if !ok {
+			return sc.errorf("invalid value for property %s: %s", sc.keyword, sc.args)
+		}
+		f.properties = append(f.properties, bdfProperty{name: sc.keyword, value: value})
+	}
+	return sc.errorf("missing ENDPROPERTIES")
+}
+
+func (f *bdfFont) parseGlyph(sc *bdfScanner) (bdfGlyph, error) {
+	out := bdfGlyph{
+		name:     sc.args,
+		encoding: -1,
+		// default to the font bounding box
+		width: f.bbWidth, height: f.bbHeight, xOff: f.bbXOff, yOff: f.bbYOff,
+	}
+	for sc.next() {
+		switch sc.keyword {
+		case "ENCODING":
+			vs, err := sc.ints(1)
+			if err != nil {
+				return out, err
+			}
+			out.encoding = vs[0]
+		case "SWIDTH":
+			vs, err := sc.ints(1)
+			if err != nil {
+				return out, err
+			}
+			out.sWidth = vs[0]
+		case "DWIDTH":
+			vs, err := sc.ints(1)
+			if err != nil {
+				return out, err
+			}
+			out.dWidth = int16(vs[0])
+		case "BBX":
+			vs, err := sc.ints(4)
+			if err != nil {
+				return out, err
+			}
+			if err := sc.checkBox(vs); err != nil {
+				return out, err
+			}
+			out.width, out.height, out.xOff, out.yOff = int16(vs[0]), int16(vs[1]), int16(vs[2]), int16(vs[3])
+		case "BITMAP":
+			rowLength := (int(out.width) + 7) / 8
+			if out.width == 0 && out.height == 0 { // empty glyph, like a space
+				out.bitmap = []byte{}
+				continue
+			}
+			if rowLength <= 0 || out.height <= 0 {
+				return out, sc.errorf("invalid bitmap size %dx%d for glyph %s", out.width, out.height, out.name)
+			}
+			out.bitmap = make([]byte, 0, rowLength*int(out.height))
+			for row := 0; row < int(out.height); row++ {
+				if !sc.next() {
+					return out, sc.errorf("missing bitmap rows")
+				}
+				data, err := hex.DecodeString(sc.keyword)
+				if err != nil {
+					return out, sc.errorf("invalid bitmap row: %s", err)
+				}
+				// tolerate shorter or longer rows
+				for len(data) < rowLength {
+					data = append(data, 0)
+				}
+				out.bitmap = append(out.bitmap, data[:rowLength]...)
+			}
+		case "ENDCHAR":
+			if out.bitmap == nil && out.width != 0 && out.height != 0 {
+				return out, sc.errorf("missing BITMAP for glyph %s", out.name)
+			}
+			return out, nil
+		}
+	}
+	return out, sc.errorf("missing ENDCHAR")
+}
+
+// font wide metrics, as stored in the accelerator
+func (f *bdfFont) ascentDescent() (ascent, descent int32) {
+	ascent, hasAscent := f.intProperty("FONT_ASCENT")
+	if !hasAscent {
+		ascent = int32(f.bbHeight) + int32(f.bbYOff)
+	}
+	descent, hasDescent := f.intProperty("FONT_DESCENT")
+	if !hasDescent {
+		descent = -int32(f.bbYOff)
+	}
+	return ascent, descent
+}
+
+// pcfProperties adds the properties derived from the
+// font header, if they are missing, as bdftopcf does.
+func (f *bdfFont) pcfProperties() []bdfProperty {
+	props := append([]bdfProperty(nil), f.properties...)
+	add := func(name string, value bitmap.Property) {
+		if !f.hasProperty(name) {
+			props = append(props, bdfProperty{name, value})
+		}
+	}
+	if f.name != "" {
+		add("FONT", bitmap.Atom(f.name))
+	}
+	add("POINT_SIZE", bitmap.Int(f.pointSize*10))
+	add("RESOLUTION_X", bitmap.Int(f.xRes))
+	add("RESOLUTION_Y", bitmap.Int(f.yRes))
+	add("PIXEL_SIZE", bitmap.Int(math.Round(float64(f.pointSize)*float64(f.yRes)/72)))
+	return props
+}
+
+// PCF tables
+const (
+	pcfProperties = 1 << iota
+	pcfAccelerators
+	pcfMetrics
+	pcfBitmaps
+	pcfInkMetrics
+	pcfBdfEncodings
+	pcfSWidths
+	pcfGlyphNames
+	pcfBdfAccelerators
+)
+
+// we use big endian and most significant bit first,
+// with bitmap rows padded to the byte
+const pcfFormat = 1<<2 | 1<<3
+
+type pcfMetric struct {
+	leftSideBearing, rightSideBearing, characterWidth int16
+	characterAscent, characterDescent                 int16
+}
+
+// bound returns the component-wise minimum (or maximum) of `m` and `other`
+func (m pcfMetric) bound(other pcfMetric, isMax bool) pcfMetric {
+	pick := func(a, b int16) int16 {
+		if (b > a) == isMax {
+			return b
+		}
+		return a
+	}
+	return pcfMetric{
+		leftSideBearing:  pick(m.leftSideBearing, other.leftSideBearing),
+		rightSideBearing: pick(m.rightSideBearing, other.rightSideBearing),
+		characterWidth:   pick(m.characterWidth, other.characterWidth),
+		characterAscent:  pick(m.characterAscent, other.characterAscent),
+		characterDescent: pick(m.characterDescent, other.characterDescent),
+	}
+}
+
+func (g bdfGlyph) metric() pcfMetric {
+	return pcfMetric{
+		leftSideBearing:  g.xOff,
+		rightSideBearing: g.xOff + g.width,
+		characterWidth:   g.dWidth,
+		characterAscent:  g.height + g.yOff,
+		characterDescent: -g.yOff,
+	}
+}
+
+// pcfWriter accumulates big endian values
+type pcfWriter struct {
+	bytes.Buffer
+}
+
+func (w *pcfWriter) u8(v uint8) { w.WriteByte(v) }
+
+func (w *pcfWriter) u16(v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	w.Write(buf[:])
+}
+
+func (w *pcfWriter) u32(v uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	w.Write(buf[:])
+}
+
+// format is always written in little endian
+func (w *pcfWriter) format(format uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], format)
+	w.Write(buf[:])
+}
+
+func (w *pcfWriter) metric(m pcfMetric) {
+	w.u16(uint16(m.leftSideBearing))
+	w.u16(uint16(m.rightSideBearing))
+	w.u16(uint16(m.characterWidth))
+	w.u16(uint16(m.characterAscent))
+	w.u16(uint16(m.characterDescent))
+	w.u16(0) // attributes
+}
+
+func (f *bdfFont) propertiesTable() []byte {
+	var (
+		w    pcfWriter
+		data bytes.Buffer // null terminated strings
+	)
+	addString := func(s string) uint32 {
+		offset := uint32(data.Len())
+		data.WriteString(s)
+		data.WriteByte(0)
+		return offset
+	}
+
+	props := f.pcfProperties()
+	w.format(pcfFormat)
+	w.u32(uint32(len(props)))
+	for _, prop := range props {
+		w.u32(addString(prop.name))
+		switch value := prop.value.(type) {
+		case bitmap.Atom:
+			w.u8(1)
+			w.u32(addString(string(value)))
+		case bitmap.Int:
+			w.u8(0)
+			w.u32(uint32(value))
+		}
+	}
+	if padding := len(props) & 3; padding != 0 {
+		w.Write(make([]byte, 4-padding))
+	}
+	w.u32(uint32(data.Len()))
+	w.Write(data.Bytes())
+	return w.Bytes()
+}
+
+func (f *bdfFont) acceleratorTable() []byte {
+	fontAscent, fontDescent := f.ascentDescent()
+	first := f.glyphs[0].metric()
+	minBounds, maxBounds := first, first
+	var (
+		maxOverlap                                          = int32(math.MinInt32)
+		constantMetrics, constantWidth, inkInside, terminal = true, true, true, true
+	)
+	for _, glyph := range f.glyphs {
+		m := glyph.metric()
+		if m != first {
+			constantMetrics = false
+		}
+		if m.characterWidth != first.characterWidth {
+			constantWidth = false
+		}
+		if m.leftSideBearing < 0 || m.rightSideBearing > m.characterWidth ||
+			int32(m.characterAscent) > fontAscent || int32(m.characterDescent) > fontDescent {
+			inkInside = false
+		}
+		if m.leftSideBearing != 0 || m.rightSideBearing != m.characterWidth ||
+			int32(m.characterAscent) != fontAscent || int32(m.characterDescent) != fontDescent {
+			terminal = false
+		}
+		if overlap := int32(m.rightSideBearing - m.characterWidth); overlap > maxOverlap {
+			maxOverlap = overlap
+		}
+		minBounds, maxBounds = minBounds.bound(m, false), maxBounds.bound(m, true)
+	}
+
+	boolByte := func(b bool) uint8 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	var w pcfWriter
+	w.format(pcfFormat)
+	w.u8(boolByte(maxOverlap <= int32(minBounds.leftSideBearing))) // noOverlap
+	w.u8(boolByte(constantMetrics))
+	w.u8(boolByte(constantMetrics && terminal))
+	w.u8(boolByte(constantWidth))
+	w.u8(boolByte(inkInside))
+	w.u8(0) // ink metrics
+	w.u8(0) // left to right
+	w.u8(0) // padding
+	w.u32(uint32(fontAscent))
+	w.u32(uint32(fontDescent))
+	w.u32(uint32(maxOverlap))
+	w.metric(minBounds)
+	w.metric(maxBounds)
+	return w.Bytes()
+}
+
+func (f *bdfFont) metricsTable() []byte {
+	var w pcfWriter
+	w.format(pcfFormat)
+	w.u32(uint32(len(f.glyphs)))
+	for _, glyph := range f.glyphs {
+		w.metric(glyph.metric())
+	}
+	return w.Bytes()
+}
+
+func (f *bdfFont) bitmapsTable() []byte {
+	var (
+		w     pcfWriter
+		sizes [4]uint32 // for each padding
+		data  []byte
+	)
+	w.format(pcfFormat)
+	w.u32(uint32(len(f.glyphs)))
+	for _, glyph := range f.glyphs {
+		w.u32(uint32(len(data)))
+		data = append(data, glyph.bitmap...)
+		rowLength := (int(glyph.width) + 7) / 8
+		if rowLength <= 0 || glyph.height <= 0 { // empty glyph
+			continue
+		}
+		for i := range sizes {
+			pad := 1 << uint(i)
+			sizes[i] += uint32(int(glyph.height) * ((rowLength + pad - 1) / pad * pad))
+		}
+	}
+	for _, size := range sizes {
+		w.u32(size)
+	}
+	w.Write(data)
+	return w.Bytes()
+}
+
+func (f *bdfFont) encodingTable() []byte {
+	// restrict to the range of encoded glyphs
+	minChar, maxChar, minByte, maxByte := 0xFF, 0, 0xFF, 0
+	for _, glyph := range f.glyphs {
+		if glyph.encoding < 0 || glyph.encoding > 0xFFFF {
+			continue
+		}
+		b, c := int(glyph.encoding>>8), int(glyph.encoding&0xFF)
+		if c < minChar {
+			minChar = c
+		}
+		if c > maxChar {
+			maxChar = c
+		}
+		if b < minByte {
+			minByte = b
+		}
+		if b > maxByte {
+			maxByte = b
+		}
+	}
+	if minChar > maxChar { // no encoded glyphs
+		minChar, maxChar, minByte, maxByte = 0, 0, 0, 0
+	}
+
+	L := maxChar - minChar + 1
+	values := make([]uint16, (maxByte-minByte+1)*L)
+	for i := range values {
+		values[i] = 0xFFFF
+	}
+	for gid, glyph := range f.glyphs {
+		if glyph.encoding < 0 || glyph.encoding > 0xFFFF {
+			continue
+		}
+		b, c := int(glyph.encoding>>8), int(glyph.encoding&0xFF)
+		if index := (b-minByte)*L + c - minChar; values[index] == 0xFFFF { // keep the first glyph
+			values[index] = uint16(gid)
+		}
+	}
+
+	defaultChar := uint16(0xFFFF)
+	if dc, ok := f.intProperty("DEFAULT_CHAR"); ok {
+		b, c := int(dc>>8), int(dc&0xFF)
+		if minByte <= b && b <= maxByte && minChar <= c && c <= maxChar {
+			defaultChar = values[(b-minByte)*L+c-minChar]
+		}
+	}
+
+	var w pcfWriter
+	w.format(pcfFormat)
+	w.u16(uint16(minChar))
+	w.u16(uint16(maxChar))
+	w.u16(uint16(minByte))
+	w.u16(uint16(maxByte))
+	w.u16(defaultChar)
+	for _, v := range values {
+		w.u16(v)
+	}
+	return w.Bytes()
+}
+
+func (f *bdfFont) sWidthsTable() []byte {
+	var w pcfWriter
+	w.format(pcfFormat)
+	w.u32(uint32(len(f.glyphs)))
+	for _, glyph := range f.glyphs {
+		w.u32(uint32(glyph.sWidth))
+	}
+	return w.Bytes()
+}
+
+func (f *bdfFont) glyphNamesTable() []byte {
+	var (
+		w     pcfWriter
+		names bytes.Buffer
+	)
+	w.format(pcfFormat)
+	w.u32(uint32(len(f.glyphs)))
+	for _, glyph := range f.glyphs {
+		w.u32(uint32(names.Len()))
+		names.WriteString(glyph.name)
+		names.WriteByte(0)
+	}
+	w.u32(uint32(names.Len()))
+	w.Write(names.Bytes())
+	return w.Bytes()
+}
+
+// toPCF returns the content of the PCF file equivalent to `f`
+func (f *bdfFont) toPCF() []byte {
+	accelerator := f.acceleratorTable()
+	tables := [...]struct {
+		kind uint32
+		data []byte
+	}{
+		// the properties must come before the encodings (see bitmap.ScanFont)
+		{pcfProperties, f.propertiesTable()},
+		{pcfAccelerators, accelerator},
+		{pcfMetrics, f.metricsTable()},
+		{pcfBitmaps, f.bitmapsTable()},
+		{pcfBdfEncodings, f.encodingTable()},
+		{pcfSWidths, f.sWidthsTable()},
+		{pcfGlyphNames, f.glyphNamesTable()},
+		{pcfBdfAccelerators, accelerator},
+	}
+
+	var out bytes.Buffer
+	out.WriteString("\x01fcp")
+	var buf [16]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(len(tables)))
+	out.Write(buf[:4])
+	offset := 8 + 16*len(tables)
+	for _, table := range tables {
+		binary.LittleEndian.PutUint32(buf[0:], table.kind)
+		binary.LittleEndian.PutUint32(buf[4:], pcfFormat)
+		binary.LittleEndian.PutUint32(buf[8:], uint32(len(table.data)))
+		binary.LittleEndian.PutUint32(buf[12:], uint32(offset))
+		out.Write(buf[:])
+		offset += (len(table.data) + 3) &^ 3
+	}
+	for _, table := range tables {
+		out.Write(table.data)
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,203 @@
+package fontconfig
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/bitmap"
+)
+
+// test/8x16.bdf has been generated from test/8x16.pcf
+
+func TestScanBDF(t *testing.T) {
+	c := NewConfig()
+	var patterns [2]Pattern
+	for i, file := range []string{"test/8x16.pcf", "test/8x16.bdf"} {
+		fs, err := c.ScanFontFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fs) != 1 {
+			t.Fatalf("expected one face in %s, got %d", file, len(fs))
+		}
+		patterns[i] = fs[0]
+	}
+
+	p := patterns[1]
+	if f := p.Format(); f != BDF {
+		t.Fatalf("expected format BDF, got %s", f)
+	}
+	if ps, _ := p.GetFloat(PIXEL_SIZE); ps != 16 {
+		t.Fatalf("expected pixel size 16, got %g", ps)
+	}
+	if sp, _ := p.GetInt(SPACING); sp != CHARCELL {
+		t.Fatalf("expected charcell spacing, got %d", sp)
+	}
+	if foundry, _ := p.GetString(FOUNDRY); foundry != "Sony" {
+		t.Fatalf("unexpected foundry %s", foundry)
+	}
+	if cs, _ := p.GetCharset(CHARSET); !cs.HasChar('A') || !cs.HasChar('é') {
+		t.Fatalf("unexpected charset %v", cs)
+	}
+
+	// besides the file, both fonts should be described by the same pattern
+	for _, p := range patterns {
+		p.Del(FILE)
+		p.Del(FONTFORMAT)
+	}
+	if patterns[0].Hash() != patterns[1].Hash() {
+		t.Fatalf("different patterns:\n%s\n%s", patterns[0], patterns[1])
+	}
+}
+
+func loadTestBitmapFace(t *testing.T, file string, format FontFormat) *bitmap.Font {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	faces, err := format.Loader()(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return faces[0].(*bitmap.Font)
+}
+
+// pixel returns the value of the pixel (x, y) of a glyph bitmap,
+// whose rows are padded to `pad` bytes
+func pixel(glyph fonts.GlyphBitmap, pad int, msbFirst bool, x, y int) bool {
+	stride := (glyph.Width + 7) / 8
+	stride = (stride + pad - 1) / pad * pad
+	b := glyph.Data[y*stride+x/8]
+	if msbFirst {
+		return b&(0x80>>uint(x%8)) != 0
+	}
+	return b&(1<<uint(x%8)) != 0
+}
+
+func TestLoadBDF(t *testing.T) {
+	pcf := loadTestBitmapFace(t, "test/8x16.pcf", PCF)
+	bdf := loadTestBitmapFace(t, "test/8x16.bdf", BDF)
+
+	if s1, s2 := pcf.LoadBitmaps(), bdf.LoadBitmaps(); s1[0] != s2[0] {
+		t.Fatalf("expected bitmap size %v, got %v", s1, s2)
+	}
+
+	for r := rune(0); r < 0x100; r++ {
+		gid1, ok1 := pcf.NominalGlyph(r)
+		gid2, ok2 := bdf.NominalGlyph(r)
+		if ok1 != ok2 || ok1 && gid1 != gid2 {
+			t.Fatalf("rune %d: expected glyph %d (%v), got %d (%v)", r, gid1, ok1, gid2, ok2)
+		}
+		if !ok1 {
+			continue
+		}
+		if n1, n2 := pcf.GlyphName(gid1), bdf.GlyphName(gid1); n1 != n2 {
+			t.Fatalf("glyph %d: expected name %s, got %s", gid1, n1, n2)
+		}
+		if a1, a2 := pcf.HorizontalAdvance(gid1), bdf.HorizontalAdvance(gid1); a1 != a2 {
+			t.Fatalf("glyph %d: expected advance %g, got %g", gid1, a1, a2)
+		}
+		e1, _ := pcf.GlyphExtents(gid1, 0, 0)
+		e2, _ := bdf.GlyphExtents(gid1, 0, 0)
+		if e1 != e2 {
+			t.Fatalf("glyph %d: expected extents %v, got %v", gid1, e1, e2)
+		}
+
+		// 8x16.pcf uses 4 bytes padding and least significant bit first
+		g1 := pcf.GlyphData(gid1, 0, 0).(fonts.GlyphBitmap)
+		g2 := bdf.GlyphData(gid1, 0, 0).(fonts.GlyphBitmap)
+		if g1.Width != g2.Width || g1.Height != g2.Height {
+			t.Fatalf("glyph %d: expected size %dx%d, got %dx%d", gid1, g1.Width, g1.Height, g2.Width, g2.Height)
+		}
+		for y := 0; y < g1.Height; y++ {
+			for x := 0; x < g1.Width; x++ {
+				if pixel(g1, 4, false, x, y) != pixel(g2, 1, true, x, y) {
+					t.Fatalf("glyph %d: different pixel at (%d, %d)", gid1, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestParseBDFInvalid(t *testing.T) {
+	const valid = `STARTFONT 2.1
+COMMENT a test font
+FONT -Test-Font-Medium-R-Normal--10-100-72-72-P-50-ISO10646-1
+SIZE 10 72 72
+FONTBOUNDINGBOX 5 8 0 -2
+STARTPROPERTIES 2
+FAMILY_NAME "Font ""Test"""
+DEFAULT_CHAR 65
+ENDPROPERTIES
+CHARS 1
+STARTCHAR A
+ENCODING 65
+SWIDTH 500 0
+DWIDTH 5 0
+BBX 5 8 0 -2
+BITMAP
+20
+50
+88
+F8
+88
+88
+00
+00
+ENDCHAR
+ENDFONT
+`
+	font, err := parseBDF(strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := font.properties[0].value; name != bitmap.Atom(`Font "Test"`) {
+		t.Fatalf("unexpected family %s", name)
+	}
+	face, err := loadBDF(strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gid, ok := face[0].NominalGlyph('A'); !ok || gid != 0 {
+		t.Fatalf("unexpected glyph %d", gid)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"STARTFONT 2.1\nENDFONT\n",
+		strings.Replace(valid, "ENDFONT", "", 1),
+		strings.Replace(valid, "SIZE 10 72 72", "SIZE 10", 1),
+		strings.Replace(valid, "BBX 5 8 0 -2", "BBX 5 -8 0 -2", 1),
+		strings.Replace(valid, "FONTBOUNDINGBOX 5 8 0 -2", "FONTBOUNDINGBOX 65541 8 0 -2", 1),
+		strings.Replace(valid, "BBX 5 8 0 -2", "BBX 0 8 0 -2", 1),
+		strings.Replace(valid, "F8", "G8", 1),
+		strings.Replace(valid, "DEFAULT_CHAR 65", "DEFAULT_CHAR \"65", 1),
+		strings.Replace(valid, "ENDCHAR", "", 1),
+	} {
+		if _, err := parseBDF(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("expected error for\n%s", invalid)
+		}
+	}
+}
+
+func TestScanMalformedBDF(t *testing.T) {
+	f, err := os.Open("test/malformed.bdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = parseBDF(f); err == nil {
+		t.Fatal("expected error for invalid font bounding box")
+	}
+
+	// the directory scan must not be interrupted
+	fs, err := NewConfig().ScanFontFile("test/malformed.bdf")
+	if err == nil && len(fs) != 0 {
+		t.Fatalf("unexpected fonts %v", fs)
+	}
+}
@@ -0,0 +1,446 @@
+package fontconfig
+
+import (
+	"errors"
+	"fmt"
+)
+
+// This file provides a Go API to build the substitution rules,
+// as an alternative to the XML format.
+
+// Target selects the pattern used by a `Test` or a `FieldExpr`,
+// as the 'target' attribute of the XML <test> and <name> elements.
+type Target uint8
+
+const (
+	TargetDefault Target = iota // the kind of the enclosing rule
+	TargetPattern               // the query pattern
+	TargetFont                  // the font pattern
+	TargetScan                  // the font pattern, during scan (only valid for tests)
+)
+
+func (t Target) kind() matchKind {
+	switch t {
+	case TargetPattern:
+		return MatchQuery
+	case TargetFont:
+		return MatchResult
+	case TargetScan:
+		return MatchScan
+	default:
+		return matchDefault
+	}
+}
+
+// Qual specifies how the values of a pattern are tested,
+// as the 'qual' attribute of the XML <test> element.
+type Qual uint8
+
+const (
+	QualAny      Qual = Qual(qualAny)      // at least one value matches
+	QualAll      Qual = Qual(qualAll)      // all the values match
+	QualFirst    Qual = Qual(qualFirst)    // the first value matches
+	QualNotFirst Qual = Qual(qualNotFirst) // a value other than the first matches
+)
+
+// Comparison is a comparison operator, used in tests and expressions.
+type Comparison uint8
+
+const (
+	CompareEqual Comparison = iota
+	CompareNotEqual
+	CompareLess
+	CompareLessEqual
+	CompareMore
+	CompareMoreEqual
+	CompareContains
+	CompareNotContains
+)
+
+var comparisonOps = [...]opKind{
+	CompareEqual:       opEqual,
+	CompareNotEqual:    opNotEqual,
+	CompareLess:        opLess,
+	CompareLessEqual:   opLessEqual,
+	CompareMore:        opMore,
+	CompareMoreEqual:   opMoreEqual,
+	CompareContains:    opContains,
+	CompareNotContains: opNotContains,
+}
+
+func (c Comparison) op() (opKind, bool) {
+	if int(c) >= len(comparisonOps) {
+		return 0, false
+	}
+	return comparisonOps[c], true
+}
+
+// EditMode specifies how an `Edit` modifies the values of a pattern,
+// as the 'mode' attribute of the XML <edit> element.
+type EditMode uint8
+
+const (
+	ModeAssign EditMode = iota
+	ModeAssignReplace
+	ModePrepend
+	ModePrependFirst
+	ModeAppend
+	ModeAppendLast
+	ModeDelete
+	ModeDeleteAll
+)
+
+var editModeOps = [...]opKind{
+	ModeAssign:        opAssign,
+	ModeAssignReplace: opAssignReplace,
+	ModePrepend:       opPrepend,
+	ModePrependFirst:  opPrependFirst,
+	ModeAppend:        opAppend,
+	ModeAppendLast:    opAppendLast,
+	ModeDelete:        opDelete,
+	ModeDeleteAll:     opDeleteAll,
+}
+
+// Binding specifies the binding of the values added by an `Edit`,
+// as the 'binding' attribute of the XML <edit> element.
+type Binding uint8
+
+const (
+	BindingWeak   Binding = Binding(vbWeak)
+	BindingStrong Binding = Binding(vbStrong)
+	BindingSame   Binding = Binding(vbSame)
+)
+
+// Expr is an expression used in tests and edits.
+// The zero value is an empty expression, only valid for
+// the `ModeDelete` and `ModeDeleteAll` edits.
+type Expr struct {
+	e *expression
+}
+
+// ValueExpr returns a constant expression, which
+// may be an Int, Float, String, Bool, Matrix, Range, Charset or Langset.
+func ValueExpr(v Value) Expr {
+	switch v := v.(type) {
+	case Int:
+		return Expr{&expression{op: opInt, u: v}}
+	case Float:
+		return Expr{&expression{op: opDouble, u: v}}
+	case String:
+		return Expr{&expression{op: opString, u: v}}
+	case Bool:
+		return Expr{&expression{op: opBool, u: v}}
+	case Range:
+		return Expr{&expression{op: opRange, u: v}}
+	case Charset:
+		return Expr{&expression{op: opCharSet, u: v}}
+	case Langset:
+		return Expr{&expression{op: opLangSet, u: v}}
+	case Matrix:
+		return MatrixExpr(ValueExpr(Float(v.Xx)), ValueExpr(Float(v.Xy)), ValueExpr(Float(v.Yx)), ValueExpr(Float(v.Yy)))
+	default:
+		return Expr{&expression{op: opInvalid}}
+	}
+}
+
+// FieldExpr returns the first value of `object` in
+// the pattern selected by `target`, as the XML <name> element.
+func FieldExpr(object Object, target Target) Expr {
+	return Expr{&expression{op: opField, u: exprName{object: object, kind: target.kind()}}}
+}
+
+// ConstExpr returns the value of the given named constant (such as "bold"),
+// as the XML <const> element.
+func ConstExpr(name string) Expr {
+	return Expr{&expression{op: opConst, u: String(name)}}
+}
+
+// MatrixExpr returns a matrix whose coefficients are given by the expressions.
+func MatrixExpr(xx, xy, yx, yy Expr) Expr {
+	return Expr{&expression{op: opMatrix, u: exprMatrix{xx: xx.e, xy: xy.e, yx: yx.e, yy: yy.e}}}
+}
+
+// ListExpr returns a list of expressions, used to
+// add several values in an `Edit`.
+func ListExpr(exprs ...Expr) Expr { return binaryExpr(opComma, exprs) }
+
+// IfExpr returns `then` if `cond` evaluates to true, `otherwise` if not.
+func IfExpr(cond, then, otherwise Expr) Expr {
+	return Expr{newExprOp(cond.e, newExprOp(then.e, otherwise.e, opQuest), opQuest)}
+}
+
+// binaryExpr builds the same tree as the XML parser
+func binaryExpr(op opKind, exprs []Expr) Expr {
+	var expr *expression
+	for i := range exprs {
+		left := exprs[len(exprs)-1-i].e
+		if expr != nil {
+			expr = newExprOp(left, expr, op)
+		} else {
+			expr = left
+		}
+	}
+	return Expr{expr}
+}
+
+// Or returns the logical or of the expressions.
+func (e Expr) Or(others ...Expr) Expr { return binaryExpr(opOr, append([]Expr{e}, others...)) }
+
+// And returns the logical and of the expressions.
+func (e Expr) And(others ...Expr) Expr { return binaryExpr(opAnd, append([]Expr{e}, others...)) }
+
+// Plus returns the sum of the expressions.
+func (e Expr) Plus(others ...Expr) Expr { return binaryExpr(opPlus, append([]Expr{e}, others...)) }
+
+// Minus returns the difference of the expressions.
+func (e Expr) Minus(others ...Expr) Expr { return binaryExpr(opMinus, append([]Expr{e}, others...)) }
+
+// Times returns the product of the expressions.
+func (e Expr) Times(others ...Expr) Expr { return binaryExpr(opTimes, append([]Expr{e}, others...)) }
+
+// Divide returns the quotient of the expressions.
+func (e Expr) Divide(others ...Expr) Expr {
+	return binaryExpr(opDivide, append([]Expr{e}, others...))
+}
+
+// Compare returns the boolean result of the comparison
+// between `e` and `other`.
+func (e Expr) Compare(c Comparison, other Expr) Expr {
+	op, ok := c.op()
+	if !ok {
+		op = opInvalid
+	}
+	return Expr{newExprOp(e.e, other.e, op)}
+}
+
+// Not returns the logical negation of the expression.
+func (e Expr) Not() Expr { return Expr{newExprOp(e.e, nil, opNot)} }
+
+// Floor returns the expression rounded down.
+func (e Expr) Floor() Expr { return Expr{newExprOp(e.e, nil, opFloor)} }
+
+// Ceil returns the expression rounded up.
+func (e Expr) Ceil() Expr { return Expr{newExprOp(e.e, nil, opCeil)} }
+
+// Round returns the expression rounded to the nearest integer.
+func (e Expr) Round() Expr { return Expr{newExprOp(e.e, nil, opRound)} }
+
+// Trunc returns the expression rounded towards zero.
+func (e Expr) Trunc() Expr { return Expr{newExprOp(e.e, nil, opTrunc)} }
+
+// String returns a human friendly representation of the expression.
+func (e Expr) String() string {
+	if e.e == nil {
+		return "<empty>"
+	}
+	return e.e.String()
+}
+
+// check returns an error for the invalid nodes
+// (see `ValueExpr` and `Expr.Compare`)
+func (e *expression) check() error {
+	if e == nil {
+		return nil
+	}
+	switch e.op.getOp() {
+	case opInvalid:
+		return errors.New("invalid expression")
+	case opMatrix:
+		m := e.u.(exprMatrix)
+		for _, coeff := range [...]*expression{m.xx, m.xy, m.yx, m.yy} {
+			if coeff == nil {
+				return errors.New("missing values in matrix")
+			}
+			if err := coeff.check(); err != nil {
+				return err
+			}
+		}
+	case opQuest, opOr, opAnd, opPlus, opMinus, opTimes, opDivide, opComma,
+		opEqual, opNotEqual, opLess, opLessEqual, opMore, opMoreEqual, opContains, opNotContains, opListing,
+		opNot, opFloor, opCeil, opRound, opTrunc:
+		tree := e.u.(exprTree)
+		if tree.left == nil {
+			return fmt.Errorf("missing operand for %s", e.op)
+		}
+		if err := tree.left.check(); err != nil {
+			return err
+		}
+		return tree.right.check()
+	}
+	return nil
+}
+
+// Test is a condition of a `Rule`, as the XML <test> element.
+type Test struct {
+	Object       Object
+	Target       Target
+	Qual         Qual
+	Compare      Comparison
+	IgnoreBlanks bool // only used for strings
+	Expr         Expr // must not be a list
+}
+
+// Edit is a modification applied by a `Rule`, as the XML <edit> element.
+type Edit struct {
+	Object  Object
+	Mode    EditMode
+	Binding Binding
+	Expr    Expr // may be a list, must be empty for the delete modes
+}
+
+// Rule is a substitution rule, as the XML <match> element:
+// the edits are applied to the patterns which match all the tests.
+type Rule struct {
+	Kind  matchKind // MatchQuery, MatchResult or MatchScan
+	Tests []Test
+	Edits []Edit
+}
+
+// Alias is a shortcut for a common `Rule`, as the XML <alias> element:
+// for the query patterns containing `Family` (ignoring blanks),
+// `Prefer` families are inserted before it, `Accept` families after it,
+// and `Default` families at the end of the list.
+type Alias struct {
+	Family                  string
+	Tests                   []Test // optional additional tests
+	Prefer, Accept, Default []string
+	Binding                 Binding
+}
+
+func familiesExpr(families []string) Expr {
+	exprs := make([]Expr, len(families))
+	for i, f := range families {
+		exprs[i] = ValueExpr(String(f))
+	}
+	return ListExpr(exprs...)
+}
+
+// Rule returns the rule equivalent to the alias.
+func (alias Alias) Rule() Rule {
+	rule := Rule{Kind: MatchQuery}
+	rule.Tests = append(append(rule.Tests, alias.Tests...), Test{
+		Object: FAMILY, Target: TargetPattern, Compare: CompareEqual,
+		IgnoreBlanks: true, Expr: ValueExpr(String(alias.Family)),
+	})
+	for _, edit := range [...]struct {
+		families []string
+		mode     EditMode
+	}{{alias.Prefer, ModePrepend}, {alias.Accept, ModeAppend}, {alias.Default, ModeAppendLast}} {
+		if len(edit.families) != 0 {
+			rule.Edits = append(rule.Edits, Edit{Object: FAMILY, Mode: edit.mode, Binding: alias.Binding, Expr: familiesExpr(edit.families)})
+		}
+	}
+	return rule
+}
+
+// AddRules adds the given rules to the configuration, after the existing ones,
+// as a new group (a configuration file when using XML), identified by `name`.
+// The rules are type checked as the XML rules are: an error is returned if one
+// of them is invalid, in which case none of the rules are added.
+func (config *Config) AddRules(name string, rules ...Rule) error {
+	parser := newConfigParser(name, config)
+	var maxObj int
+	for _, rule := range rules {
+		ruleMax, err := parser.addRule(rule)
+		if err != nil {
+			return err
+		}
+		if maxObj < ruleMax {
+			maxObj = ruleMax
+		}
+	}
+
+	// all the rules are valid: commit them
+	config.subst = append(config.subst, parser.ruleset)
+	if config.maxObjects < maxObj {
+		config.maxObjects = maxObj
+	}
+	return nil
+}
+
+// addRule adds `rule` to the rule set of the parser, without modifying
+// the configuration, and returns the value to use for `Config.maxObjects`.
+func (parser *configParser) addRule(rule Rule) (int, error) {
+	if rule.Kind < MatchQuery || rule.Kind >= matchKindEnd {
+		return 0, parser.error("invalid match target %d", rule.Kind)
+	}
+	if len(rule.Tests)+len(rule.Edits) == 0 {
+		return 0, parser.error("No <test> nor <edit> elements in <match>")
+	}
+
+	var d directive
+	for _, test := range rule.Tests {
+		if test.Expr.e == nil {
+			return 0, parser.error("missing test expression")
+		}
+		if test.Expr.e.op == opComma {
+			return 0, parser.error("Having multiple values in <test> isn't supported and may not work as expected")
+		}
+		if err := test.Expr.e.check(); err != nil {
+			return 0, parser.error("test for %s: %s", test.Object, err)
+		}
+		if test.Qual > QualNotFirst {
+			return 0, parser.error("invalid test qual %d", test.Qual)
+		}
+		compare, ok := test.Compare.op()
+		if !ok {
+			return 0, parser.error("invalid comparison %d", test.Compare)
+		}
+		var flags int64
+		if test.IgnoreBlanks {
+			flags |= opFlagIgnoreBlanks
+		}
+		t, err := parser.newTest(test.Target.kind(), uint8(test.Qual), test.Object,
+			opWithFlags(compare, flags), test.Expr.e.copyT())
+		if err != nil {
+			return 0, err
+		}
+		d.tests = append(d.tests, t)
+	}
+
+	for _, edit := range rule.Edits {
+		if int(edit.Mode) >= len(editModeOps) {
+			return 0, parser.error("invalid edit mode %d", edit.Mode)
+		}
+		if edit.Binding > BindingSame {
+			return 0, parser.error("invalid binding %d", edit.Binding)
+		}
+		mode := editModeOps[edit.Mode]
+		if (mode == opDelete || mode == opDeleteAll) && edit.Expr.e != nil {
+			return 0, parser.error("Expression doesn't take any effects for delete and delete_all")
+		}
+		if err := edit.Expr.e.check(); err != nil {
+			return 0, parser.error("edit for %s: %s", edit.Object, err)
+		}
+		if rule.Kind == MatchScan && edit.Object >= FirstCustomObject {
+			return 0, parser.error("<match target=\"scan\"> cannot edit user-defined object \"%s\"", edit.Object)
+		}
+		e, err := parser.newEdit(edit.Object, mode, edit.Expr.e.copyT(), valueBinding(edit.Binding))
+		if err != nil {
+			return 0, err
+		}
+		d.edits = append(d.edits, e)
+	}
+
+	return parser.ruleset.add(d, rule.Kind), nil
+}
+
+// AcceptGlob adds a file name selector to the configuration: the font files matching `glob`
+// are accepted, even if they match a rejecting glob. See `RejectGlob`.
+// This is the equivalent of the <selectfont><acceptfont><glob> element.
+func (config *Config) AcceptGlob(glob string) { config.globAdd(glob, true) }
+
+// RejectGlob adds a file name selector to the configuration: the font files matching `glob`
+// are ignored when scanning.
+// This is the equivalent of the <selectfont><rejectfont><glob> element.
+func (config *Config) RejectGlob(glob string) { config.globAdd(glob, false) }
+
+// AcceptPattern adds a font selector to the configuration: the fonts matching `pattern`
+// are accepted, even if they match a rejecting pattern. See `RejectPattern`.
+// This is the equivalent of the <selectfont><acceptfont><pattern> element.
+func (config *Config) AcceptPattern(pattern Pattern) { config.patternsAdd(pattern, true) }
+
+// RejectPattern adds a font selector to the configuration: the fonts matching `pattern`
+// are ignored when scanning.
+// This is the equivalent of the <selectfont><rejectfont><pattern> element.
+func (config *Config) RejectPattern(pattern Pattern) { config.patternsAdd(pattern, false) }
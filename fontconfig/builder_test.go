@@ -0,0 +1,151 @@
+package fontconfig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBuilderMatchesXML(t *testing.T) {
+	doc := []byte(`<?xml version="1.0"?>
+	<fontconfig>
+		<match target="pattern">
+			<test qual="any" name="family" compare="eq" ignore-blanks="true"><string>Times</string></test>
+			<test name="size" compare="more_eq" target="default"><double>12</double></test>
+			<edit name="weight" mode="assign" binding="strong">
+				<if><name>embolden</name><const>bold</const><plus><int>10</int><name target="pattern">weight</name></plus></if>
+			</edit>
+			<edit name="family" mode="append"><string>A</string><string>B</string></edit>
+			<edit name="matrix" mode="assign"><matrix><double>1</double><double>0.2</double><double>0</double><double>1</double></matrix></edit>
+		</match>
+		<match target="font">
+			<test name="scalable" compare="eq" qual="all"><bool>true</bool></test>
+			<edit name="antialias" mode="delete_all" />
+		</match>
+		<alias binding="same">
+			<family>Sans</family>
+			<prefer><family>DejaVu Sans</family><family>Verdana</family></prefer>
+			<default><family>Arial</family></default>
+		</alias>
+	</fontconfig>`)
+
+	fromXML := NewConfig()
+	if err := fromXML.LoadFromMemory(bytes.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	fromGo := NewConfig()
+	err := fromGo.AddRules("go",
+		Rule{
+			Kind: MatchQuery,
+			Tests: []Test{
+				{Object: FAMILY, IgnoreBlanks: true, Expr: ValueExpr(String("Times"))},
+				{Object: SIZE, Compare: CompareMoreEqual, Expr: ValueExpr(Float(12))},
+			},
+			Edits: []Edit{
+				{Object: WEIGHT, Binding: BindingStrong, Expr: IfExpr(
+					FieldExpr(EMBOLDEN, TargetDefault),
+					ConstExpr("bold"),
+					ValueExpr(Int(10)).Plus(FieldExpr(WEIGHT, TargetPattern)),
+				)},
+				{Object: FAMILY, Mode: ModeAppend, Expr: ListExpr(ValueExpr(String("A")), ValueExpr(String("B")))},
+				{Object: MATRIX, Expr: ValueExpr(Matrix{Xx: 1, Xy: 0.2, Yx: 0, Yy: 1})},
+			},
+		},
+		Rule{
+			Kind:  MatchResult,
+			Tests: []Test{{Object: SCALABLE, Qual: QualAll, Expr: ValueExpr(Bool(True))}},
+			Edits: []Edit{{Object: ANTIALIAS, Mode: ModeDeleteAll}},
+		},
+		Alias{
+			Family:  "Sans",
+			Prefer:  []string{"DejaVu Sans", "Verdana"},
+			Default: []string{"Arial"},
+			Binding: BindingSame,
+		}.Rule(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fromXML.subst) != 1 || len(fromGo.subst) != 1 {
+		t.Fatalf("expected one rule set, got %d and %d", len(fromXML.subst), len(fromGo.subst))
+	}
+	if !reflect.DeepEqual(fromXML.subst[0].subst, fromGo.subst[0].subst) {
+		t.Fatalf("expected\n%v\ngot\n%v", fromXML.subst[0].subst, fromGo.subst[0].subst)
+	}
+	if fromXML.maxObjects != fromGo.maxObjects {
+		t.Fatalf("expected %d objects, got %d", fromXML.maxObjects, fromGo.maxObjects)
+	}
+
+	query := NewPattern()
+	query.AddString(FAMILY, "Sans")
+	query.AddFloat(SIZE, 14)
+	p1, p2 := query.Duplicate(), query.Duplicate()
+	fromXML.Substitute(p1, nil, MatchQuery)
+	fromGo.Substitute(p2, nil, MatchQuery)
+	if p1.Hash() != p2.Hash() {
+		t.Fatalf("expected %s, got %s", p1, p2)
+	}
+	if fam, _ := p2.GetString(FAMILY); fam != "DejaVu Sans" {
+		t.Fatalf("unexpected substitution %s", p2)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	custom := FirstCustomObject + 30
+	for _, rule := range []Rule{
+		{Kind: MatchQuery},
+		{Kind: matchKindEnd, Edits: []Edit{{Object: FAMILY, Expr: ValueExpr(String("a"))}}},
+		{Kind: MatchQuery, Tests: []Test{{Object: FAMILY, Expr: ListExpr(ValueExpr(String("a")), ValueExpr(String("b")))}}},
+		{Kind: MatchQuery, Tests: []Test{{Object: FAMILY}}},
+		{Kind: MatchQuery, Tests: []Test{{Object: FAMILY, Expr: ValueExpr(Int(2))}}},                    // type mismatch
+		{Kind: MatchQuery, Tests: []Test{{Object: FAMILY, Compare: 20, Expr: ValueExpr(String("a"))}}},  // invalid comparison
+		{Kind: MatchQuery, Edits: []Edit{{Object: SIZE, Expr: ValueExpr(String("large"))}}},             // type mismatch
+		{Kind: MatchQuery, Edits: []Edit{{Object: SIZE, Mode: ModeDelete, Expr: ValueExpr(Float(12))}}}, // useless expression
+		{Kind: MatchQuery, Edits: []Edit{{Object: SIZE, Expr: ValueExpr(nil)}}},
+		{Kind: MatchQuery, Edits: []Edit{{Object: SIZE, Expr: Expr{}.Floor()}}},
+		{Kind: MatchQuery, Edits: []Edit{{Object: SIZE, Mode: 20, Expr: ValueExpr(Float(12))}}},
+		{Kind: MatchScan, Edits: []Edit{{Object: custom, Expr: ValueExpr(String("a"))}}}, // custom object in scan
+	} {
+		config := NewConfig()
+		valid := Rule{Kind: MatchQuery, Edits: []Edit{{Object: custom, Mode: ModeDelete}}}
+		err := config.AddRules("invalid", valid, rule)
+		if err == nil {
+			t.Fatalf("expected error for %v", rule)
+		}
+		if ce, ok := err.(*ConfigError); !ok || ce.Source != "invalid" || ce.Severity != SeverityError {
+			t.Fatalf("unexpected error %#v", err)
+		}
+		if len(config.subst) != 0 || config.maxObjects != 0 {
+			t.Fatal("invalid rules should not be added")
+		}
+	}
+}
+
+func TestBuilderSelectFont(t *testing.T) {
+	config := NewConfig()
+	config.RejectGlob("/usr/share/fonts/misc/*.pcf")
+	config.AcceptGlob("/usr/share/fonts/misc/6x13.pcf")
+	if config.acceptFilename("/usr/share/fonts/misc/7x14.pcf") {
+		t.Fatal("expected rejected file")
+	}
+	if !config.acceptFilename("/usr/share/fonts/misc/6x13.pcf") || !config.acceptFilename("/usr/share/fonts/arial.ttf") {
+		t.Fatal("expected accepted file")
+	}
+
+	reject, accept := NewPattern(), NewPattern()
+	reject.Add(SCALABLE, Bool(False), true)
+	accept.AddString(FAMILY, "Fixed")
+	config.RejectPattern(reject)
+	config.AcceptPattern(accept)
+	font := NewPattern()
+	font.Add(SCALABLE, Bool(False), true)
+	if config.acceptFont(font) {
+		t.Fatal("expected rejected font")
+	}
+	font.AddString(FAMILY, "Fixed")
+	if !config.acceptFont(font) {
+		t.Fatal("expected accepted font")
+	}
+}
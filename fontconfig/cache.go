@@ -59,11 +59,25 @@ func (p Pattern) serializeBin() ([]byte, error) {
 		return nil, err
 	}
 	for obj, list := range p {
+		// custom objects are stored with their name, since
+		// they are not constant between processes
+		var name string
+		if obj >= FirstCustomObject {
+			name, _ = customObjectName(obj)
+		}
+		if name != "" {
+			obj = invalid
+		}
 		binary.BigEndian.PutUint16(buf[:], uint16(obj))
 		_, err := w.Write(buf[:])
 		if err != nil {
 			return nil, err
 		}
+		if name != "" {
+			if err = String(name).serializeBin(&w); err != nil {
+				return nil, err
+			}
+		}
 		if err := list.serializeBin(&w); err != nil {
 			return nil, err
 		}
@@ -340,6 +354,15 @@ func deserializePatternBin(data []byte) (Pattern, error) {
 		}
 		obj := Object(binary.BigEndian.Uint16(data[offset:]))
 		offset += 2
+		if obj == invalid { // custom object, stored with its name
+			var name String
+			read, err := name.deserializeBin(data[offset:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern: %s", err)
+			}
+			offset += read
+			obj = customObject(string(name))
+		}
 		list, read, err := deserializeValueListBin(data[offset:])
 		if err != nil {
 			return nil, fmt.Errorf("invalid pattern: %s", err)
@@ -74,6 +74,8 @@ func parseCharSet(str string) (Charset, error) {
 		} else if len(fields) >= 3 && fields[1] == "-" {
 			lastS = fields[2]
 			fields = fields[3:]
+		} else {
+			fields = fields[1:]
 		}
 
 		first, err := strconv.ParseInt(firstS, 16, 32)
@@ -92,9 +94,7 @@ func parseCharSet(str string) (Charset, error) {
 			return out, fmt.Errorf("invalid charset range %s %s", firstS, lastS)
 		}
 
-		for u := rune(first); u < rune(last+1); u++ {
-			out.AddChar(u)
-		}
+		out.addRange(rune(first), rune(last))
 	}
 	return out, nil
 }
@@ -159,8 +159,11 @@ func charsetSubtractCount(a, b Charset) uint32 {
 }
 
 // Returns whether `a` and `b` contain the same set of Unicode chars.
+// Empty leaves (see `DelChar`) are ignored.
 func charsetEqual(a, b Charset) bool {
 	ai, bi := newCharsetIter(a), newCharsetIter(b)
+	ai.skipEmpty()
+	bi.skipEmpty()
 	for ai.leaf != nil && bi.leaf != nil {
 		if ai.page() != bi.page() {
 			return false
@@ -170,6 +173,8 @@ func charsetEqual(a, b Charset) bool {
 		}
 		ai.next()
 		bi.next()
+		ai.skipEmpty()
+		bi.skipEmpty()
 	}
 	return ai.leaf == bi.leaf
 }
@@ -196,7 +201,10 @@ func (a Charset) isSubset(b Charset) bool {
 			ai++
 			bi++
 		} else if an < bn { // Does a have any pages not in b?
-			return false
+			if a.pages[ai] != (charPage{}) {
+				return false
+			}
+			ai++
 		} else {
 			bi = b.findLeafForward(bi+1, an)
 			if bi < 0 {
@@ -204,8 +212,13 @@ func (a Charset) isSubset(b Charset) bool {
 			}
 		}
 	}
-	//  did we look at every page?
-	return ai >= len(a.pageNumbers)
+	//  did we look at every (non empty) page?
+	for ; ai < len(a.pageNumbers); ai++ {
+		if a.pages[ai] != (charPage{}) {
+			return false
+		}
+	}
+	return true
 }
 
 // Locate the leaf containing the specified char, creating it if desired
@@ -335,17 +348,170 @@ func (a Charset) Len() int {
 	return count
 }
 
+// Ranges returns the sorted list of the maximal
+// inclusive ranges [lo, hi] of runes in the set.
+// See `RangeIterator` to avoid allocating the whole list.
+func (a Charset) Ranges() [][2]rune {
+	var out [][2]rune
+	iter := a.RangeIterator()
+	for lo, hi, ok := iter.Next(); ok; lo, hi, ok = iter.Next() {
+		out = append(out, [2]rune{lo, hi})
+	}
+	return out
+}
+
+// CharsetRangeIterator iterates over the maximal inclusive
+// ranges of runes of a charset, in increasing order.
+type CharsetRangeIterator struct {
+	charset Charset
+	page    int    // current page index
+	word    int    // current word in the page
+	bits    uint32 // the bits of the current word not yet visited
+
+	pending    rune // the first rune of the next range
+	hasPending bool
+}
+
+// RangeIterator returns an iterator over the ranges of runes in the set.
+// The charset must not be modified during the iteration.
+func (a Charset) RangeIterator() *CharsetRangeIterator {
+	iter := &CharsetRangeIterator{charset: a}
+	if len(a.pages) != 0 {
+		iter.bits = a.pages[0][0]
+	}
+	return iter
+}
+
+// nextRune returns the next rune of the set, or false at the end.
+func (iter *CharsetRangeIterator) nextRune() (rune, bool) {
+	for iter.bits == 0 {
+		iter.word++
+		if iter.word == len(charPage{}) {
+			iter.word = 0
+			iter.page++
+		}
+		if iter.page >= len(iter.charset.pages) {
+			return 0, false
+		}
+		iter.bits = iter.charset.pages[iter.page][iter.word]
+	}
+	bit := bits.TrailingZeros32(iter.bits)
+	iter.bits &= iter.bits - 1
+	return rune(iter.charset.pageNumbers[iter.page])<<8 | rune(iter.word<<5) | rune(bit), true
+}
+
+// Next returns the next range [lo, hi], or false at the end of the set.
+func (iter *CharsetRangeIterator) Next() (lo, hi rune, ok bool) {
+	if iter.hasPending {
+		lo, ok = iter.pending, true
+		iter.hasPending = false
+	} else {
+		lo, ok = iter.nextRune()
+	}
+	if !ok {
+		return 0, 0, false
+	}
+	hi = lo
+	for {
+		r, ok := iter.nextRune()
+		if !ok {
+			break
+		}
+		if r != hi+1 {
+			iter.pending, iter.hasPending = r, true
+			break
+		}
+		hi = r
+	}
+	return lo, hi, true
+}
+
+// NewCharsetFromRanges returns the set containing the runes
+// of the inclusive ranges [lo, hi].
+// Invalid ranges (with lo > hi) are ignored, and the runes are clamped to
+// the [0, 0xFFFFFF] interval.
+func NewCharsetFromRanges(ranges [][2]rune) Charset {
+	var out Charset
+	for _, r := range ranges {
+		out.addRange(r[0], r[1])
+	}
+	return out
+}
+
+// addRange adds the runes in [lo, hi], a page at a time.
+func (fcs *Charset) addRange(lo, hi rune) {
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > maxCharsetRune {
+		hi = maxCharsetRune
+	}
+	for lo <= hi {
+		pageEnd := lo | 0xff
+		if pageEnd > hi {
+			pageEnd = hi
+		}
+		leaf := fcs.findLeafCreate(uint16(lo >> 8))
+		for r := lo; r <= pageEnd; r++ {
+			leaf[(r&0xff)>>5] |= 1 << (r & 0x1f)
+		}
+		lo = pageEnd + 1
+	}
+}
+
+// Union returns a new set with the runes in `a` or in `b`.
+func (a Charset) Union(b Charset) Charset { return charsetUnion(a, b) }
+
+// Intersect returns a new set with the runes both in `a` and in `b`.
+func (a Charset) Intersect(b Charset) Charset {
+	return operate(a, b, (*charPage).intersectLeaf, false, false)
+}
+
+// Subtract returns a new set with the runes in `a` but not in `b`.
+func (a Charset) Subtract(b Charset) Charset { return charsetSubtract(a, b) }
+
+// IsSubset returns `true` if all the runes in `a` are also in `b`.
+func (a Charset) IsSubset(b Charset) bool { return a.isSubset(b) }
+
+// Equal returns `true` if `a` and `b` contain the same runes.
+func (a Charset) Equal(b Charset) bool { return charsetEqual(a, b) }
+
+// ParseCharset parses a charset in the fontconfig text syntax,
+// that is a space separated list of hexadecimal runes or ranges, such as "20-7e a0 ff".
+// See `Charset.Text` for the reverse operation.
+func ParseCharset(str string) (Charset, error) { return parseCharSet(str) }
+
+// Text formats the charset in the fontconfig text syntax.
+// See `ParseCharset` for the reverse operation.
+func (a Charset) Text() string {
+	var buf strings.Builder
+	iter := a.RangeIterator()
+	for lo, hi, ok := iter.Next(); ok; lo, hi, ok = iter.Next() {
+		if buf.Len() != 0 {
+			buf.WriteByte(' ')
+		}
+		if lo == hi {
+			fmt.Fprintf(&buf, "%x", lo)
+		} else {
+			fmt.Fprintf(&buf, "%x-%x", lo, hi)
+		}
+	}
+	return buf.String()
+}
+
 func charsetUnion(a, b Charset) Charset {
-	return operate(a, b, (*charPage).unionLeaf, true)
+	return operate(a, b, (*charPage).unionLeaf, true, true)
 }
 
-func operate(a, b Charset, overlap func(result *charPage, al, bl charPage) bool, bonly bool) Charset {
+// `aonly` and `bonly` control whether the pages found only
+// in `a` (resp. `b`) are kept
+func operate(a, b Charset, overlap func(result *charPage, al, bl charPage) bool, aonly, bonly bool) Charset {
 	var fcs Charset
 	ai, bi := newCharsetIter(a), newCharsetIter(b)
 	for ai.leaf != nil || (bonly && bi.leaf != nil) {
 		aiPage, biPage := ai.page(), bi.page()
 		if aiPage < biPage {
-			if ai.leaf != nil {
+			if aonly && ai.leaf != nil {
 				fcs.addLeaf(aiPage, *ai.leaf)
 			}
 			ai.next()
@@ -378,6 +544,20 @@ func (result *charPage) unionLeaf(a, b charPage) bool {
 	return true
 }
 
+// store in `result` the intersection of `a` and `b`,
+// returning false if it is empty
+func (result *charPage) intersectLeaf(al, bl charPage) bool {
+	nonempty := false
+	for i := range result {
+		v := al[i] & bl[i]
+		result[i] = v
+		if v != 0 {
+			nonempty = true
+		}
+	}
+	return nonempty
+}
+
 func (result *charPage) subtractLeaf(al, bl charPage) bool {
 	nonempty := false
 	for i := range result {
@@ -392,7 +572,7 @@ func (result *charPage) subtractLeaf(al, bl charPage) bool {
 
 // Returns a set including only those chars found in `a` but not `b`.
 func charsetSubtract(a, b Charset) Charset {
-	return operate(a, b, (*charPage).subtractLeaf, false)
+	return operate(a, b, (*charPage).subtractLeaf, true, false)
 }
 
 // charsetIter is an iterator for the leaves of a charset
@@ -441,3 +621,10 @@ func (iter *charsetIter) next() {
 	iter.pos += 1
 	iter.updateLeaf()
 }
+
+// skipEmpty advances to the next non empty leaf
+func (iter *charsetIter) skipEmpty() {
+	for iter.leaf != nil && *iter.leaf == (charPage{}) {
+		iter.next()
+	}
+}
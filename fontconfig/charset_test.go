@@ -2,6 +2,7 @@ package fontconfig
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -120,3 +121,81 @@ func TestMergeMany(t *testing.T) {
 	}
 	fmt.Printf("Merging sequentially %d charsets: %s\n", len(charsets), time.Since(ti))
 }
+
+func TestCharsetOperations(t *testing.T) {
+	a := NewCharsetFromRanges([][2]rune{{0x20, 0x7e}, {0xa0, 0x17f}, {0x3000, 0x3000}})
+	b := NewCharsetFromRanges([][2]rune{{0x41, 0x5a}, {0x100, 0x2ff}, {0x10000, 0x1000f}, {5, 2}})
+
+	if L := a.Len(); L != 0x7e-0x20+1+0x17f-0xa0+1+1 {
+		t.Fatalf("unexpected length %d", L)
+	}
+
+	union := a.Union(b)
+	inter := a.Intersect(b)
+	sub := a.Subtract(b)
+	for _, r := range []rune{0, 0x20, 0x41, 0x7f, 0xff, 0x100, 0x17f, 0x180, 0x2ff, 0x3000, 0x10005, 0x10010} {
+		inA, inB := a.HasChar(r), b.HasChar(r)
+		if union.HasChar(r) != (inA || inB) || inter.HasChar(r) != (inA && inB) || sub.HasChar(r) != (inA && !inB) {
+			t.Fatalf("rune %x: invalid operation", r)
+		}
+	}
+
+	if exp := [][2]rune{{0x41, 0x5a}, {0x100, 0x17f}}; !reflect.DeepEqual(inter.Ranges(), exp) {
+		t.Fatalf("expected %v, got %v", exp, inter.Ranges())
+	}
+	if !inter.IsSubset(a) || !inter.IsSubset(b) || a.IsSubset(b) || !sub.Union(inter).Equal(a) {
+		t.Fatal("invalid subset relations")
+	}
+	if !a.Intersect(Charset{}).Equal(Charset{}) || !a.Subtract(a).Equal(Charset{}) {
+		t.Fatal("expected empty sets")
+	}
+
+	// empty leaves are ignored
+	c := NewCharsetFromRanges([][2]rune{{0x20, 0x7e}, {0x500, 0x500}})
+	c.DelChar(0x500)
+	d := NewCharsetFromRanges([][2]rune{{0x20, 0x7e}})
+	if !c.Equal(d) || !d.Equal(c) || !c.IsSubset(d) {
+		t.Fatal("empty leaves should be ignored")
+	}
+
+	// ranges spanning several pages
+	e := NewCharsetFromRanges([][2]rune{{0xf0, 0x310}, {0x312, 0x312}})
+	iter := e.RangeIterator()
+	var ranges [][2]rune
+	for lo, hi, ok := iter.Next(); ok; lo, hi, ok = iter.Next() {
+		ranges = append(ranges, [2]rune{lo, hi})
+	}
+	if exp := [][2]rune{{0xf0, 0x310}, {0x312, 0x312}}; !reflect.DeepEqual(ranges, exp) {
+		t.Fatalf("expected %v, got %v", exp, ranges)
+	}
+	if _, _, ok := iter.Next(); ok {
+		t.Fatal("iterator should be exhausted")
+	}
+	if r := (Charset{}).Ranges(); r != nil {
+		t.Fatalf("unexpected ranges %v", r)
+	}
+}
+
+func TestCharsetText(t *testing.T) {
+	cs := NewCharsetFromRanges([][2]rune{{0x20, 0x7e}, {0xa0, 0xa0}, {0x1f600, 0x1f64f}})
+	text := cs.Text()
+	if exp := "20-7e a0 1f600-1f64f"; text != exp {
+		t.Fatalf("expected %s, got %s", exp, text)
+	}
+	parsed, err := ParseCharset(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(cs) {
+		t.Fatalf("invalid roundtrip for %s", text)
+	}
+	if parsed, err = ParseCharset("20 - 22 30- 31"); err != nil || parsed.Text() != "20-22 30-31" {
+		t.Fatalf("unexpected parsed charset %s (%v)", parsed.Text(), err)
+	}
+
+	for _, invalid := range []string{"zz", "30-20", "20-110000"} {
+		if _, err := ParseCharset(invalid); err == nil {
+			t.Fatalf("expected error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// fc-list lists the fonts matching a pattern, as its C counterpart.
+//
+//	go run fc-list.go [-cache fonts.cache] [-format fmt] [pattern [element...]]
+//
+// The fonts are loaded from the cache file, if given, or
+// scanned from the default directories with the standard configuration.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+	"github.com/benoitkugler/textprocessing/fontconfig/cmd/internal/cmdutil"
+)
+
+// errNoMatch is returned in quiet mode when no fonts matched
+var errNoMatch = errors.New("no fonts matched")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err == errNoMatch {
+		os.Exit(1)
+	} else if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fc-list", flag.ContinueOnError)
+	cache := flags.String("cache", "", "font set cache file, created if needed (default to scanning the system fonts)")
+	format := flags.String("format", "", "use the given output format")
+	verbose := flags.Bool("verbose", false, "display entire font pattern verbosely")
+	quiet := flags.Bool("quiet", false, "suppress all normal output, exit 1 if no fonts matched")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [options] [pattern [element...]]\n", flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	pattern := fontconfig.NewPattern()
+	if flags.NArg() >= 1 {
+		var err error
+		pattern, err = fontconfig.ParseName(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("unable to parse the pattern: %s", err)
+		}
+	}
+
+	var objects []fontconfig.Object
+	if flags.NArg() >= 2 {
+		var err error
+		objects, err = cmdutil.ParseObjects(fontconfig.Standard, flags.Args()[1:])
+		if err != nil {
+			return err
+		}
+	} else if !*verbose {
+		objects = []fontconfig.Object{fontconfig.FAMILY, fontconfig.STYLE, fontconfig.FILE}
+	}
+
+	fs, err := cmdutil.LoadFontset(*cache)
+	if err != nil {
+		return err
+	}
+
+	fs = fs.List(pattern, objects...)
+
+	if *quiet {
+		if len(fs) == 0 {
+			return errNoMatch
+		}
+		return nil
+	}
+
+	tmpl := *format
+	if tmpl == "" && !*verbose {
+		if flags.NArg() >= 2 {
+			tmpl = "%{=unparse}\n"
+		} else {
+			tmpl = "%{=fclist}\n"
+		}
+	}
+	return cmdutil.PrintFonts(out, fs, tmpl)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+// writeCache scans the test fonts and stores them in a cache file
+func writeCache(t *testing.T) string {
+	fs, err := fontconfig.Standard.ScanFontDirectories("../../test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "fonts.cache")
+	f, err := os.Create(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = fs.Serialize(f); err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestList(t *testing.T) {
+	cache := writeCache(t)
+
+	var buf bytes.Buffer
+	if err := run([]string{"-cache", cache, "-format", "%{file|basename}\n", ":spacing=charcell"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	files := strings.Fields(buf.String())
+	if len(files) != 3 { // 4x6.pcf, 8x16.pcf and 8x16.bdf
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+	for _, file := range files {
+		if ext := filepath.Ext(file); ext != ".pcf" && ext != ".bdf" {
+			t.Fatalf("unexpected file %s", file)
+		}
+	}
+
+	buf.Reset()
+	if err := run([]string{"-cache", cache, "Misc Fixed:pixelsize=6", "family", "pixelsize"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "Misc Fixed:pixelsize=6\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	if err := run([]string{"-cache", cache, "-quiet", "Unknown Family"}, &buf); err != errNoMatch {
+		t.Fatalf("expected no match, got %v", err)
+	}
+	if err := run([]string{"-cache", cache, "family", "unknown"}, &buf); err == nil {
+		t.Fatal("expected error for unknown element")
+	}
+}
@@ -0,0 +1,105 @@
+// fc-match returns the best font matching a pattern, as its C counterpart.
+//
+//	go run fc-match.go [-cache fonts.cache] [-sort] [-all] [-format fmt] [pattern [element...]]
+//
+// The fonts are loaded from the cache file, if given, or
+// scanned from the default directories with the standard configuration,
+// which is also used to perform the substitutions on the pattern.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+	"github.com/benoitkugler/textprocessing/fontconfig/cmd/internal/cmdutil"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil && err != flag.ErrHelp {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fc-match", flag.ContinueOnError)
+	cache := flags.String("cache", "", "font set cache file, created if needed (default to scanning the system fonts)")
+	sort := flags.Bool("sort", false, "display sorted list of matches")
+	all := flags.Bool("all", false, "display unpruned sorted list of matches")
+	format := flags.String("format", "", "use the given output format")
+	verbose := flags.Bool("verbose", false, "display entire font pattern verbosely")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [options] [pattern [element...]]\n", flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config := fontconfig.Standard
+
+	pattern := fontconfig.NewPattern()
+	if flags.NArg() >= 1 {
+		var err error
+		pattern, err = fontconfig.ParseName(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("unable to parse the pattern: %s", err)
+		}
+	}
+
+	var objects []fontconfig.Object
+	if flags.NArg() >= 2 {
+		var err error
+		objects, err = cmdutil.ParseObjects(config, flags.Args()[1:])
+		if err != nil {
+			return err
+		}
+	}
+
+	fs, err := cmdutil.LoadFontset(*cache)
+	if err != nil {
+		return err
+	}
+
+	config.Substitute(pattern, nil, fontconfig.MatchQuery)
+	pattern.SubstituteDefault()
+
+	var results fontconfig.Fontset
+	if *sort || *all {
+		sorted, _ := fs.Sort(pattern, !*all)
+		for _, font := range sorted {
+			results = append(results, config.PrepareRender(pattern, font))
+		}
+	} else if match := fs.Match(pattern, config); match != nil {
+		results = fontconfig.Fontset{match}
+	}
+
+	if len(objects) != 0 { // only keep the required elements
+		for i, font := range results {
+			filtered := fontconfig.NewPattern()
+			for _, object := range objects {
+				for j := 0; ; j++ {
+					v, res := font.GetAt(object, j)
+					if res != fontconfig.ResultMatch {
+						break
+					}
+					filtered.Add(object, v, true)
+				}
+			}
+			results[i] = filtered
+		}
+	}
+
+	tmpl := *format
+	if tmpl == "" && !*verbose {
+		if len(objects) != 0 {
+			tmpl = "%{=unparse}\n"
+		} else {
+			tmpl = "%{=fcmatch}\n"
+		}
+	}
+	return cmdutil.PrintFonts(out, results, tmpl)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+// writeCache scans the test fonts and stores them in a cache file
+func writeCache(t *testing.T) string {
+	fs, err := fontconfig.Standard.ScanFontDirectories("../../test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "fonts.cache")
+	f, err := os.Create(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = fs.Serialize(f); err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestMatch(t *testing.T) {
+	cache := writeCache(t)
+
+	var buf bytes.Buffer
+	if err := run([]string{"-cache", cache, "Misc Fixed:pixelsize=6"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "4x6.pcf: \"Misc Fixed\" \"Regular\"\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	buf.Reset()
+	if err := run([]string{"-cache", cache, "-sort", "-format", "%{file|basename}\n", "Sony Fixed:pixelsize=16"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if files := strings.Fields(buf.String()); len(files) == 0 || !strings.HasPrefix(files[0], "8x16.") {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := run([]string{"-cache", cache, "Misc Fixed:pixelsize=6", "pixelsize"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != ":pixelsize=6\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+}
@@ -0,0 +1,81 @@
+// fc-query queries the font files and prints the resulting patterns,
+// as its C counterpart.
+//
+//	go run fc-query.go [-index i] [-format fmt] font-file...
+//
+// No configuration is applied.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+	"github.com/benoitkugler/textprocessing/fontconfig/cmd/internal/cmdutil"
+)
+
+// errFailed is returned when some files could not be queried
+var errFailed = errors.New("some files could not be queried")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err == errFailed {
+		os.Exit(1)
+	} else if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fc-query", flag.ContinueOnError)
+	index := flags.Int("index", -1, "display only the font face with the given index (default to all faces)")
+	format := flags.String("format", "", "use the given output format (default to a verbose description)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [options] font-file...\n", flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing font file")
+	}
+
+	config := fontconfig.NewConfig()
+	failed := false
+	for _, file := range flags.Args() {
+		fs, err := config.ScanFontFile(file)
+		if err != nil {
+			log.Printf("can't query face %s: %s", file, err)
+			failed = true
+			continue
+		}
+
+		if *index != -1 {
+			var filtered fontconfig.Fontset
+			for _, font := range fs {
+				if id, _ := font.GetInt(fontconfig.INDEX); int(id) == *index {
+					filtered = append(filtered, font)
+				}
+			}
+			fs = filtered
+		}
+
+		if err = cmdutil.PrintFonts(out, fs, *format); err != nil {
+			return err
+		}
+	}
+
+	if failed {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"-format", "%{family}:%{pixelsize}\n", "../../test/4x6.pcf", "../../test/8x16.bdf"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "Misc Fixed:6\nSony Fixed:16\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+
+	buf.Reset()
+	if err := run([]string{"-index", "1", "-format", "%{family}\n", "../../test/4x6.pcf"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	if err := run([]string{"../../test/fonts.conf.in"}, &buf); err != errFailed {
+		t.Fatalf("expected query failure, got %v", err)
+	}
+	if err := run(nil, &buf); err == nil {
+		t.Fatal("expected error for missing files")
+	}
+}
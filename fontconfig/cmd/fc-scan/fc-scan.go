@@ -0,0 +1,64 @@
+// fc-scan scans the font files and directories, and prints the resulting patterns,
+// as its C counterpart.
+//
+//	go run fc-scan.go [-format fmt] file-or-directory...
+//
+// The standard configuration is applied.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+	"github.com/benoitkugler/textprocessing/fontconfig/cmd/internal/cmdutil"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil && err != flag.ErrHelp {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("fc-scan", flag.ContinueOnError)
+	format := flags.String("format", "", "use the given output format (default to a verbose description)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [options] file-or-directory...\n", flags.Name())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing file or directory")
+	}
+
+	config := fontconfig.Standard
+	var fs fontconfig.Fontset
+	for _, path := range flags.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		var scanned fontconfig.Fontset
+		if info.IsDir() {
+			scanned, err = config.ScanFontDirectories(path)
+		} else {
+			scanned, err = config.ScanFontFile(path)
+		}
+		if err != nil {
+			return err
+		}
+		fs = append(fs, scanned...)
+	}
+
+	return cmdutil.PrintFonts(out, fs, *format)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"-format", "%{file}\n", "../../test", "../../test/4x6.pcf"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	files := strings.Fields(buf.String())
+	if len(files) < 2 || files[len(files)-1] != "../../test/4x6.pcf" {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+
+	if err := run([]string{"../../test/missing"}, &buf); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
@@ -0,0 +1,60 @@
+// Package cmdutil provides the helpers shared by the command line tools.
+package cmdutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+// LoadFontset returns the fonts stored in the `cache` file, which is created
+// (see `fontconfig.ScanAndCache`) if it does not exist.
+// If `cache` is empty, the fonts are scanned from the default directories
+// with the standard configuration.
+func LoadFontset(cache string) (fontconfig.Fontset, error) {
+	if cache == "" {
+		dirs, err := fontconfig.DefaultFontDirs()
+		if err != nil {
+			return nil, err
+		}
+		return fontconfig.Standard.ScanFontDirectories(dirs...)
+	}
+	if _, err := os.Stat(cache); err != nil {
+		return fontconfig.ScanAndCache(cache)
+	}
+	return fontconfig.LoadFontsetFile(cache)
+}
+
+// ParseObjects resolves the object names given on the command line,
+// both builtin and known by `config`.
+func ParseObjects(config *fontconfig.Config, names []string) ([]fontconfig.Object, error) {
+	out := make([]fontconfig.Object, len(names))
+	for i, name := range names {
+		object, ok := config.LookupObject(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown element %s", name)
+		}
+		out[i] = object
+	}
+	return out, nil
+}
+
+// PrintFonts writes the fonts to `out`, using the `format` template
+// (see `fontconfig.Pattern.FormatString`), or a verbose description
+// if `format` is empty.
+func PrintFonts(out io.Writer, fonts fontconfig.Fontset, format string) error {
+	for _, font := range fonts {
+		if format == "" {
+			fmt.Fprintln(out, font)
+			continue
+		}
+		s, err := font.FormatString(format)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, s)
+	}
+	return nil
+}
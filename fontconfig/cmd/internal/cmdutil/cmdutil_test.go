@@ -0,0 +1,71 @@
+package cmdutil
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+func TestLoadFontset(t *testing.T) {
+	fs, err := fontconfig.NewConfig().ScanFontFile("../../../test/8x16.pcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(t.TempDir(), "fonts.cache")
+	f, err := os.Create(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.Serialize(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	loaded, err := LoadFontset(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != len(fs) || loaded[0].Hash() != fs[0].Hash() {
+		t.Fatalf("unexpected font set %v", loaded)
+	}
+}
+
+func TestParseObjects(t *testing.T) {
+	config := fontconfig.NewConfig()
+	custom, err := config.RegisterObject("myobject", fontconfig.KindString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := ParseObjects(config, []string{"family", "myobject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0] != fontconfig.FAMILY || objects[1] != custom {
+		t.Fatalf("unexpected objects %v", objects)
+	}
+	if _, err = ParseObjects(config, []string{"family", "unknown"}); err == nil {
+		t.Fatal("expected error for unknown object")
+	}
+}
+
+func TestPrintFonts(t *testing.T) {
+	p := fontconfig.NewPattern()
+	p.AddString(fontconfig.FAMILY, "Fixed")
+	var buf bytes.Buffer
+	if err := PrintFonts(&buf, fontconfig.Fontset{p, p}, "%{family}\n"); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != "Fixed\nFixed\n" {
+		t.Fatalf("unexpected output %q", s)
+	}
+	buf.Reset()
+	if err := PrintFonts(&buf, fontconfig.Fontset{p}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if s := buf.String(); s != p.String()+"\n" {
+		t.Fatalf("unexpected verbose output %q", s)
+	}
+}
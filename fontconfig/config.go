@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // ported from fontconfig/src/fccfg.c Copyright © 2000 Keith Packard
@@ -30,6 +31,8 @@ type Config struct {
 
 	// the name is user defined, and the object assigned by the library
 	customObjects map[string]Object
+	// the types of the objects registered with RegisterObject
+	customTypes map[Object]typeMeta
 
 	// List of patterns used to control font file selection
 	acceptGlobs    strSet
@@ -41,6 +44,18 @@ type Config struct {
 	// used to allocate appropriate intermediate storage
 	// for performing a whole set of substitutions
 	maxObjects int
+
+	// directories collected from <dir> and <cachedir> elements,
+	// see `LoadWithIncludes`
+	fontDirs, cacheDirs []string
+
+	// problems found when loading XML files, see `SetStrict`
+	warnings []*ConfigError
+	strict   bool
+
+	diagnostics diagnostics.Sink // optional, see SetDiagnostics
+
+	tracer func(RuleTrace) // optional, see SetTracer
 }
 
 // NewConfig returns a new empty, initialized configuration
@@ -70,6 +85,10 @@ func (c *Config) Copy() *Config {
 	for k, v := range c.customObjects {
 		out.customObjects[k] = v
 	}
+	out.customTypes = make(map[Object]typeMeta, len(c.customTypes))
+	for k, v := range c.customTypes {
+		out.customTypes[k] = v
+	}
 
 	out.acceptGlobs = make(strSet, len(c.acceptGlobs))
 	for k, v := range c.acceptGlobs {
@@ -89,6 +108,10 @@ func (c *Config) Copy() *Config {
 		out.rejectPatterns[i] = v.Duplicate()
 	}
 
+	out.fontDirs = append([]string(nil), c.fontDirs...)
+	out.cacheDirs = append([]string(nil), c.cacheDirs...)
+	out.warnings = append([]*ConfigError(nil), c.warnings...)
+
 	return &out
 }
 
@@ -117,8 +140,7 @@ func (config *Config) LoadFromDir(dir string) error {
 			return nil
 		}
 		// add all files of the form [0-9]*.conf
-		name := info.Name()
-		if isConf := name != "" && '0' <= name[0] && name[0] <= '9' && strings.HasSuffix(name, ".conf"); !isConf {
+		if !isConfFile(info.Name()) {
 			return nil // ignore the file
 		}
 
@@ -143,6 +165,11 @@ func (config *Config) LoadFromDir(dir string) error {
 	return err
 }
 
+// isConfFile returns true for names of the form [0-9]*.conf
+func isConfFile(name string) bool {
+	return name != "" && '0' <= name[0] && name[0] <= '9' && strings.HasSuffix(name, ".conf")
+}
+
 // ScanFontDirectories recursively scans the given directories, opening the
 // valid font files and building the associated font patterns.
 // Symbolic links for files are resolved, but not for directories.
@@ -193,7 +220,7 @@ func (config *Config) ScanFontRessource(content fonts.Resource, contentID string
 			out = append(out, f)
 		}
 	}
-	return fonts, nil
+	return out, nil
 }
 
 // Recursively scan a directory and build the font patterns,
@@ -276,6 +303,14 @@ func (config *Config) patternsAdd(pattern Pattern, accept bool) {
 	*set = append(*set, pattern)
 }
 
+// AcceptFontFile returns true if the file at `path` would be scanned by
+// `ScanFontDirectories`: hidden files, files which are known not to be fonts
+// (like metrics files) and files rejected by a <selectfont> glob are ignored.
+// This is useful to monitor font directories.
+func (config *Config) AcceptFontFile(path string) bool {
+	return validFontFile(filepath.Base(path)) && config.acceptFilename(path)
+}
+
 // uses filename-based font source selectors to accept/reject a file
 func (config *Config) acceptFilename(filename string) bool {
 	globsMatch := func(globs strSet, name string) bool {
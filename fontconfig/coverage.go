@@ -0,0 +1,106 @@
+package fontconfig
+
+import (
+	"sync"
+	"unicode"
+
+	"github.com/benoitkugler/textlayout/language"
+)
+
+// CoveringFonts returns a fallback chain of fonts from `set` covering the runes of `text`.
+// The fonts are sorted by closeness to `base`, as for `Sort`, and trimmed:
+// a font is only included if it covers runes not covered by the previous fonts.
+// Control characters, which are not rendered, are ignored.
+// The runes covered by no font are returned in `missing`.
+// As for `Sort`, this function should be called only after `Config.Substitute`
+// and `Pattern.SubstituteDefault` have been called for `base`.
+func (set Fontset) CoveringFonts(text []rune, base Pattern) (fonts Fontset, missing Charset) {
+	for _, r := range text {
+		if !unicode.IsControl(r) {
+			missing.AddChar(r)
+		}
+	}
+
+	for _, node := range set.sortNodes(base) {
+		if missing.Len() == 0 {
+			break
+		}
+		cs, ok := node.pattern.GetCharset(CHARSET)
+		if !ok {
+			continue
+		}
+		covered := missing.Intersect(cs)
+		if covered.Len() == 0 {
+			continue
+		}
+		fonts = append(fonts, node.pattern)
+		missing = missing.Subtract(covered)
+	}
+
+	return fonts, missing
+}
+
+// FontsForScript returns the fonts from `set` supporting at least one of the
+// languages written with `script`, as reported by their LANG object.
+// The order of `set` is preserved.
+func (set Fontset) FontsForScript(script language.Script) Fontset {
+	langs := scriptLangs()[script]
+	if len(langs) == 0 {
+		return nil
+	}
+
+	var out Fontset
+	for _, font := range set {
+		v, _ := font.GetAt(LANG, 0)
+		var ls Langset
+		switch v := v.(type) {
+		case Langset:
+			ls = v
+		case String:
+			ls = langSetPromote(v)
+		default:
+			continue
+		}
+		for _, lang := range langs {
+			if ls.HasLang(lang) == LangEqual {
+				out = append(out, font)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// the minimum number of runes of a script in an orthography,
+// for the language to be considered as written with it
+const minScriptRunes = 10
+
+var (
+	scriptLangsOnce sync.Once
+	scriptLangsMap  map[language.Script][]string
+)
+
+// scriptLangs returns the languages with an orthography
+// using each script, computed on first use.
+// The languages are sorted, as in `fcLangCharSets`.
+func scriptLangs() map[language.Script][]string {
+	scriptLangsOnce.Do(func() {
+		scriptLangsMap = make(map[language.Script][]string)
+		for _, lcs := range fcLangCharSets {
+			counts := make(map[language.Script]int)
+			for _, ra := range lcs.charset.Ranges() {
+				for r := ra[0]; r <= ra[1]; r++ {
+					if script := language.LookupScript(r); script.IsRealScript() {
+						counts[script]++
+					}
+				}
+			}
+			for script, count := range counts {
+				if count >= minScriptRunes {
+					scriptLangsMap[script] = append(scriptLangsMap[script], lcs.lang)
+				}
+			}
+		}
+	})
+	return scriptLangsMap
+}
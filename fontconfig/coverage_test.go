@@ -0,0 +1,82 @@
+package fontconfig
+
+import (
+	"testing"
+
+	"github.com/benoitkugler/textlayout/language"
+)
+
+func coverageFont(family string, weight float32, charset Charset) Pattern {
+	return BuildPattern(
+		PatternElement{Object: FAMILY, Value: String(family)},
+		PatternElement{Object: WEIGHT, Value: Float(weight)},
+		PatternElement{Object: CHARSET, Value: charset},
+		PatternElement{Object: LANG, Value: BuildLangset(charset, "")},
+	)
+}
+
+func TestCoveringFonts(t *testing.T) {
+	latin := NewCharsetFromRanges([][2]rune{{' ', '~'}, {0xA0, 0x17F}})
+	greek := NewCharsetFromRanges([][2]rune{{' ', '~'}, {0x370, 0x3FF}})
+	arabic := NewCharsetFromRanges([][2]rune{{' ', ' '}, {0x600, 0x6FF}})
+	set := Fontset{
+		coverageFont("Arabic", WEIGHT_REGULAR, arabic),
+		coverageFont("Greek", WEIGHT_REGULAR, greek),
+		coverageFont("Latin", WEIGHT_REGULAR, latin),
+		coverageFont("Latin", WEIGHT_BOLD, latin),
+	}
+
+	base := NewPattern()
+	base.AddString(FAMILY, "Latin")
+	base.AddFloat(WEIGHT, WEIGHT_BOLD)
+	base.SubstituteDefault()
+
+	fonts, missing := set.CoveringFonts([]rune("Café αβγ\n"), base)
+	if missing.Len() != 0 {
+		t.Fatalf("unexpected missing runes %s", missing.Text())
+	}
+	if len(fonts) != 2 || fonts[0].Hash() != set[3].Hash() || fonts[1].Hash() != set[1].Hash() {
+		t.Fatalf("unexpected fallback chain %v", fonts)
+	}
+
+	fonts, missing = set.CoveringFonts([]rune("abc ب 中"), base)
+	if missing.Text() != "4e2d" {
+		t.Fatalf("unexpected missing runes %s", missing.Text())
+	}
+	if len(fonts) != 2 || fonts[0].Hash() != set[3].Hash() || fonts[1].Hash() != set[0].Hash() {
+		t.Fatalf("unexpected fallback chain %v", fonts)
+	}
+
+	if fonts, missing = set.CoveringFonts(nil, base); len(fonts) != 0 || missing.Len() != 0 {
+		t.Fatal("expected no fonts")
+	}
+}
+
+func TestFontsForScript(t *testing.T) {
+	ls := func(langs string) Pattern {
+		return BuildPattern(PatternElement{Object: LANG, Value: NewLangset(langs)})
+	}
+	set := Fontset{ls("en|fr"), ls("ar|fa"), ls("ja"), ls("ru|en"), NewPattern()}
+
+	for _, test := range []struct {
+		script   language.Script
+		expected []int
+	}{
+		{language.Latin, []int{0, 3}},
+		{language.Arabic, []int{1}},
+		{language.Katakana, []int{2}},
+		{language.Cyrillic, []int{3}},
+		{language.Thai, nil},
+		{language.Common, nil},
+	} {
+		got := set.FontsForScript(test.script)
+		if len(got) != len(test.expected) {
+			t.Fatalf("for %s, expected %d fonts, got %d", test.script, len(test.expected), len(got))
+		}
+		for i, index := range test.expected {
+			if got[i].Hash() != set[index].Hash() {
+				t.Fatalf("for %s, unexpected font %s", test.script, got[i])
+			}
+		}
+	}
+}
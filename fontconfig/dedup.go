@@ -0,0 +1,136 @@
+package fontconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// DedupPolicy chooses, among faces installed several times, the one to keep.
+// `faces` contains at least two patterns, coming from different files, in
+// the order of the fontset. It returns the index of the chosen face.
+type DedupPolicy func(faces Fontset) int
+
+// NewestVersion is the default deduplication policy.
+// It keeps the face with the highest FONTVERSION, or the first one
+// if several faces have the same version.
+func NewestVersion(faces Fontset) int {
+	best, bestVersion := 0, int32(-1)
+	for i, face := range faces {
+		version, _ := face.GetInt(FONTVERSION)
+		if version > bestVersion {
+			best, bestVersion = i, version
+		}
+	}
+	return best
+}
+
+// DuplicateFaces is a group of faces found in several files,
+// as reported by `Fontset.Deduplicate`.
+type DuplicateFaces struct {
+	Kept     Pattern // the face chosen by the policy
+	Shadowed Fontset // the faces removed from the fontset
+}
+
+// ShadowedFiles returns the (sorted) files of the removed faces.
+func (d DuplicateFaces) ShadowedFiles() []string {
+	seen := make(strSet)
+	var out []string
+	for _, face := range d.Shadowed {
+		file, _ := face.GetString(FILE)
+		if !seen[file] {
+			seen[file] = true
+			out = append(out, file)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// dedupKey identifies a face, ignoring its version.
+// The pixel size and spacing distinguish the strikes of bitmap fonts,
+// which usually share their names.
+type dedupKey struct {
+	family, style, postscript string
+	pixelSize                 float32
+	spacing                   int32
+}
+
+func newDedupKey(face Pattern) dedupKey {
+	family, _ := face.GetString(FAMILY)
+	style, _ := face.GetString(STYLE)
+	postscript, _ := face.GetString(POSTSCRIPT_NAME)
+	pixelSize, _ := face.GetFloat(PIXEL_SIZE)
+	spacing, _ := face.GetInt(SPACING)
+	return dedupKey{
+		family:     strings.ToLower(family),
+		style:      strings.ToLower(style),
+		postscript: postscript,
+		pixelSize:  pixelSize,
+		spacing:    spacing,
+	}
+}
+
+// Deduplicate removes the faces installed several times, for instance in different
+// directories or with different formats. The faces are grouped by family, style,
+// PostScript name (case insensitively for the family and style), pixel size and
+// spacing (so that the strikes of bitmap fonts are kept), and
+// `policy` is used to choose, in each group, the face to keep, based for instance
+// on its FONTVERSION. If `policy` is nil, `NewestVersion` is used.
+// The faces coming from the same file as the chosen one are also kept, so that
+// a group is only considered when its faces come from at least two files.
+// The order of the kept faces is preserved, and the removed ones
+// are returned, grouped, in the order of the set.
+func (set Fontset) Deduplicate(policy DedupPolicy) (Fontset, []DuplicateFaces) {
+	if policy == nil {
+		policy = NewestVersion
+	}
+
+	var keys []dedupKey // in order of appearance
+	groups := make(map[dedupKey][]int)
+	for i, face := range set {
+		key := newDedupKey(face)
+		if _, has := groups[key]; !has {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	shadowed := make([]bool, len(set))
+	var duplicates []DuplicateFaces
+	for _, key := range keys {
+		indices := groups[key]
+		if len(indices) < 2 {
+			continue
+		}
+
+		faces := make(Fontset, len(indices))
+		files := make(strSet)
+		for i, index := range indices {
+			faces[i] = set[index]
+			file, _ := faces[i].GetString(FILE)
+			files[file] = true
+		}
+		if len(files) < 2 {
+			continue
+		}
+
+		kept := faces[policy(faces)]
+		keptFile, _ := kept.GetString(FILE)
+		group := DuplicateFaces{Kept: kept}
+		for i, face := range faces {
+			if file, _ := face.GetString(FILE); file != keptFile {
+				shadowed[indices[i]] = true
+				group.Shadowed = append(group.Shadowed, face)
+			}
+		}
+		duplicates = append(duplicates, group)
+	}
+
+	out := make(Fontset, 0, len(set))
+	for i, face := range set {
+		if !shadowed[i] {
+			out = append(out, face)
+		}
+	}
+	return out, duplicates
+}
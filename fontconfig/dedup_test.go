@@ -0,0 +1,132 @@
+package fontconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func dedupFace(family, style, file string, version int32) Pattern {
+	return BuildPattern(
+		PatternElement{Object: FAMILY, Value: String(family)},
+		PatternElement{Object: STYLE, Value: String(style)},
+		PatternElement{Object: FILE, Value: String(file)},
+		PatternElement{Object: FONTVERSION, Value: Int(version)},
+	)
+}
+
+func TestDeduplicate(t *testing.T) {
+	set := Fontset{
+		dedupFace("Sans", "Regular", "/usr/share/fonts/sans.ttf", 0x10000),
+		dedupFace("Sans", "Bold", "/usr/share/fonts/sans-bold.ttf", 0x10000),
+		dedupFace("sans", "regular", "/home/user/.fonts/sans.otf", 0x20000),
+		dedupFace("Serif", "Regular", "/usr/share/fonts/serif.ttc", 0x10000),
+		dedupFace("Serif", "Regular", "/usr/share/fonts/serif.ttc", 0x10000), // same file
+		dedupFace("Sans", "Bold", "/home/user/.fonts/sans-bold.otf", 0x10000),
+	}
+
+	out, duplicates := set.Deduplicate(nil)
+	if len(out) != 4 || len(duplicates) != 2 {
+		t.Fatalf("unexpected deduplication %d %v", len(out), duplicates)
+	}
+	// newest version
+	if duplicates[0].Kept.Hash() != set[2].Hash() || len(duplicates[0].Shadowed) != 1 {
+		t.Fatalf("unexpected duplicates %v", duplicates[0])
+	}
+	if files := duplicates[0].ShadowedFiles(); !reflect.DeepEqual(files, []string{"/usr/share/fonts/sans.ttf"}) {
+		t.Fatalf("unexpected shadowed files %v", files)
+	}
+	// first one for same versions
+	if files := duplicates[1].ShadowedFiles(); !reflect.DeepEqual(files, []string{"/home/user/.fonts/sans-bold.otf"}) {
+		t.Fatalf("unexpected shadowed files %v", files)
+	}
+
+	// custom policy: prefer system fonts
+	out, duplicates = set.Deduplicate(func(faces Fontset) int {
+		for i, face := range faces {
+			if file, _ := face.GetString(FILE); filepath.Dir(file) == "/usr/share/fonts" {
+				return i
+			}
+		}
+		return 0
+	})
+	if len(out) != 4 || len(duplicates) != 2 || duplicates[0].Kept.Hash() != set[0].Hash() {
+		t.Fatalf("unexpected deduplication %v", duplicates)
+	}
+	for i, index := range []int{0, 1, 3, 4} {
+		if out[i].Hash() != set[index].Hash() {
+			t.Fatalf("unexpected font %s", out[i])
+		}
+	}
+}
+
+func TestDeduplicateScan(t *testing.T) {
+	data, err := ioutil.ReadFile("test/DejaVuSerif-Italic.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, dir := range []string{dir1, dir2} {
+		if err = ioutil.WriteFile(filepath.Join(dir, "DejaVuSerif-Italic.ttf"), data, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set, err := NewConfig().ScanFontDirectories(dir1, dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, duplicates := set.Deduplicate(nil)
+	if len(set) != 2 || len(out) != 1 || len(duplicates) != 1 {
+		t.Fatalf("unexpected deduplication %d %d %d", len(set), len(out), len(duplicates))
+	}
+	if files := duplicates[0].ShadowedFiles(); !reflect.DeepEqual(files, []string{filepath.Join(dir2, "DejaVuSerif-Italic.ttf")}) {
+		t.Fatalf("unexpected shadowed files %v", files)
+	}
+}
+
+func TestDeduplicateBitmapStrikes(t *testing.T) {
+	strike := func(file string, pixelSize float32) Pattern {
+		p := dedupFace("Fixed", "Regular", file, 0)
+		p.AddFloat(PIXEL_SIZE, pixelSize)
+		p.AddInt(SPACING, CHARCELL)
+		return p
+	}
+	set := Fontset{
+		strike("/usr/share/fonts/misc/6x13.pcf", 13),
+		strike("/usr/share/fonts/misc/9x18.pcf", 18),
+		strike("/home/user/.fonts/9x18.bdf", 18),
+	}
+	out, duplicates := set.Deduplicate(nil)
+	if len(out) != 2 || len(duplicates) != 1 {
+		t.Fatalf("unexpected deduplication %d %v", len(out), duplicates)
+	}
+	if files := duplicates[0].ShadowedFiles(); !reflect.DeepEqual(files, []string{"/home/user/.fonts/9x18.bdf"}) {
+		t.Fatalf("unexpected shadowed files %v", files)
+	}
+
+	// the same strike, in two formats
+	dir := t.TempDir()
+	for _, file := range []string{"4x6.pcf", "8x16.pcf", "8x16.bdf"} {
+		data, err := ioutil.ReadFile(filepath.Join("test", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(dir, file), data, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	set, err := NewConfig().ScanFontDirectories(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, duplicates = set.Deduplicate(nil)
+	if len(set) != 3 || len(out) != 2 || len(duplicates) != 1 {
+		t.Fatalf("unexpected deduplication %d %d %d", len(set), len(out), len(duplicates))
+	}
+	if size, _ := duplicates[0].Kept.GetFloat(PIXEL_SIZE); size != 16 {
+		t.Fatalf("unexpected kept strike %s", duplicates[0].Kept)
+	}
+}
@@ -0,0 +1,34 @@
+package fontconfig
+
+import "github.com/benoitkugler/textprocessing/diagnostics"
+
+var packageDiagnostics diagnostics.Var
+
+// SetDiagnostics sets the sink receiving the problems found by this package,
+// which are not reported as errors (like invalid values or font files).
+// By default, nothing is reported.
+// See also `Config.SetDiagnostics`.
+func SetDiagnostics(sink diagnostics.Sink) { packageDiagnostics.Set(sink) }
+
+// SetDiagnostics sets the sink receiving the problems found when
+// using the configuration (loading XML files or scanning fonts),
+// instead of the package sink (see `SetDiagnostics`).
+// Passing nil restores the package sink.
+func (config *Config) SetDiagnostics(sink diagnostics.Sink) { config.diagnostics = sink }
+
+// report sends `d` to the package sink
+func report(d diagnostics.Diagnostic) {
+	d.Package = "fontconfig"
+	packageDiagnostics.Report(d)
+}
+
+// report sends `d` to the sink of the config, if any,
+// or to the package sink
+func (config *Config) report(d diagnostics.Diagnostic) {
+	if config == nil || config.diagnostics == nil {
+		report(d)
+		return
+	}
+	d.Package = "fontconfig"
+	config.diagnostics.Report(d)
+}
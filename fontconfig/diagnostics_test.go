@@ -0,0 +1,54 @@
+package fontconfig
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
+)
+
+type recorder []diagnostics.Diagnostic
+
+func (r *recorder) Report(d diagnostics.Diagnostic) { *r = append(*r, d) }
+
+func TestDiagnostics(t *testing.T) {
+	var pkg, cfg recorder
+	SetDiagnostics(&pkg)
+	defer SetDiagnostics(nil)
+
+	p := NewPattern()
+	p.Add(WEIGHT, String("bold"), true)
+	if len(pkg) != 1 || pkg[0].Category != diagnostics.InvalidValue || pkg[0].Package != "fontconfig" {
+		t.Fatalf("unexpected diagnostics %v", pkg)
+	}
+
+	config := NewConfig()
+	config.SetDiagnostics(&cfg)
+	fsys := fstest.MapFS{"fonts/invalid.ttf": &fstest.MapFile{Data: []byte("not a font")}}
+	if _, err := config.ScanFontFS(fsys, "fonts"); err != nil {
+		t.Fatal(err)
+	}
+	err := config.LoadFromMemory(strings.NewReader(`<fontconfig><match><edit name="size"><name target="font">size</name></edit></match></fontconfig>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg) != 2 || len(pkg) != 1 {
+		t.Fatalf("unexpected diagnostics %v %v", cfg, pkg)
+	}
+	if d := cfg[0]; d.Category != diagnostics.InvalidFont || d.File != "fonts/invalid.ttf" {
+		t.Fatalf("unexpected diagnostic %v", d)
+	}
+	if d := cfg[1]; d.Category != diagnostics.InvalidConfig || d.File != "memory" {
+		t.Fatalf("unexpected diagnostic %v", d)
+	}
+
+	// the package sink is used by default
+	config.SetDiagnostics(nil)
+	if _, err := config.ScanFontFS(fsys, "fonts"); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkg) != 2 || pkg[1].Category != diagnostics.InvalidFont {
+		t.Fatalf("unexpected diagnostics %v", pkg)
+	}
+}
@@ -0,0 +1,303 @@
+package fontconfig
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// This file implements a cache organised by directory, as the C library does:
+// each directory is associated to one cache file, storing the fonts directly
+// in the directory and the list of its sub-directories.
+// A cache entry is invalidated when the modification time of its directory changes,
+// which happens when files are added, removed or renamed (but not when a file is
+// modified in place).
+
+// dirCacheMagic identifies the format of the cache files
+const dirCacheMagic = "fcgo-dircache-2"
+
+// dirCache is the content of one cache file.
+type dirCache struct {
+	dir        string
+	mtime      int64 // in nano seconds
+	configHash [sha1.Size]byte
+	subdirs    []dirCacheSubdir
+	fonts      Fontset // not filtered by the config selectors
+}
+
+type dirCacheSubdir struct {
+	name string // base name
+	// number of fonts before the sub-directory, so that the
+	// lexical order used by `ScanFontDirectories` is preserved
+	position int
+}
+
+// ScanFontDirectoriesCached is the same as `ScanFontDirectories`, but stores the result
+// of the scan in `cacheDir`, with one cache file per font directory.
+// On subsequent calls, only the directories which have been modified
+// are scanned again. The cache is also invalidated when the scan rules of
+// the configuration change.
+// `cacheDir` is created if needed. An error is returned if the cache files can't be written.
+func (config *Config) ScanFontDirectoriesCached(cacheDir string, dirs ...string) (Fontset, error) {
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("fontconfig: can't create cache directory: %s", err)
+	}
+
+	hash := config.scanRulesHash()
+	seen := make(strSet)
+	var out Fontset
+	for _, dir := range dirs {
+		fonts, err := config.readDirCached(cacheDir, filepath.Clean(dir), hash, seen)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fonts...)
+	}
+	return out, nil
+}
+
+// scanRulesHash returns a hash of the rules applied when scanning
+// fonts, so that caches are invalidated when they change.
+// The rules are hashed in their XML form, which includes all their values.
+func (config *Config) scanRulesHash() [sha1.Size]byte {
+	h := sha1.New()
+	wr := newXMLWriter(h, config)
+	for _, rs := range config.subst {
+		for _, directive := range rs.subst[MatchScan] {
+			wr.writeDirective(directive, MatchScan)
+		}
+	}
+	if wr.err == nil {
+		wr.err = wr.enc.Flush()
+	}
+	if wr.err != nil { // should not happen for valid rules
+		fmt.Fprintln(h, wr.err)
+	}
+	var out [sha1.Size]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+func (config *Config) readDirCached(cacheDir, dir string, configHash [sha1.Size]byte, seen strSet) (Fontset, error) {
+	if seen[dir] {
+		return nil, nil
+	}
+	seen[dir] = true
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid font location: %s", err)
+	}
+
+	cacheFile := dirCacheFile(cacheDir, dir)
+	cache, err := loadDirCache(cacheFile)
+	if err != nil || cache.dir != dir || cache.mtime != info.ModTime().UnixNano() || cache.configHash != configHash {
+		if debugMode {
+			fmt.Println("scanning (not cached)", dir)
+		}
+		cache, err = config.scanDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		cache.mtime = info.ModTime().UnixNano()
+		cache.configHash = configHash
+		if err = cache.save(cacheFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		out   Fontset
+		start int
+	)
+	addFonts := func(end int) {
+		for _, font := range cache.fonts[start:end] {
+			file, _ := font.GetString(FILE)
+			if config.acceptFilename(file) && config.acceptFont(font) {
+				out = append(out, font)
+			}
+		}
+		start = end
+	}
+	for _, subdir := range cache.subdirs {
+		addFonts(subdir.position)
+		fonts, err := config.readDirCached(cacheDir, filepath.Join(dir, subdir.name), configHash, seen)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, fonts...)
+	}
+	addFonts(len(cache.fonts))
+	return out, nil
+}
+
+// scanDir scans the fonts in `dir`, without recursing
+// in the sub-directories, and without applying the file and pattern selectors.
+func (config *Config) scanDir(dir string) (dirCache, error) {
+	out := dirCache{dir: dir}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return out, fmt.Errorf("invalid font location: %s", err)
+	}
+	for _, info := range entries {
+		path := filepath.Join(dir, info.Name())
+		if info.IsDir() {
+			out.subdirs = append(out.subdirs, dirCacheSubdir{name: info.Name(), position: len(out.fonts)})
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			path, err = filepath.EvalSymlinks(path)
+			if err != nil {
+				return out, err
+			}
+		}
+		if !validFontFile(info.Name()) {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return out, err
+		}
+		out.fonts = append(out.fonts, scanOneFontFile(file, path, config)...)
+		file.Close()
+	}
+	return out, nil
+}
+
+// dirCacheFile returns the path of the cache file for `dir`
+func dirCacheFile(cacheDir, dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	h := sha1.Sum([]byte(dir))
+	return filepath.Join(cacheDir, hex.EncodeToString(h[:])+".fccache")
+}
+
+func (dc dirCache) serialize(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(dirCacheMagic)
+
+	writeString := func(s string) {
+		var tmp [2]byte
+		binary.BigEndian.PutUint16(tmp[:], uint16(len(s)))
+		buf.Write(tmp[:])
+		buf.WriteString(s)
+	}
+
+	writeString(dc.dir)
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], uint64(dc.mtime))
+	buf.Write(tmp[:])
+	buf.Write(dc.configHash[:])
+	binary.BigEndian.PutUint32(tmp[:], uint32(len(dc.subdirs)))
+	buf.Write(tmp[:4])
+	for _, subdir := range dc.subdirs {
+		writeString(subdir.name)
+		binary.BigEndian.PutUint32(tmp[:], uint32(subdir.position))
+		buf.Write(tmp[:4])
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return dc.fonts.Serialize(w)
+}
+
+func deserializeDirCache(r io.Reader) (dirCache, error) {
+	var out dirCache
+	magic := make([]byte, len(dirCacheMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != dirCacheMagic {
+		return out, errors.New("invalid cache file header")
+	}
+
+	readString := func() (string, error) {
+		var tmp [2]byte
+		if _, err := io.ReadFull(r, tmp[:]); err != nil {
+			return "", err
+		}
+		s := make([]byte, binary.BigEndian.Uint16(tmp[:]))
+		_, err := io.ReadFull(r, s)
+		return string(s), err
+	}
+
+	var (
+		tmp [8]byte
+		err error
+	)
+	if out.dir, err = readString(); err != nil {
+		return out, fmt.Errorf("invalid cache file: %s", err)
+	}
+	if _, err = io.ReadFull(r, tmp[:]); err != nil {
+		return out, fmt.Errorf("invalid cache file: %s", err)
+	}
+	out.mtime = int64(binary.BigEndian.Uint64(tmp[:]))
+	if _, err = io.ReadFull(r, out.configHash[:]); err != nil {
+		return out, fmt.Errorf("invalid cache file: %s", err)
+	}
+	if _, err = io.ReadFull(r, tmp[:4]); err != nil {
+		return out, fmt.Errorf("invalid cache file: %s", err)
+	}
+	L := binary.BigEndian.Uint32(tmp[:])
+	if L > 1e6 { // guard against malicious files with hard limit
+		return out, fmt.Errorf("invalid cache file: unsupported number of sub-directories: %d", L)
+	}
+	out.subdirs = make([]dirCacheSubdir, L)
+	for i := range out.subdirs {
+		if out.subdirs[i].name, err = readString(); err != nil {
+			return out, fmt.Errorf("invalid cache file: %s", err)
+		}
+		if _, err = io.ReadFull(r, tmp[:4]); err != nil {
+			return out, fmt.Errorf("invalid cache file: %s", err)
+		}
+		out.subdirs[i].position = int(binary.BigEndian.Uint32(tmp[:]))
+	}
+
+	out.fonts, err = LoadFontset(r)
+	if err != nil {
+		return out, err
+	}
+	for i, subdir := range out.subdirs {
+		if subdir.position > len(out.fonts) || (i > 0 && subdir.position < out.subdirs[i-1].position) {
+			return out, fmt.Errorf("invalid cache file: invalid sub-directory position %d", subdir.position)
+		}
+	}
+	return out, nil
+}
+
+func loadDirCache(file string) (dirCache, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return dirCache{}, err
+	}
+	defer f.Close()
+	return deserializeDirCache(f)
+}
+
+// save writes to a temporary file which is then renamed,
+// so that a cache file is always complete.
+func (dc dirCache) save(file string) error {
+	f, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return fmt.Errorf("fontconfig: can't write cache file: %s", err)
+	}
+	err = dc.serialize(f)
+	if errC := f.Close(); err == nil {
+		err = errC
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("fontconfig: can't write cache file: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package fontconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(dst, b, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanFontDirectoriesCached(t *testing.T) {
+	fontDir, err := ioutil.TempDir("", "fonts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(fontDir)
+	cacheDir, err := ioutil.TempDir("", "fccache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	subDir := filepath.Join(fontDir, "sub")
+	if err = os.Mkdir(subDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	copyFile(t, "test/DejaVuSerif-Italic.ttf", filepath.Join(fontDir, "DejaVuSerif-Italic.ttf"))
+	copyFile(t, "test/4x6.pcf", filepath.Join(subDir, "4x6.pcf"))
+
+	c := NewConfig()
+	expected, err := c.ScanFontDirectories(fontDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := c.ScanFontDirectoriesCached(cacheDir, fontDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != len(expected) || len(fs) != 2 {
+		t.Fatalf("expected %d fonts, got %d", len(expected), len(fs))
+	}
+	if files, _ := ioutil.ReadDir(cacheDir); len(files) != 2 {
+		t.Fatalf("expected one cache file per directory, got %d", len(files))
+	}
+
+	// corrupt the font file without changing the directory mtime:
+	// the cached entry must be used
+	info, err := os.Stat(subDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(subDir, "4x6.pcf"), []byte("invalid"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(subDir, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	fs, err = c.ScanFontDirectoriesCached(cacheDir, fontDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 2 {
+		t.Fatalf("expected 2 cached fonts, got %d", len(fs))
+	}
+	for i := range fs {
+		if fs[i].Hash() != expected[i].Hash() {
+			t.Fatalf("unexpected cached font %s", fs[i])
+		}
+	}
+
+	// modifying the directory invalidates the entry
+	if err = os.Remove(filepath.Join(subDir, "4x6.pcf")); err != nil {
+		t.Fatal(err)
+	}
+	later := info.ModTime().Add(time.Second)
+	if err = os.Chtimes(subDir, later, later); err != nil {
+		t.Fatal(err)
+	}
+	fs, err = c.ScanFontDirectoriesCached(cacheDir, fontDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 1 {
+		t.Fatalf("expected 1 font after rescan, got %d", len(fs))
+	}
+}
+
+func TestScanFontDirectoriesCachedOrder(t *testing.T) {
+	fontDir, cacheDir := t.TempDir(), t.TempDir()
+
+	// "0-sub" comes before the font files in lexical order
+	subDir := filepath.Join(fontDir, "0-sub")
+	if err := os.Mkdir(subDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	copyFile(t, "test/DejaVuSerif-Italic.ttf", filepath.Join(fontDir, "DejaVuSerif-Italic.ttf"))
+	copyFile(t, "test/4x6.pcf", filepath.Join(subDir, "4x6.pcf"))
+
+	c := NewConfig()
+	expected, err := c.ScanFontDirectories(fontDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range [2]int{} { // not cached, then cached
+		fs, err := c.ScanFontDirectoriesCached(cacheDir, fontDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fs) != len(expected) {
+			t.Fatalf("expected %d fonts, got %d", len(expected), len(fs))
+		}
+		for i := range fs {
+			if fs[i].Hash() != expected[i].Hash() {
+				t.Fatalf("unexpected order: %s", fs[i])
+			}
+		}
+	}
+}
+
+func TestScanFontDirectoriesCachedCharset(t *testing.T) {
+	fontDir, cacheDir := t.TempDir(), t.TempDir()
+	copyFile(t, "test/DejaVuSerif-Italic.ttf", filepath.Join(fontDir, "DejaVuSerif-Italic.ttf"))
+
+	withScanCharset := func(r rune) *Config {
+		c := NewConfig()
+		err := c.LoadFromMemory(strings.NewReader(fmt.Sprintf(`<fontconfig>
+			<match target="scan"><edit name="charset"><charset><int>%d</int></charset></edit></match>
+		</fontconfig>`, r)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	// the configurations only differ by a charset
+	c1, c2 := withScanCharset('a'), withScanCharset('b')
+	if c1.scanRulesHash() == c2.scanRulesHash() {
+		t.Fatal("expected different hashes")
+	}
+
+	for _, test := range []struct {
+		config *Config
+		r      rune
+	}{{c1, 'a'}, {c2, 'b'}} {
+		fs, err := test.config.ScanFontDirectoriesCached(cacheDir, fontDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs, _ := fs[0].GetCharset(CHARSET)
+		if len(fs) != 1 || !cs.HasChar(test.r) || cs.Len() != 1 {
+			t.Fatalf("unexpected charset %v", cs)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package fontconfig
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MatchScore describes the contribution of one object
+// to the score of a candidate font (lower is better).
+type MatchScore struct {
+	// QueryValue and FontValue are the values which gave
+	// the best score, or nil if no value was compared.
+	QueryValue, FontValue Value
+
+	Object Object
+	// Strong is true for the score computed with the values
+	// of the query strongly bound. It is only meaningful for
+	// objects having distinct strong and weak priorities (like FAMILY).
+	Strong bool
+
+	// Score is the score used when sorting the fonts.
+	Score float32
+
+	// QueryIndex is the position of QueryValue in
+	// the query values, or -1 if no value was compared.
+	QueryIndex int
+}
+
+func (ms MatchScore) String() string {
+	name := ms.Object.String()
+	if ms.Object.toMatcher(false) != nil && fcMatchers[ms.Object].strong != fcMatchers[ms.Object].weak {
+		if ms.Strong {
+			name += " (strong)"
+		} else {
+			name += " (weak)"
+		}
+	}
+	if ms.QueryIndex == -1 {
+		return fmt.Sprintf("%s: %g", name, ms.Score)
+	}
+	return fmt.Sprintf("%s: %g (query value %d: %v, font value: %v)", name, ms.Score, ms.QueryIndex, ms.QueryValue, ms.FontValue)
+}
+
+// Explanation details how a font has been scored
+// when matching a query.
+type Explanation struct {
+	Font Pattern
+	// Scores contains one item for each object of the query
+	// used in the matching, sorted by decreasing priority.
+	Scores []MatchScore
+}
+
+func (ex Explanation) String() string {
+	file, _ := ex.Font.GetString(FILE)
+	family, _ := ex.Font.GetString(FAMILY)
+	chunks := []string{fmt.Sprintf("%s (%s)", family, file)}
+	for _, score := range ex.Scores {
+		chunks = append(chunks, "\t"+score.String())
+	}
+	return strings.Join(chunks, "\n")
+}
+
+// Explain returns the `n` fonts of `set` closest to `p`, in the
+// order returned by `Sort` (without trimming), alongside the
+// details of their score, to help understanding the choice of
+// a font. If `n` is negative, all the fonts are returned.
+// As for `Sort`, `p` should have been prepared by `Config.Substitute`
+// and `Pattern.SubstituteDefault`.
+func (set Fontset) Explain(p Pattern, n int) []Explanation {
+	nodes := set.sortNodes(p)
+	if n >= 0 && n < len(nodes) {
+		nodes = nodes[:n]
+	}
+
+	out := make([]Explanation, len(nodes))
+	for i, node := range nodes {
+		out[i] = Explanation{Font: node.pattern, Scores: explainScores(p, node)}
+	}
+	return out
+}
+
+func explainScores(p Pattern, node *sortNode) []MatchScore {
+	var out []MatchScore
+	for pri := matcherPriority(0); pri < priorityEnd; pri++ {
+		for _, match := range fcMatchers {
+			if match.compare == nil || (match.strong != pri && match.weak != pri) {
+				continue
+			}
+			query := p.getVals(match.object)
+			if len(query) == 0 {
+				continue
+			}
+			score := MatchScore{
+				Object:     match.object,
+				Strong:     match.strong == pri,
+				Score:      node.score[pri],
+				QueryIndex: -1,
+			}
+			if target := node.pattern.getVals(match.object); len(target) != 0 {
+				score.QueryIndex, score.QueryValue, score.FontValue = explainValues(&match, query, target, score.Strong)
+			}
+			out = append(out, score)
+		}
+	}
+	return out
+}
+
+// explainValues follows `fdFromPatternList`, returning the best values
+// and the index of the query value
+func explainValues(match *matcher, pattern, target valueList, strong bool) (int, Value, Value) {
+	var (
+		queryIndex            = -1
+		queryValue, fontValue Value
+	)
+	best := float32(math.MaxFloat32)
+	for j, v1 := range pattern {
+		if match.weak != match.strong && (v1.Binding == vbStrong) != strong {
+			continue
+		}
+		for _, v2 := range target {
+			_, v := match.compare(v1.Value, v2.Value)
+			if v < 0 {
+				return -1, nil, nil
+			}
+			if v = v*1000 + float32(j); v < best {
+				best = v
+				queryIndex, queryValue, fontValue = j, v1.Value, v2.Value
+			}
+		}
+	}
+	return queryIndex, queryValue, fontValue
+}
@@ -2,8 +2,9 @@ package fontconfig
 
 import (
 	"fmt"
-	"log"
 	"math"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 type opKind uint64 // a flag might be added, see `withFlags` and `getOp`
@@ -186,6 +187,22 @@ type exprMatrix struct {
 	xx, xy, yx, yy *expression
 }
 
+// constant returns the matrix if all its elements are numbers
+func (m exprMatrix) constant() (Matrix, bool) {
+	var out [4]float32
+	for i, e := range [...]*expression{m.xx, m.xy, m.yx, m.yy} {
+		switch v := e.u.(type) {
+		case Int:
+			out[i] = float32(v)
+		case Float:
+			out[i] = float32(v)
+		default:
+			return Matrix{}, false
+		}
+	}
+	return Matrix{Xx: out[0], Xy: out[1], Yx: out[2], Yy: out[3]}, true
+}
+
 type exprName struct {
 	object Object
 	kind   matchKind
@@ -306,7 +323,7 @@ func (e *expression) evaluate(result, query Pattern, kind matchKind) Value {
 				v = nil
 			}
 		} else if kind == MatchQuery && name.kind == MatchResult {
-			log.Println("fontconfig: <name> tag has target=\"font\" in a <match target=\"pattern\">.")
+			report(diagnostics.Diagnostic{Category: diagnostics.InvalidConfig, Message: `<name> tag has target="font" in a <match target="pattern">`})
 			v = nil
 		} else {
 			v, res = result.GetAt(name.object, 0)
@@ -415,9 +432,9 @@ func (e *expression) evaluate(result, query Pattern, kind matchKind) Value {
 			}
 			switch op {
 			case opPlus:
-				v = langSetUnion(vle, vre)
+				v = vle.Union(vre)
 			case opMinus:
-				v = langSetSubtract(vle, vre)
+				v = vle.Subtract(vre)
 			}
 		}
 	case opNot:
@@ -449,12 +466,15 @@ func (e *expression) evaluate(result, query Pattern, kind matchKind) Value {
 }
 
 func (parser *configParser) typecheckValue(value, type_ typeMeta) error {
-	if (value == typeInt{}) {
+	if (value == typeInt{} || value == typeSlant{}) {
 		value = typeFloat{}
 	}
 	if (type_ == typeInt{}) {
 		type_ = typeFloat{}
 	}
+	if (type_ == typeSlant{}) {
+		type_ = typeRange{}
+	}
 	if value != type_ {
 		if (value == typeLangSet{} && type_ == typeString{}) ||
 			(value == typeString{} && type_ == typeLangSet{}) ||
@@ -482,7 +502,7 @@ func (parser *configParser) typecheckExpr(expr *expression, type_ typeMeta) (err
 
 	defer func() {
 		if err != nil {
-			err = parser.error("expression %s: %s", expr, err)
+			err = fmt.Errorf("expression %s: %s", expr, err)
 		}
 	}()
 
@@ -503,9 +523,8 @@ func (parser *configParser) typecheckExpr(expr *expression, type_ typeMeta) (err
 		err = parser.typecheckValue(typeRange{}, type_)
 	case opField:
 		name := expr.u.(exprName)
-		o, ok := objects[name.object.String()]
-		if ok {
-			err = parser.typecheckValue(o.typeInfo, type_)
+		if o := parser.config.objectType(name.object); o != nil {
+			err = parser.typecheckValue(o, type_)
 		}
 	case opConst:
 		c := nameGetConstant(string(expr.u.(String)))
@@ -515,7 +534,7 @@ func (parser *configParser) typecheckExpr(expr *expression, type_ typeMeta) (err
 				err = parser.typecheckValue(o.typeInfo, type_)
 			}
 		} else {
-			err = parser.error("invalid constant used : %s", expr.u.(String))
+			err = fmt.Errorf("invalid constant used : %s", expr.u.(String))
 		}
 	case opQuest:
 		tree := expr.u.(exprTree)
@@ -719,13 +738,13 @@ func compareValue(leftO Value, op opKind, rightO Value) bool {
 		}
 		switch op {
 		case opContains, opListing:
-			ret = l.includes(r)
+			ret = l.Includes(r)
 		case opNotContains:
-			ret = !l.includes(r)
+			ret = !l.Includes(r)
 		case opEqual:
-			ret = langsetEqual(l, r)
+			ret = l.Equal(r)
 		case opNotEqual:
-			ret = !langsetEqual(l, r)
+			ret = !l.Equal(r)
 		}
 	case nil:
 		sameType := rightO == nil
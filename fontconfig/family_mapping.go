@@ -2,10 +2,10 @@ package fontconfig
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/benoitkugler/textlayout/language"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 func exprAsString(expr *expression) string {
@@ -94,7 +94,7 @@ func GenerateSubstitution() ([]ExportedFamilySubstitution, error) {
 			case 1:
 				test := tests[0]
 				if test.object == SPACING {
-					log.Println("ignored test", tests)
+					report(diagnostics.Diagnostic{Category: diagnostics.UnsupportedRule, Message: fmt.Sprintf("ignored test %s", tests)})
 					continue
 				}
 
@@ -127,7 +127,7 @@ func GenerateSubstitution() ([]ExportedFamilySubstitution, error) {
 					lang, fam = exprAsString(tests[0].expr), exprAsString(tests[1].expr)
 					langOp, famOp = tests[0].op.getOp(), tests[1].op.getOp()
 				} else {
-					log.Println("ignored test", tests)
+					report(diagnostics.Diagnostic{Category: diagnostics.UnsupportedRule, Message: fmt.Sprintf("ignored test %s", tests)})
 					continue
 				}
 
@@ -158,11 +158,11 @@ func GenerateSubstitution() ([]ExportedFamilySubstitution, error) {
 				if tests[0].object == FAMILY && tests[1].object == FAMILY && tests[2].object == FAMILY {
 					subs.TestCode = "noGenericFamily{}"
 				} else {
-					log.Println("ignored test", tests)
+					report(diagnostics.Diagnostic{Category: diagnostics.UnsupportedRule, Message: fmt.Sprintf("ignored test %s", tests)})
 					continue
 				}
 			default:
-				log.Println("ignored test", tests)
+				report(diagnostics.Diagnostic{Category: diagnostics.UnsupportedRule, Message: fmt.Sprintf("ignored test %s", tests)})
 				continue
 			}
 
@@ -9,11 +9,12 @@ package fontconfig
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"sync"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // test only: print debug information to stdout
@@ -67,11 +68,11 @@ func DefaultFontDirs() ([]string, error) {
 	for _, dir := range dirs {
 		info, err := os.Stat(dir)
 		if err != nil {
-			log.Println("invalid font dir", dir, err)
+			report(diagnostics.Diagnostic{Category: diagnostics.MissingFontDir, Message: fmt.Sprintf("invalid font dir: %s", err), File: dir})
 			continue
 		}
 		if !info.IsDir() {
-			log.Println("font dir is not a directory", dir)
+			report(diagnostics.Diagnostic{Category: diagnostics.MissingFontDir, Message: "font dir is not a directory", File: dir})
 			continue
 		}
 		validDirs = append(validDirs, dir)
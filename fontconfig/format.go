@@ -0,0 +1,726 @@
+package fontconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ported from fontconfig/src/fcformat.c Copyright © 2008,2009 Red Hat, Inc.
+
+// The format language is the one used by fc-list and fc-match:
+//
+//	%%			a literal %
+//	%{object}		the values of object, separated by commas
+//	%{:object}		same, prefixed by ':'
+//	%{object=}		same, prefixed by 'object='
+//	%{object[i]}		the i-th value of object
+//	%{object:-default}	default is used if object is missing
+//	%{=builtin}		one of unparse, fcmatch, fclist, fccat, pkgkit
+//	%{{expr}}		a sub-expression
+//	%{+obj1,obj2{expr}}	expr, evaluated with only the given objects
+//	%{-obj1,obj2{expr}}	expr, evaluated without the given objects
+//	%{?obj1,!obj2{then}{else}} conditional on the presence of objects
+//	%{#object}		the number of values of object
+//	%{[]obj1,obj2{expr}}	expr, evaluated for each value of the objects
+//
+// An optional width may be added after the '%', as in %-20{family}:
+// a negative width means left alignment.
+// The result of an expression may be converted with
+// '|converter', where converter is one of downcase, basename, dirname,
+// cescape, shescape, xmlescape, delete(chars), escape(chars) and translate(from,to).
+// Backslash escapes the following character, with the C meaning for \n, \t, etc...
+
+const (
+	fcmatchFormat = `%{file:-<unknown filename>|basename}: "%{family[0]:-<unknown family>}" "%{style[0]:-<unknown style>}"`
+	fclistFormat  = `%{?file{%{file}: }}%{-file{%{=unparse}}}`
+	fccatFormat   = `"%{file|basename|cescape}" %{index} "%{-file{%{=unparse|cescape}}}"`
+	pkgkitFormat  = `%{[]family{font(%{family|downcase|delete( )})\n}}%{[]lang{font(:lang=%{lang|downcase|translate(_,-)})\n}}`
+)
+
+// FormatString formats the pattern according to `template`,
+// using the format language of fc-list and fc-match,
+// for instance "%{family[0]} %{?style{(%{style})}}".
+// Custom objects are resolved by name, whatever the configuration
+// which defined them.
+func (p Pattern) FormatString(template string) (string, error) {
+	return p.formatString(template, nil)
+}
+
+// FormatString is the same as `Pattern.FormatString`, but only resolves
+// the custom objects known by `config` (see `Config.LookupObject`).
+func (config *Config) FormatString(p Pattern, template string) (string, error) {
+	return p.formatString(template, config)
+}
+
+func (p Pattern) formatString(template string, config *Config) (string, error) {
+	var buf strings.Builder
+	c := formatContext{format: template, config: config}
+	if err := c.interpretExpr(p, &buf, 0); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// FormatString formats each pattern of the set according to `template`
+// (see `Pattern.FormatString`) and concatenates the results.
+func (set Fontset) FormatString(template string) (string, error) {
+	var buf strings.Builder
+	for _, p := range set {
+		s, err := p.FormatString(template)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(s)
+	}
+	return buf.String(), nil
+}
+
+type formatContext struct {
+	format string
+	pos    int
+
+	config *Config // used to resolve the objects, may be nil
+}
+
+func (c *formatContext) error(format string, args ...interface{}) error {
+	return fmt.Errorf("fontconfig: invalid format %q at %d: %s", c.format, c.pos, fmt.Sprintf(format, args...))
+}
+
+// return 0 at the end of the input
+func (c *formatContext) current() byte {
+	if c.pos < len(c.format) {
+		return c.format[c.pos]
+	}
+	return 0
+}
+
+func (c *formatContext) consumeChar(term byte) bool {
+	if c.current() != term || term == 0 {
+		return false
+	}
+	c.pos++
+	return true
+}
+
+func (c *formatContext) expectChar(term byte) error {
+	if !c.consumeChar(term) {
+		if c.pos >= len(c.format) {
+			return c.error("expected '%c', got end of string", term)
+		}
+		return c.error("expected '%c', got '%c'", term, c.current())
+	}
+	return nil
+}
+
+func escapedChar(ch byte) byte {
+	switch ch {
+	case 'a':
+		return '\a'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'v':
+		return '\v'
+	default:
+		return ch
+	}
+}
+
+// readWord reads an identifier, stopping at the first punctuation character
+func (c *formatContext) readWord() (string, error) {
+	var word []byte
+	for c.pos < len(c.format) {
+		ch := c.format[c.pos]
+		if ch == '\\' {
+			c.pos++
+			if c.pos < len(c.format) {
+				word = append(word, escapedChar(c.format[c.pos]))
+				c.pos++
+			}
+			continue
+		} else if isPunct(ch) {
+			break
+		}
+		word = append(word, ch)
+		c.pos++
+	}
+	if len(word) == 0 {
+		return "", c.error("expected identifier")
+	}
+	return string(word), nil
+}
+
+// readChars reads until `term` or '}'
+func (c *formatContext) readChars(term byte) (string, error) {
+	var word []byte
+	for c.pos < len(c.format) {
+		ch := c.format[c.pos]
+		if ch == '\\' {
+			c.pos++
+			if c.pos < len(c.format) {
+				word = append(word, escapedChar(c.format[c.pos]))
+				c.pos++
+			}
+			continue
+		} else if ch == term || ch == '}' {
+			break
+		}
+		word = append(word, ch)
+		c.pos++
+	}
+	if len(word) == 0 {
+		return "", c.error("expected character data")
+	}
+	return string(word), nil
+}
+
+// readObjects reads a comma separated list of object names
+func (c *formatContext) readObjects() ([]Object, error) {
+	var out []Object
+	for {
+		word, err := c.readWord()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c.objectFromName(word))
+		if !c.consumeChar(',') {
+			return out, nil
+		}
+	}
+}
+
+// objectFromName returns `invalid` for unknown objects,
+// which are then never found in patterns.
+func (c *formatContext) objectFromName(name string) Object {
+	var (
+		object Object
+		ok     bool
+	)
+	if c.config == nil {
+		object, ok = lookupObject(name)
+	} else {
+		object, ok = c.config.LookupObject(name)
+	}
+	if !ok {
+		return invalid
+	}
+	return object
+}
+
+// interpretExpr interprets the format until `term` (or the end of input),
+// without consuming it.
+func (c *formatContext) interpretExpr(p Pattern, buf *strings.Builder, term byte) error {
+	for c.pos < len(c.format) && c.format[c.pos] != term {
+		switch c.format[c.pos] {
+		case '\\':
+			c.pos++
+			if c.pos < len(c.format) {
+				buf.WriteByte(escapedChar(c.format[c.pos]))
+				c.pos++
+			}
+			continue
+		case '%':
+			if err := c.interpretPercent(p, buf); err != nil {
+				return err
+			}
+			continue
+		}
+		buf.WriteByte(c.format[c.pos])
+		c.pos++
+	}
+	return nil
+}
+
+func (c *formatContext) interpretSubexpr(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('{'); err != nil {
+		return err
+	}
+	if err := c.interpretExpr(p, buf, '}'); err != nil {
+		return err
+	}
+	return c.expectChar('}')
+}
+
+func (c *formatContext) maybeInterpretSubexpr(p Pattern, buf *strings.Builder) error {
+	if c.current() == '{' {
+		return c.interpretSubexpr(p, buf)
+	}
+	return nil
+}
+
+func (c *formatContext) skipSubexpr() error {
+	if err := c.expectChar('{'); err != nil {
+		return err
+	}
+	for nest := 1; nest != 0; {
+		if c.pos >= len(c.format) {
+			return c.error("expected '}', got end of string")
+		}
+		switch c.format[c.pos] {
+		case '\\':
+			c.pos++ // skip the escaped char
+		case '{':
+			nest++
+		case '}':
+			nest--
+		}
+		c.pos++
+	}
+	return nil
+}
+
+func (c *formatContext) maybeSkipSubexpr() error {
+	if c.current() == '{' {
+		return c.skipSubexpr()
+	}
+	return nil
+}
+
+func (c *formatContext) interpretPercent(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('%'); err != nil {
+		return err
+	}
+
+	if c.consumeChar('%') { // "%%"
+		buf.WriteByte('%')
+		return nil
+	}
+
+	// parse an optional width specifier
+	width, err := c.readWidth()
+	if err != nil {
+		return err
+	}
+
+	if err := c.expectChar('{'); err != nil {
+		return err
+	}
+
+	start := buf.Len()
+
+	switch c.current() {
+	case '=':
+		err = c.interpretBuiltin(p, buf)
+	case '{':
+		err = c.interpretSubexpr(p, buf)
+	case '+':
+		err = c.interpretFilterIn(p, buf)
+	case '-':
+		err = c.interpretFilterOut(p, buf)
+	case '?':
+		err = c.interpretCond(p, buf)
+	case '#':
+		err = c.interpretCount(p, buf)
+	case '[':
+		err = c.interpretEnumerate(p, buf)
+	default:
+		err = c.interpretSimple(p, buf)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := c.maybeInterpretConverts(buf, start); err != nil {
+		return err
+	}
+	alignToWidth(buf, start, width)
+
+	return c.expectChar('}')
+}
+
+func (c *formatContext) readWidth() (int, error) {
+	end := c.pos
+	if end < len(c.format) && (c.format[end] == '-' || c.format[end] == '+') {
+		end++
+	}
+	for end < len(c.format) && '0' <= c.format[end] && c.format[end] <= '9' {
+		end++
+	}
+	if end == c.pos || (end == c.pos+1 && c.format[c.pos] == '-') {
+		return 0, nil
+	}
+	width, err := strconv.Atoi(c.format[c.pos:end])
+	if err != nil {
+		return 0, c.error("invalid width: %s", err)
+	}
+	c.pos = end
+	return width, nil
+}
+
+func alignToWidth(buf *strings.Builder, start, width int) {
+	s := buf.String()
+	l := len(s) - start
+	if width < 0 { // left align
+		for ; l < -width; l++ {
+			buf.WriteByte(' ')
+		}
+	} else if l < width { // right align
+		aligned := s[:start] + strings.Repeat(" ", width-l) + s[start:]
+		buf.Reset()
+		buf.WriteString(aligned)
+	}
+}
+
+func (c *formatContext) interpretBuiltin(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('='); err != nil {
+		return err
+	}
+	word, err := c.readWord()
+	if err != nil {
+		return err
+	}
+
+	var format string
+	switch word {
+	case "unparse":
+		buf.WriteString(p.unparse(c.config))
+		return nil
+	case "fcmatch":
+		format = fcmatchFormat
+	case "fclist":
+		format = fclistFormat
+	case "fccat":
+		format = fccatFormat
+	case "pkgkit":
+		format = pkgkitFormat
+	default:
+		return c.error("unknown builtin %s", word)
+	}
+
+	sub := formatContext{format: format, config: c.config}
+	return sub.interpretExpr(p, buf, 0)
+}
+
+func (c *formatContext) interpretFilterIn(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('+'); err != nil {
+		return err
+	}
+	objs, err := c.readObjects()
+	if err != nil {
+		return err
+	}
+	subpat := NewPattern()
+	for _, o := range objs {
+		if l, ok := p[o]; ok {
+			subpat[o] = l
+		}
+	}
+	return c.interpretSubexpr(subpat, buf)
+}
+
+func (c *formatContext) interpretFilterOut(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('-'); err != nil {
+		return err
+	}
+	objs, err := c.readObjects()
+	if err != nil {
+		return err
+	}
+	subpat := make(Pattern, len(p))
+	for o, l := range p {
+		subpat[o] = l
+	}
+	for _, o := range objs {
+		delete(subpat, o)
+	}
+	return c.interpretSubexpr(subpat, buf)
+}
+
+func (c *formatContext) interpretCond(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('?'); err != nil {
+		return err
+	}
+
+	pass := true
+	for {
+		negate := c.consumeChar('!')
+		word, err := c.readWord()
+		if err != nil {
+			return err
+		}
+		_, found := p.GetAt(c.objectFromName(word), 0)
+		pass = pass && (negate != (found == ResultMatch))
+		if !c.consumeChar(',') {
+			break
+		}
+	}
+
+	if pass {
+		if err := c.interpretSubexpr(p, buf); err != nil {
+			return err
+		}
+		return c.maybeSkipSubexpr()
+	}
+	if err := c.skipSubexpr(); err != nil {
+		return err
+	}
+	return c.maybeInterpretSubexpr(p, buf)
+}
+
+func (c *formatContext) interpretCount(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('#'); err != nil {
+		return err
+	}
+	word, err := c.readWord()
+	if err != nil {
+		return err
+	}
+	buf.WriteString(strconv.Itoa(len(p.getVals(c.objectFromName(word)))))
+	return nil
+}
+
+func (c *formatContext) interpretEnumerate(p Pattern, buf *strings.Builder) error {
+	if err := c.expectChar('['); err != nil {
+		return err
+	}
+	if err := c.expectChar(']'); err != nil {
+		return err
+	}
+	objs, err := c.readObjects()
+	if err != nil {
+		return err
+	}
+
+	isEmpty := true
+	for _, o := range objs {
+		isEmpty = isEmpty && len(p.getVals(o)) == 0
+	}
+	if isEmpty { // nothing to enumerate
+		return c.skipSubexpr()
+	}
+
+	subpat := make(Pattern, len(p))
+	for o, l := range p {
+		subpat[o] = l
+	}
+	formatSave := c.pos
+	for idx := 0; ; idx++ {
+		notLast := false
+		for _, o := range objs {
+			delete(subpat, o)
+			vals := p.getVals(o)
+			if idx < len(vals) {
+				subpat[o] = &valueList{vals[idx]}
+				notLast = notLast || idx+1 < len(vals)
+			}
+		}
+		c.pos = formatSave
+		if err := c.interpretSubexpr(subpat, buf); err != nil {
+			return err
+		}
+		if !notLast {
+			return nil
+		}
+	}
+}
+
+func (c *formatContext) interpretSimple(p Pattern, buf *strings.Builder) error {
+	addColon := c.consumeChar(':')
+
+	word, err := c.readWord()
+	if err != nil {
+		return err
+	}
+
+	idx := -1
+	if c.consumeChar('[') {
+		end := c.pos
+		for end < len(c.format) && '0' <= c.format[end] && c.format[end] <= '9' {
+			end++
+		}
+		idx, err = strconv.Atoi(c.format[c.pos:end])
+		if err != nil {
+			return c.error("expected non-negative number")
+		}
+		c.pos = end
+		if err := c.expectChar(']'); err != nil {
+			return err
+		}
+	}
+
+	addEltName := c.consumeChar('=')
+
+	// modifiers: for now we just support 'default value'
+	var (
+		elseString    string
+		hasElseString bool
+	)
+	if c.consumeChar(':') {
+		if err := c.expectChar('-'); err != nil {
+			return err
+		}
+		elseString, err = c.readChars('|')
+		if err != nil {
+			return err
+		}
+		hasElseString = true
+	}
+
+	vals := p.getVals(c.objectFromName(word))
+	if len(vals) == 0 && !hasElseString {
+		return nil
+	}
+
+	if addColon {
+		buf.WriteByte(':')
+	}
+	if addEltName {
+		buf.WriteString(word)
+		buf.WriteByte('=')
+	}
+
+	if idx != -1 {
+		if idx < len(vals) {
+			unparseValue(buf, vals[idx].Value, invalid, "")
+		} else {
+			buf.WriteString(elseString)
+		}
+	} else if len(vals) != 0 {
+		for i, v := range vals {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			unparseValue(buf, v.Value, invalid, "")
+		}
+	} else {
+		buf.WriteString(elseString)
+	}
+	return nil
+}
+
+func (c *formatContext) maybeInterpretConverts(buf *strings.Builder, start int) error {
+	for c.consumeChar('|') {
+		if err := c.interpretConvert(buf, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interpretConvert applies the converter to buf[start:]
+func (c *formatContext) interpretConvert(buf *strings.Builder, start int) error {
+	s := buf.String()
+	prefix, str := s[:start], s[start:]
+
+	word, err := c.readWord()
+	if err != nil {
+		return err
+	}
+
+	var out string
+	switch word {
+	case "downcase":
+		out = strings.ToLower(str)
+	case "basename":
+		out = filepath.Base(str)
+	case "dirname":
+		out = filepath.Dir(str)
+	case "cescape":
+		out = cescape(str)
+	case "shescape":
+		out = "'" + strings.ReplaceAll(str, "'", `'\''`) + "'"
+	case "xmlescape":
+		out = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(str)
+	case "delete", "escape":
+		if err := c.expectChar('('); err != nil {
+			return err
+		}
+		chars, err := c.readChars(')')
+		if err != nil {
+			return err
+		}
+		if err := c.expectChar(')'); err != nil {
+			return err
+		}
+		if word == "delete" {
+			out = deleteChars(str, chars)
+		} else {
+			out = escapeChars(str, chars)
+		}
+	case "translate":
+		if err := c.expectChar('('); err != nil {
+			return err
+		}
+		from, err := c.readChars(',')
+		if err != nil {
+			return err
+		}
+		if err := c.expectChar(','); err != nil {
+			return err
+		}
+		to, err := c.readChars(')')
+		if err != nil {
+			return err
+		}
+		if err := c.expectChar(')'); err != nil {
+			return err
+		}
+		out = translateChars(str, from, to)
+	default:
+		return c.error("unknown converter %s", word)
+	}
+
+	buf.Reset()
+	buf.WriteString(prefix)
+	buf.WriteString(out)
+	return nil
+}
+
+func cescape(str string) string {
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' || str[i] == '"' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(str[i])
+	}
+	return buf.String()
+}
+
+func deleteChars(str, chars string) string {
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		if strings.IndexByte(chars, str[i]) == -1 {
+			buf.WriteByte(str[i])
+		}
+	}
+	return buf.String()
+}
+
+// the first char of `chars` is used as escape
+func escapeChars(str, chars string) string {
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		if strings.IndexByte(chars, str[i]) != -1 {
+			buf.WriteByte(chars[0])
+		}
+		buf.WriteByte(str[i])
+	}
+	return buf.String()
+}
+
+// if `to` is shorter than `from`, its last char is used
+// for the remaining chars
+func translateChars(str, from, to string) string {
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		if j := strings.IndexByte(from, str[i]); j != -1 {
+			if j >= len(to) {
+				j = len(to) - 1
+			}
+			buf.WriteByte(to[j])
+		} else {
+			buf.WriteByte(str[i])
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,111 @@
+package fontconfig
+
+import "testing"
+
+func TestFormatString(t *testing.T) {
+	p := NewPattern()
+	p.Add(FAMILY, String("DejaVu Sans"), true)
+	p.Add(FAMILY, String("DejaVu"), true)
+	p.Add(STYLE, String("Book"), true)
+	p.Add(WEIGHT, Int(WEIGHT_BOLD), true)
+	p.Add(FILE, String("/usr/share/fonts/dejavu/DejaVuSans.ttf"), true)
+	p.Add(INDEX, Int(0), true)
+	p.Add(LANG, NewLangset("fr|en"), true)
+
+	for _, tc := range [...]struct {
+		format, expected string
+	}{
+		{"%{family}", "DejaVu Sans,DejaVu"},
+		{"%{family[1]}", "DejaVu"},
+		{"%{family[2]:-none}", "none"},
+		{"%{foundry:-unknown}", "unknown"},
+		{"%{foundry}", ""},
+		{"%{:family[0]=}", ":family=DejaVu Sans"},
+		{"%{weight}", "200"},
+		{"%{#family}", "2"},
+		{"100%%\\t", "100%\t"},
+		{"[%10{style}]", "[      Book]"},
+		{"[%-10{style}]", "[Book      ]"},
+		{"%{?style{yes}{no}}", "yes"},
+		{"%{?!style{yes}{no}}", "no"},
+		{"%{?style,foundry{yes}{no}}", "no"},
+		{"%{?foundry{yes}}", ""},
+		{"%{file|basename}", "DejaVuSans.ttf"},
+		{"%{file|dirname}", "/usr/share/fonts/dejavu"},
+		{"%{family[0]|downcase|delete( )}", "dejavusans"},
+		{"%{family[0]|translate( ,_)}", "DejaVu_Sans"},
+		{"%{family[0]|escape(\\\\ )}", "DejaVu\\ Sans"},
+		{"%{style|shescape}", "'Book'"},
+		{"%{+family,style{%{=unparse}}}", "DejaVu Sans,DejaVu:style=Book"},
+		{"%{-file,index,lang,weight{%{=unparse}}}", "DejaVu Sans,DejaVu:style=Book"},
+		{"%{[]family{<%{family}>}}", "<DejaVu Sans><DejaVu>"},
+		{"%{=fcmatch}", `DejaVuSans.ttf: "DejaVu Sans" "Book"`},
+		{"%{-lang,weight{%{=fclist}}}", "/usr/share/fonts/dejavu/DejaVuSans.ttf: DejaVu Sans,DejaVu:style=Book:index=0"},
+		{"%{-lang,weight{%{=pkgkit}}}", "font(dejavusans)\nfont(dejavu)\n"},
+	} {
+		got, err := p.FormatString(tc.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.expected {
+			t.Errorf("format %s: expected %q, got %q", tc.format, tc.expected, got)
+		}
+	}
+
+	for _, format := range [...]string{
+		"%{family",
+		"%{}",
+		"%{=unknown}",
+		"%{family|unknown}",
+		"%{?family{a}",
+	} {
+		if _, err := p.FormatString(format); err == nil {
+			t.Errorf("expected error for format %s", format)
+		}
+	}
+}
+
+func TestFontsetFormatString(t *testing.T) {
+	fs := Fontset{NewPattern(), NewPattern()}
+	fs[0].AddString(FAMILY, "A")
+	fs[1].AddString(FAMILY, "B")
+	out, err := fs.FormatString("%{family}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "A\nB\n" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestFormatStringCustom(t *testing.T) {
+	config := NewConfig()
+	tier, err := config.RegisterObject("tier", KindInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPattern()
+	p.AddString(FAMILY, "A")
+	p.AddInt(tier, 2)
+
+	template := "%{tier}|%{#tier}|%{?tier{yes}{no}}|%{=unparse}"
+	out, err := config.FormatString(p, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "2|1|yes|A:tier=2" {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if out2, _ := p.FormatString(template); out2 != out {
+		t.Fatalf("unexpected output %q", out2)
+	}
+
+	// the object is not known by this configuration
+	out, err = NewConfig().FormatString(p, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "|0|no|A" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
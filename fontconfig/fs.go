@@ -0,0 +1,142 @@
+package fontconfig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// This file provides support for configurations and fonts
+// stored in an fs.FS, such as an embed.FS.
+
+// LoadFromFS is the same as `LoadFromDir`, but walks the directory `root` of `fsys`.
+// The files are loaded in lexical order.
+func (config *Config) LoadFromFS(fsys fs.FS, root string) error {
+	if debugMode {
+		fmt.Printf("\tScanning config dir %s\n", root)
+	}
+
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("fontconfig: cannot read config %s : %s", path, err)
+		}
+		if d.IsDir() || !isConfFile(d.Name()) { // keep going
+			return nil
+		}
+
+		fi, err := fsys.Open(path)
+		if err != nil {
+			return fmt.Errorf("fontconfig: can't open such file %s: %s", path, err)
+		}
+		defer fi.Close()
+
+		return config.parseAndLoadFromMemory(path, fi)
+	})
+}
+
+// ScanFontFS is the same as `ScanFontDirectories`, but scans the font files in `fsys`.
+// `roots` may be directories, which are walked recursively, or font files.
+// The FILE object of the returned patterns contains the path of the font files in `fsys`,
+// so that `FSLoader` may be used to load the fonts.
+func (config *Config) ScanFontFS(fsys fs.FS, roots ...string) (Fontset, error) {
+	seen := make(strSet) // keep track of visited dirs to avoid double includes
+	var out Fontset
+	for _, root := range roots {
+		err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return fmt.Errorf("invalid font location: %s", err)
+			}
+			if d.IsDir() { // keep going
+				if seen[path] {
+					return fs.SkipDir
+				}
+				seen[path] = true
+				return nil
+			}
+			if !validFontFile(d.Name()) {
+				return nil
+			}
+
+			// path selector
+			if !config.acceptFilename(path) {
+				return nil
+			}
+
+			file, err := openFSResource(fsys, path)
+			if err != nil {
+				return err
+			}
+			fonts := scanOneFontFile(file, path, config)
+			if closer, ok := file.(io.Closer); ok {
+				closer.Close()
+			}
+
+			// pattern selector
+			for _, f := range fonts {
+				if config.acceptFont(f) {
+					out = append(out, f)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// openFSResource opens the file `name` of `fsys`. If the file does not
+// support random access, its content is read into memory.
+// The returned resource should be closed if it implements io.Closer.
+func openFSResource(fsys fs.FS, name string) (fonts.Resource, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if res, ok := file.(fonts.Resource); ok {
+		return res, nil
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(content), nil
+}
+
+// FSLoader loads the faces of the patterns returned by `ScanFontFS`,
+// resolving their file name in `FS`.
+// It implements the `FaceLoader` interface of the `pango/fcfonts` package.
+type FSLoader struct {
+	FS fs.FS
+}
+
+// LoadFace opens and parses the given face.
+func (fl FSLoader) LoadFace(key fonts.FaceID, format FontFormat) (fonts.Face, error) {
+	loader := format.Loader()
+	if loader == nil {
+		return nil, fmt.Errorf("unsupported file format %s", format)
+	}
+
+	file, err := openFSResource(fl.FS, key.File)
+	if err != nil {
+		return nil, fmt.Errorf("font file not found: %s", err)
+	}
+	if closer, ok := file.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	faces, err := loader(file)
+	if err != nil {
+		return nil, fmt.Errorf("corrupted font file (with type %s): %s", format, key.File)
+	}
+	if int(key.Index) >= len(faces) {
+		return nil, fmt.Errorf("out of range font index: %d", key.Index)
+	}
+	return faces[key.Index], nil
+}
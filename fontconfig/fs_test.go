@@ -0,0 +1,94 @@
+package fontconfig
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf.d/20-b.conf":     confFile(matchFamily("b")),
+		"conf.d/10-a.conf":     confFile(matchFamily("a")),
+		"conf.d/sub/15-c.conf": confFile(matchFamily("c")),
+		"conf.d/README":        &fstest.MapFile{Data: []byte("not a config file")},
+		"conf.d/a.conf":        confFile(`<invalid/>`),
+	}
+	config := NewConfig()
+	if err := config.LoadFromFS(fsys, "conf.d"); err != nil {
+		t.Fatal(err)
+	}
+
+	var families []string
+	for _, rs := range config.subst {
+		for _, rule := range rs.subst[MatchQuery] {
+			families = append(families, string(rule.tests[0].expr.u.(String)))
+		}
+	}
+	if exp := []string{"a", "b", "c"}; !reflect.DeepEqual(families, exp) {
+		t.Fatalf("expected rules order %v, got %v", exp, families)
+	}
+
+	if err := NewConfig().LoadFromFS(fsys, "missing"); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+	fsys["conf.d/30-invalid.conf"] = confFile(`<invalid/>`)
+	if err := NewConfig().LoadFromFS(fsys, "conf.d"); err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+}
+
+func TestScanFontFS(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, file := range []string{"DejaVuSerif-Italic.ttf", "4x6.pcf", "fontawesome-webfont.woff2"} {
+		b, err := ioutil.ReadFile("test/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys["fonts/"+file] = &fstest.MapFile{Data: b}
+	}
+	fsys["fonts/README"] = &fstest.MapFile{Data: []byte("not a font")}
+
+	c := NewConfig()
+	fs, err := c.ScanFontFS(fsys, "fonts", "fonts/4x6.pcf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) != 4 { // the single file is scanned twice
+		t.Fatalf("expected 4 fonts, got %d", len(fs))
+	}
+
+	expected, err := c.ScanFontFile("test/DejaVuSerif-Italic.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fs[1]
+	if file, _ := got.GetString(FILE); file != "fonts/DejaVuSerif-Italic.ttf" {
+		t.Fatalf("unexpected file %s", file)
+	}
+	got, exp := got.Duplicate(), expected[0].Duplicate()
+	got.Del(FILE)
+	exp.Del(FILE)
+	if got.Hash() != exp.Hash() {
+		t.Fatalf("different patterns:\n%s\n%s", got, exp)
+	}
+
+	loader := FSLoader{FS: fsys}
+	for _, p := range fs {
+		face, err := loader.LoadFace(p.FaceID(), p.Format())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if face == nil {
+			t.Fatal("nil face")
+		}
+	}
+
+	if _, err := loader.LoadFace(fs[0].FaceID(), Type1); err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+	if _, err = c.ScanFontFS(fsys, "missing"); err == nil {
+		t.Fatal("expected error for missing root")
+	}
+}
@@ -0,0 +1,206 @@
+package fontconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// This file implements the support for the <include>, <dir> and <cachedir>
+// elements, which is only enabled by `Config.LoadWithIncludes`.
+
+// PathResolver converts the file name found in an <include>, <dir>, <remap-dir> or <cachedir>
+// element (given by `element`) into a slash separated path.
+// `prefix` is the value of the 'prefix' attribute of the element, and
+// `from` is the path of the configuration file containing the element.
+// An empty string may be returned to ignore the element.
+type PathResolver func(element, name, prefix, from string) string
+
+// DefaultPathResolver mimics the resolution performed by the C library:
+//   - a leading '~' is replaced by the home directory
+//   - the 'xdg' prefix resolves the name relatively to XDG_CONFIG_HOME for <include>,
+//     XDG_DATA_HOME for <dir> and XDG_CACHE_HOME for <cachedir>
+//   - relative names are resolved relatively to the directory of `from`
+func DefaultPathResolver(element, name, prefix, from string) string {
+	if prefix == "xdg" {
+		var env, def string
+		switch element {
+		case "include":
+			env, def = "XDG_CONFIG_HOME", ".config"
+		case "cachedir":
+			env, def = "XDG_CACHE_HOME", ".cache"
+		default:
+			env, def = "XDG_DATA_HOME", ".local/share"
+		}
+		base := os.Getenv(env)
+		if base == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return ""
+			}
+			base = path.Join(home, def)
+		}
+		return path.Join(base, name)
+	}
+
+	if name == "~" || strings.HasPrefix(name, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		return path.Join(home, name[1:])
+	}
+
+	if path.IsAbs(name) {
+		return path.Clean(name)
+	}
+	return path.Join(path.Dir(from), name)
+}
+
+// LoadWithIncludes loads the configuration file `file`, following its <include> directives,
+// and collecting its <dir> and <cachedir> elements (see `FontDirs` and `CacheDirs`).
+// This makes possible to use real configurations, such as /etc/fonts/fonts.conf.
+//
+// The paths found in the configuration files are converted by `resolve` (which defaults to `DefaultPathResolver`),
+// and the included files are then opened with `fsys`, after removing the leading slash. In particular,
+// `os.DirFS("/")` may be used to access the usual file system, as in
+//
+//	config.LoadWithIncludes(os.DirFS("/"), "/etc/fonts/fonts.conf", nil)
+//
+// Including a directory loads its files of the form [0-9]*.conf, in lexical order.
+// Missing files are errors, unless the 'ignore_missing' attribute is set.
+// Each file is loaded at most once, and include cycles are reported as errors.
+func (config *Config) LoadWithIncludes(fsys fs.FS, file string, resolve PathResolver) error {
+	if resolve == nil {
+		resolve = DefaultPathResolver
+	}
+	ctx := includeContext{fsys: fsys, resolve: resolve, loaded: make(strSet)}
+	return ctx.loadFile(config, file)
+}
+
+// FontDirs returns the font directories collected from the <dir> and <remap-dir> elements,
+// to be used with `ScanFontDirectories`.
+// See `LoadWithIncludes`.
+func (config *Config) FontDirs() []string { return config.fontDirs }
+
+// CacheDirs returns the directories collected from the <cachedir> elements.
+// See `LoadWithIncludes`.
+func (config *Config) CacheDirs() []string { return config.cacheDirs }
+
+type includeContext struct {
+	fsys    fs.FS
+	resolve PathResolver
+
+	loading []string // stack of the files being loaded
+	loaded  strSet
+}
+
+// fsPath converts a resolved path to a path valid for fs.FS
+func fsPath(file string) string {
+	if file = strings.TrimPrefix(path.Clean(file), "/"); file == "" {
+		return "."
+	}
+	return file
+}
+
+func (ctx *includeContext) loadFile(config *Config, file string) error {
+	for _, loading := range ctx.loading {
+		if loading == file {
+			return fmt.Errorf("fontconfig: include cycle detected: %s", strings.Join(append(ctx.loading, file), " -> "))
+		}
+	}
+	if ctx.loaded[file] {
+		return nil
+	}
+	ctx.loaded[file] = true
+
+	f, err := ctx.fsys.Open(fsPath(file))
+	if err != nil {
+		return fmt.Errorf("fontconfig: can't open config file %s: %s", file, err)
+	}
+	defer f.Close()
+
+	ctx.loading = append(ctx.loading, file)
+	err = config.parseAndLoad(file, f, ctx)
+	ctx.loading = ctx.loading[:len(ctx.loading)-1]
+	return err
+}
+
+// include loads `file`, or the [0-9]*.conf files if `file` is a directory
+func (ctx *includeContext) include(config *Config, file string, ignoreMissing bool) error {
+	info, err := fs.Stat(ctx.fsys, fsPath(file))
+	if err != nil {
+		if ignoreMissing && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("fontconfig: can't include %s: %s", file, err)
+	}
+
+	if !info.IsDir() {
+		return ctx.loadFile(config, file)
+	}
+
+	entries, err := fs.ReadDir(ctx.fsys, fsPath(file)) // sorted by name
+	if err != nil {
+		return fmt.Errorf("fontconfig: can't include %s: %s", file, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isConfFile(entry.Name()) {
+			continue
+		}
+		if err = ctx.loadFile(config, path.Join(file, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (parser *configParser) parseInclude() error {
+	last := parser.p()
+	name := strings.TrimSpace(last.str.String())
+	last.str.Reset()
+	prefix := last.getAttr("prefix")
+	last.getAttr("deprecated") // ignored
+	ignore := False
+	if attr := last.getAttr("ignore_missing"); attr != "" {
+		var err error
+		if ignore, err = parser.lexBool(attr); err != nil {
+			return err
+		}
+	}
+
+	file := parser.includes.resolve("include", name, prefix, parser.name)
+	if file == "" {
+		return nil
+	}
+
+	// the rules defined before the include directive are added first
+	if !parser.ruleset.isEmpty() {
+		parser.config.subst = append(parser.config.subst, parser.ruleset)
+		parser.ruleset = ruleSet{name: parser.ruleset.name, description: parser.ruleset.description, domain: parser.ruleset.domain}
+	}
+
+	return parser.includes.include(parser.config, file, ignore == True)
+}
+
+func (parser *configParser) parseDir(element elemTag) {
+	last := parser.p()
+	name := strings.TrimSpace(last.str.String())
+	last.str.Reset()
+	prefix := last.getAttr("prefix")
+	last.getAttr("salt")    // ignored
+	last.getAttr("as-path") // ignored
+
+	dir := parser.includes.resolve(element.String(), name, prefix, parser.name)
+	if dir == "" {
+		return
+	}
+	if element == elementCacheDir {
+		parser.config.cacheDirs = append(parser.config.cacheDirs, dir)
+	} else {
+		parser.config.fontDirs = append(parser.config.fontDirs, dir)
+	}
+}
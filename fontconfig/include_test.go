@@ -0,0 +1,131 @@
+package fontconfig
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func confFile(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(`<?xml version="1.0"?>
+<!DOCTYPE fontconfig SYSTEM "urn:fontconfig:fonts.dtd">
+<fontconfig>` + content + `</fontconfig>`)}
+}
+
+func matchFamily(family string) string {
+	return `<match><test name="family"><string>` + family + `</string></test>
+	<edit name="family" mode="append"><string>` + family + `-fallback</string></edit></match>`
+}
+
+func TestLoadWithIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"etc/fonts/fonts.conf": confFile(`
+			<dir>/usr/share/fonts</dir>
+			<dir prefix="xdg">fonts</dir>
+			<dir>~/.fonts</dir>
+			<cachedir>/var/cache/fontconfig</cachedir>
+			` + matchFamily("first") + `
+			<include ignore_missing="yes">conf.d</include>
+			<include ignore_missing="yes">/missing/fonts.conf</include>
+			<config><rescan><int>30</int></rescan></config>
+			` + matchFamily("last")),
+		"etc/fonts/conf.d/10-a.conf": confFile(matchFamily("a") + `<include>../local.conf</include>`),
+		"etc/fonts/conf.d/20-b.conf": confFile(`<remap-dir as-path="/fonts">/opt/fonts</remap-dir>` + matchFamily("b")),
+		"etc/fonts/conf.d/README":    &fstest.MapFile{Data: []byte("not a config file")},
+		"etc/fonts/local.conf":       confFile(matchFamily("local")),
+	}
+	for env, value := range map[string]string{"XDG_DATA_HOME": "/home/user/.local/share", "HOME": "/home/user"} {
+		defer os.Setenv(env, os.Getenv(env))
+		os.Setenv(env, value)
+	}
+
+	config := NewConfig()
+	err := config.LoadWithIncludes(fsys, "/etc/fonts/fonts.conf", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDirs := []string{"/usr/share/fonts", "/home/user/.local/share/fonts", "/home/user/.fonts", "/opt/fonts"}
+	if dirs := config.FontDirs(); !reflect.DeepEqual(dirs, expectedDirs) {
+		t.Fatalf("expected dirs %v, got %v", expectedDirs, dirs)
+	}
+	if dirs := config.CacheDirs(); !reflect.DeepEqual(dirs, []string{"/var/cache/fontconfig"}) {
+		t.Fatalf("unexpected cache dirs %v", dirs)
+	}
+
+	// the rules are applied in the order of the include directives
+	var families []string
+	for _, rs := range config.subst {
+		for _, rule := range rs.subst[MatchQuery] {
+			families = append(families, string(rule.tests[0].expr.u.(String)))
+		}
+	}
+	if exp := []string{"first", "a", "local", "b", "last"}; !reflect.DeepEqual(families, exp) {
+		t.Fatalf("expected rules order %v, got %v", exp, families)
+	}
+
+	p := NewPattern()
+	p.AddString(FAMILY, "local")
+	config.Substitute(p, nil, MatchQuery)
+	if fams := p.GetStrings(FAMILY); !reflect.DeepEqual(fams, []string{"local", "local-fallback"}) {
+		t.Fatalf("unexpected families %v", fams)
+	}
+
+	// the directories are copied
+	if cp := config.Copy(); !reflect.DeepEqual(cp.FontDirs(), config.FontDirs()) {
+		t.Fatal("dirs not copied")
+	}
+}
+
+func TestLoadWithIncludesErrors(t *testing.T) {
+	for _, fsys := range []fstest.MapFS{
+		{ // missing file
+			"fonts.conf": confFile(`<include>conf.d</include>`),
+		},
+		{ // cycle
+			"fonts.conf":       confFile(`<include>conf.d</include>`),
+			"conf.d/10-a.conf": confFile(`<include>../fonts.conf</include>`),
+		},
+		{ // invalid attribute
+			"fonts.conf": confFile(`<include ignore_missing="maybe">conf.d</include>`),
+		},
+	} {
+		if err := NewConfig().LoadWithIncludes(fsys, "fonts.conf", nil); err == nil {
+			t.Fatalf("expected error for %s", fsys["fonts.conf"].Data)
+		}
+	}
+
+	err := NewConfig().LoadWithIncludes(fstest.MapFS{
+		"fonts.conf":       confFile(`<include>conf.d</include>`),
+		"conf.d/10-a.conf": confFile(`<include>../fonts.conf</include>`),
+	}, "fonts.conf", nil)
+	if !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	// without includes support, the elements are rejected
+	err = NewConfig().LoadFromMemory(strings.NewReader(string(confFile(`<dir>/usr/share/fonts</dir>`).Data)))
+	if err == nil {
+		t.Fatal("expected error for unsupported element")
+	}
+}
+
+func TestCustomResolver(t *testing.T) {
+	fsys := fstest.MapFS{
+		"root/fonts.conf":     confFile(`<include>/etc/fonts/local.conf</include><dir>/usr/share/fonts</dir>`),
+		"root/etc/local.conf": confFile(matchFamily("local")),
+	}
+	// redirect the absolute paths to the 'root' directory
+	resolve := func(element, name, prefix, from string) string {
+		return "root" + strings.Replace(name, "/fonts/", "/", 1)
+	}
+	config := NewConfig()
+	if err := config.LoadWithIncludes(fsys, "root/fonts.conf", resolve); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.subst) != 2 || len(config.FontDirs()) != 1 {
+		t.Fatalf("unexpected config %v", config.FontDirs())
+	}
+}
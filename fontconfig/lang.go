@@ -3,20 +3,21 @@ package fontconfig
 import (
 	"encoding/binary"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // fontconfig/src/fclang.c Copyright © 2002 Keith Packard
 
-type langResult uint8
+// LangResult describes how two languages (or language sets) are related.
+type LangResult uint8
 
 const (
-	langEqual              langResult = 0 // exact match
-	langDifferentCountry   langResult = 1
-	langDifferentTerritory langResult = 1 // same primary language but not exact
-	langDifferentLang      langResult = 2
+	LangEqual              LangResult = 0 // exact match
+	LangDifferentTerritory LangResult = 1 // same primary language but not exact
+	LangDifferentLang      LangResult = 2
 )
 
 type langToCharset struct {
@@ -60,9 +61,9 @@ func newCharSetFromLang(lang string) *Charset {
 
 	for i, lcs := range fcLangCharSets {
 		switch langCompare(lang, lcs.lang) {
-		case langEqual:
+		case LangEqual:
 			return &lcs.charset
-		case langDifferentTerritory:
+		case LangDifferentTerritory:
 			if country == -1 {
 				country = i
 			}
@@ -133,11 +134,11 @@ func normalizeLang(lang string) string {
 	tlen := len(territory)
 	tm := territory
 	if llen < 2 || llen > 3 {
-		log.Printf("fontconfig: ignoring %s: not a valid language tag", lang)
+		report(diagnostics.Diagnostic{Category: diagnostics.InvalidValue, Message: fmt.Sprintf("ignoring %s: not a valid language tag", lang)})
 		return result
 	}
 	if tlen != 0 && (tlen < 2 || tlen > 3) && !(territory[0] == 'z' && tlen < 5) {
-		log.Printf("fontconfig: ignoring %s: not a valid region tag", lang)
+		report(diagnostics.Diagnostic{Category: diagnostics.InvalidValue, Message: fmt.Sprintf("ignoring %s: not a valid region tag", lang)})
 		return result
 	}
 	if modifier != "" {
@@ -169,8 +170,8 @@ func langEnd(c string) bool {
 	return c == "" || c[0] == '-'
 }
 
-func langCompare(s1, s2 string) langResult {
-	result := langDifferentLang
+func langCompare(s1, s2 string) LangResult {
+	result := LangDifferentLang
 
 	isUnd := toLower(s1) == 'u' && toLower(s1[1:]) == 'n' &&
 		toLower(s1[2:]) == 'd' && langEnd(s1[3:])
@@ -180,17 +181,17 @@ func langCompare(s1, s2 string) langResult {
 		c2 := toLower(s2[i:])
 		if c1 != c2 {
 			if !isUnd && langEnd(s1[i:]) && langEnd(s2[i:]) {
-				return langDifferentTerritory
+				return LangDifferentTerritory
 			}
 			return result
 		} else if c1 == 0 {
 			if isUnd {
 				return result
 			}
-			return langEqual
+			return LangEqual
 		} else if c1 == '-' {
 			if !isUnd {
-				result = langDifferentTerritory
+				result = LangDifferentTerritory
 			}
 		}
 
@@ -287,10 +288,10 @@ func findLangIndex(lang string) int {
 	return -(mid + 1)
 }
 
-// add adds `lang` to `ls`.
+// Add adds `lang` to `ls`.
 // `lang` should be of the form Ll-Tt where Ll is a
 // two or three letter language from ISO 639 and Tt is a territory from ISO 3166.
-func (ls *Langset) add(lang string) {
+func (ls *Langset) Add(lang string) {
 	id := findLangIndex(lang)
 	if id >= 0 {
 		ls.bitSet(id)
@@ -340,7 +341,8 @@ func (ls *Langset) bitReset(id int) {
 	ls.page[bucket] &= ^(1 << (by & 0x1f))
 }
 
-func langsetEqual(lsa, lsb Langset) bool {
+// Equal returns true if `lsa` and `lsb` contain the same languages.
+func (lsa Langset) Equal(lsb Langset) bool {
 	if lsa.page != lsb.page {
 		return false
 	}
@@ -356,7 +358,7 @@ func (ls Langset) containsLang(lang string) bool {
 	}
 	// search up and down among equal languages for a match
 	for i := id - 1; i >= 0; i-- {
-		if langCompare(fcLangCharSets[i].lang, lang) == langDifferentLang {
+		if langCompare(fcLangCharSets[i].lang, lang) == LangDifferentLang {
 			break
 		}
 		if ls.bitGet(i) && langContains(fcLangCharSets[i].lang, lang) {
@@ -364,7 +366,7 @@ func (ls Langset) containsLang(lang string) bool {
 		}
 	}
 	for i := id; i < len(fcLangCharSets); i++ {
-		if langCompare(fcLangCharSets[i].lang, lang) == langDifferentLang {
+		if langCompare(fcLangCharSets[i].lang, lang) == LangDifferentLang {
 			break
 		}
 		if ls.bitGet(i) && langContains(fcLangCharSets[i].lang, lang) {
@@ -381,8 +383,8 @@ func (ls Langset) containsLang(lang string) bool {
 	return extra != ""
 }
 
-// return true if lsa contains every language in lsb
-func (lsa Langset) includes(lsb Langset) bool {
+// Includes returns true if `lsa` contains every language in `lsb`.
+func (lsa Langset) Includes(lsb Langset) bool {
 	// check bitmaps for missing language support
 	for i := range lsb.page {
 		missing := lsb.page[i] & ^lsa.page[i]
@@ -421,7 +423,7 @@ func (lsa Langset) includes(lsb Langset) bool {
 func NewLangset(str string) Langset {
 	var ls Langset
 	for _, lang := range strings.Split(str, "|") {
-		ls.add(lang)
+		ls.Add(lang)
 	}
 	return ls
 }
@@ -468,14 +470,18 @@ var codePageRange = [...]struct {
 
 func isExclusiveLang(lang string) bool {
 	for _, cp := range codePageRange {
-		if langCompare(lang, cp.lang) == langEqual {
+		if langCompare(lang, cp.lang) == LangEqual {
 			return true
 		}
 	}
 	return false
 }
 
-func buildLangSet(charset Charset, exclusiveLang string) Langset {
+// BuildLangset returns the languages whose orthography is entirely
+// covered by `charset`, using the orthographies compiled into the package.
+// If `exclusiveLang` is one of the Han languages (ja, zh-cn, ko, zh-tw), the other
+// Han languages are only included if their orthography is the same.
+func BuildLangset(charset Charset, exclusiveLang string) Langset {
 	var exclusiveCharset *Charset
 	if exclusiveLang != "" {
 		exclusiveCharset = newCharSetFromLang(exclusiveLang)
@@ -511,7 +517,26 @@ mainLoop:
 	return ls
 }
 
-func (ls *Langset) del(lang string) {
+// LangCoverage returns the fraction of the orthography of `lang`
+// covered by `charset`, and the runes of the orthography missing in `charset`.
+// The orthographies are the ones compiled into the package; if `lang` has no exact
+// orthography, the one of a language with a different territory is used (for instance "fr"
+// for "fr-ca"). If no orthography is found, `ok` is false.
+func LangCoverage(charset Charset, lang string) (covered float32, missing Charset, ok bool) {
+	orthography := newCharSetFromLang(lang)
+	if orthography == nil {
+		return 0, Charset{}, false
+	}
+	missing = charsetSubtract(*orthography, charset)
+	total := orthography.Len()
+	if total == 0 {
+		return 1, missing, true
+	}
+	return float32(total-missing.Len()) / float32(total), missing, true
+}
+
+// Del removes `lang` from `ls`.
+func (ls *Langset) Del(lang string) {
 	id := findLangIndex(lang)
 	if id >= 0 {
 		ls.bitReset(id)
@@ -520,17 +545,21 @@ func (ls *Langset) del(lang string) {
 	}
 }
 
-func (ls *Langset) hasLang(lang string) langResult {
+// HasLang checks whether `ls` supports `lang`, returning
+// `LangEqual` if it does, `LangDifferentTerritory` if it supports
+// the same language with a different territory, and `LangDifferentLang`
+// otherwise.
+func (ls Langset) HasLang(lang string) LangResult {
 	id := findLangIndex(lang)
 	if id < 0 {
 		id = -id - 1
 	} else if ls.bitGet(id) {
-		return langEqual
+		return LangEqual
 	}
-	best := langDifferentLang
+	best := LangDifferentLang
 	for i := id - 1; i >= 0; i-- {
 		r := langCompare(lang, fcLangCharSets[i].lang)
-		if r == langDifferentLang {
+		if r == LangDifferentLang {
 			break
 		}
 		if ls.bitGet(i) && r < best {
@@ -539,7 +568,7 @@ func (ls *Langset) hasLang(lang string) langResult {
 	}
 	for i := id; i < len(fcLangCharSets); i++ {
 		r := langCompare(lang, fcLangCharSets[i].lang)
-		if r == langDifferentLang {
+		if r == LangDifferentLang {
 			break
 		}
 		if ls.bitGet(i) && r < best {
@@ -547,7 +576,7 @@ func (ls *Langset) hasLang(lang string) langResult {
 		}
 	}
 	for extra := range ls.extra {
-		if best <= langEqual {
+		if best <= LangEqual {
 			break
 		}
 		if r := langCompare(lang, extra); r < best {
@@ -557,28 +586,30 @@ func (ls *Langset) hasLang(lang string) langResult {
 	return best
 }
 
-func (ls *Langset) compareStrSet(set strSet) langResult {
-	best := langDifferentLang
+func (ls *Langset) compareStrSet(set strSet) LangResult {
+	best := LangDifferentLang
 	for extra := range set {
-		if best <= langEqual {
+		if best <= LangEqual {
 			break
 		}
-		if r := ls.hasLang(extra); r < best {
+		if r := ls.HasLang(extra); r < best {
 			best = r
 		}
 	}
 	return best
 }
 
-func langSetCompare(lsa, lsb Langset) langResult {
+// Compare returns the best relation between the languages in `lsa` and `lsb`
+// (see `HasLang`).
+func (lsa Langset) Compare(lsb Langset) LangResult {
 	var aInCountrySet, bInCountrySet uint32
 
 	for i := range lsa.page {
 		if lsa.page[i]&lsb.page[i] != 0 {
-			return langEqual
+			return LangEqual
 		}
 	}
-	best := langDifferentLang
+	best := LangDifferentLang
 	for _, langCountry := range fcLangCountrySets {
 		aInCountrySet = 0
 		bInCountrySet = 0
@@ -588,7 +619,7 @@ func langSetCompare(lsa, lsb Langset) langResult {
 			bInCountrySet |= lsb.page[i] & langCountry[i]
 
 			if aInCountrySet != 0 && bInCountrySet != 0 {
-				best = langDifferentTerritory
+				best = LangDifferentTerritory
 				break
 			}
 		}
@@ -598,7 +629,7 @@ func langSetCompare(lsa, lsb Langset) langResult {
 			best = r
 		}
 	}
-	if best > langEqual && lsb.extra != nil {
+	if best > LangEqual && lsb.extra != nil {
 		if r := lsa.compareStrSet(lsb.extra); r < best {
 			best = r
 		}
@@ -615,12 +646,14 @@ func langSetOperate(a, b Langset, fn func(ls *Langset, s string)) Langset {
 	return langset
 }
 
-func langSetUnion(a, b Langset) Langset {
-	return langSetOperate(a, b, (*Langset).add)
+// Union returns a new set with the languages of `a` and `b`.
+func (a Langset) Union(b Langset) Langset {
+	return langSetOperate(a, b, (*Langset).Add)
 }
 
-func langSetSubtract(a, b Langset) Langset {
-	return langSetOperate(a, b, (*Langset).del)
+// Subtract returns a new set with the languages of `a` which are not in `b`.
+func (a Langset) Subtract(b Langset) Langset {
+	return langSetOperate(a, b, (*Langset).Del)
 }
 
 func langSetPromote(lang String) Langset {
@@ -636,6 +669,17 @@ func langSetPromote(lang String) Langset {
 	return ls
 }
 
+// Langs returns the sorted list of the languages in `ls`.
+func (ls Langset) Langs() []string {
+	langs := ls.getLangs()
+	sorted := make([]string, 0, len(langs))
+	for l := range langs {
+		sorted = append(sorted, l)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
 // Returns a string set of all languages in `ls`.
 func (ls Langset) getLangs() strSet {
 	langs := make(strSet)
@@ -1,118 +1,119 @@
 package fontconfig
 
 import (
+	"reflect"
 	"testing"
 )
 
 // ported from fontconfig/test/test-bz89617.c: 2000 Keith Packard 2015 Akira TAGOH
 
-func comp(l1, l2 string) langResult {
+func comp(l1, l2 string) LangResult {
 	var ls1, ls2 Langset
 
-	ls1.add(l1)
-	ls2.add(l2)
+	ls1.Add(l1)
+	ls2.Add(l2)
 
-	return langSetCompare(ls1, ls2)
+	return ls1.Compare(ls2)
 }
 
 func TestCompareLang(t *testing.T) {
 	/* 1 */
-	if comp("ku-am", "ku-iq") != langDifferentTerritory {
+	if comp("ku-am", "ku-iq") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-am", "ku-iq")
 	}
 
 	/* 2 */
-	if comp("ku-am", "ku-ir") != langDifferentTerritory {
+	if comp("ku-am", "ku-ir") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-am", "ku-ir")
 	}
 
 	/* 3 */
-	if comp("ku-am", "ku-tr") != langDifferentTerritory {
+	if comp("ku-am", "ku-tr") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-am", "ku-tr")
 	}
 
 	/* 4 */
-	if comp("ku-iq", "ku-ir") != langDifferentTerritory {
+	if comp("ku-iq", "ku-ir") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-iq", "ku-ir")
 	}
 
 	/* 5 */
-	if comp("ku-iq", "ku-tr") != langDifferentTerritory {
+	if comp("ku-iq", "ku-tr") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-iq", "ku-tr")
 	}
 
 	/* 6 */
-	if comp("ku-ir", "ku-tr") != langDifferentTerritory {
+	if comp("ku-ir", "ku-tr") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ku-ir", "ku-tr")
 	}
 
 	/* 7 */
-	if comp("ps-af", "ps-pk") != langDifferentTerritory {
+	if comp("ps-af", "ps-pk") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ps-af", "ps-pk")
 	}
 
 	/* 8 */
-	if comp("ti-er", "ti-et") != langDifferentTerritory {
+	if comp("ti-er", "ti-et") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "ti-er", "ti-et")
 	}
 
 	/* 9 */
-	if comp("zh-cn", "zh-hk") != langDifferentTerritory {
+	if comp("zh-cn", "zh-hk") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-cn", "zh-hk")
 	}
 
 	/* 10 */
-	if comp("zh-cn", "zh-mo") != langDifferentTerritory {
+	if comp("zh-cn", "zh-mo") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-cn", "zh-mo")
 	}
 
 	/* 11 */
-	if comp("zh-cn", "zh-sg") != langDifferentTerritory {
+	if comp("zh-cn", "zh-sg") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-cn", "zh-sg")
 	}
 
 	/* 12 */
-	if comp("zh-cn", "zh-tw") != langDifferentTerritory {
+	if comp("zh-cn", "zh-tw") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-cn", "zh-tw")
 	}
 
 	/* 13 */
-	if comp("zh-hk", "zh-mo") != langDifferentTerritory {
+	if comp("zh-hk", "zh-mo") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-hk", "zh-mo")
 	}
 
 	/* 14 */
-	if comp("zh-hk", "zh-sg") != langDifferentTerritory {
+	if comp("zh-hk", "zh-sg") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-hk", "zh-sg")
 	}
 
 	/* 15 */
-	if comp("zh-hk", "zh-tw") != langDifferentTerritory {
+	if comp("zh-hk", "zh-tw") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-hk", "zh-tw")
 	}
 
 	/* 16 */
-	if comp("zh-mo", "zh-sg") != langDifferentTerritory {
+	if comp("zh-mo", "zh-sg") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-mo", "zh-sg")
 	}
 
 	/* 17 */
-	if comp("zh-mo", "zh-tw") != langDifferentTerritory {
+	if comp("zh-mo", "zh-tw") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-mo", "zh-tw")
 	}
 
 	/* 18 */
-	if comp("zh-sg", "zh-tw") != langDifferentTerritory {
+	if comp("zh-sg", "zh-tw") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "zh-sg", "zh-tw")
 	}
 
 	/* 19 */
-	if comp("mn-mn", "mn-cn") != langDifferentTerritory {
+	if comp("mn-mn", "mn-cn") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "mn-mn", "mn-cn")
 	}
 
 	/* 20 */
-	if comp("pap-an", "pap-aw") != langDifferentTerritory {
+	if comp("pap-an", "pap-aw") != LangDifferentTerritory {
 		t.Errorf("wrong comparison for %s and %s", "pap-an", "pap-aw")
 	}
 }
@@ -120,7 +121,7 @@ func TestCompareLang(t *testing.T) {
 func langsetFrom(langs []string) Langset {
 	var ls Langset
 	for _, lang := range langs {
-		ls.add(lang)
+		ls.Add(lang)
 	}
 	return ls
 }
@@ -149,3 +150,60 @@ func TestBasicLangsetOps(t *testing.T) {
 		}
 	}
 }
+
+func TestLangsetOperations(t *testing.T) {
+	a := NewLangset("fr|en|x-custom")
+	b := NewLangset("en|de")
+
+	if exp, got := []string{"de", "en", "fr", "x-custom"}, a.Union(b).Langs(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if exp, got := []string{"fr", "x-custom"}, a.Subtract(b).Langs(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if fr, en := NewLangset("fr|en"), NewLangset("en"); !fr.Includes(en) || en.Includes(fr) {
+		t.Fatal("invalid inclusion")
+	}
+	if r := a.Compare(b); r != LangEqual {
+		t.Fatalf("expected equal, got %d", r)
+	}
+	if r := a.HasLang("fr-ca"); r != LangDifferentTerritory {
+		t.Fatalf("expected different territory, got %d", r)
+	}
+
+	c := a.Copy()
+	c.Del("x-custom")
+	c.Del("fr")
+	if !c.Equal(NewLangset("en")) || a.Equal(c) {
+		t.Fatalf("unexpected langset %s", c)
+	}
+}
+
+func TestLangCoverage(t *testing.T) {
+	fr := *newCharSetFromLang("fr")
+
+	covered, missing, ok := LangCoverage(fr, "fr")
+	if !ok || covered != 1 || missing.Len() != 0 {
+		t.Fatalf("unexpected coverage %g (%s)", covered, missing.Text())
+	}
+	if ls := BuildLangset(fr, ""); ls.HasLang("fr") != LangEqual {
+		t.Fatalf("expected french in %s", ls)
+	}
+
+	// remove some accents
+	partial := fr.Subtract(NewCharsetFromRanges([][2]rune{{'é', 'é'}, {'è', 'è'}}))
+	covered, missing, ok = LangCoverage(partial, "fr-ca") // no orthography for fr-ca: use fr
+	if !ok || missing.Text() != "e8-e9" {
+		t.Fatalf("unexpected missing runes %s", missing.Text())
+	}
+	if exp := float32(fr.Len()-2) / float32(fr.Len()); covered != exp {
+		t.Fatalf("expected coverage %g, got %g", exp, covered)
+	}
+	if ls := BuildLangset(partial, ""); ls.HasLang("fr") == LangEqual {
+		t.Fatal("french should not be supported")
+	}
+
+	if _, _, ok = LangCoverage(fr, "xx-unknown"); ok {
+		t.Fatal("expected no orthography")
+	}
+}
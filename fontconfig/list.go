@@ -65,26 +65,26 @@ func (font Pattern) getDefaultObjectLangIndex(object Object, lang string) int {
 	for i, v := range e {
 		if s, ok := v.Value.(String); ok {
 			res := langCompare(string(s), lang)
-			if res == langEqual {
+			if res == LangEqual {
 				return i
 			}
-			if res == langDifferentCountry && idx < 0 {
+			if res == LangDifferentTerritory && idx < 0 {
 				idx = i
 			}
 			if defidx < 0 {
 				// workaround for fonts that has non-English value at the head of values.
 				res = langCompare(string(s), "en")
-				if res == langEqual {
+				if res == LangEqual {
 					defidx = i
 				}
 			}
 		}
 	}
 
-	if idx > 0 {
+	if idx >= 0 {
 		return idx
 	}
-	if defidx > 0 {
+	if defidx >= 0 {
 		return defidx
 	}
 	return 0
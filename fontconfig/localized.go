@@ -0,0 +1,96 @@
+package fontconfig
+
+import "strings"
+
+// LocalizedName is one of the values of the FAMILY, STYLE or FULLNAME objects,
+// as returned by `Pattern.LocalizedNames`.
+type LocalizedName struct {
+	Name string
+	// Lang is the language of the name, as stored in the pattern,
+	// like "en" or "zh-tw", or "und" if it is unknown.
+	Lang string
+}
+
+// return the object storing the languages of `object`
+func langObject(object Object) Object {
+	switch object {
+	case FAMILY:
+		return FAMILYLANG
+	case STYLE:
+		return STYLELANG
+	case FULLNAME:
+		return FULLNAMELANG
+	default:
+		return invalid
+	}
+}
+
+// LocalizedNames returns all the values of `object`, which must be FAMILY,
+// STYLE or FULLNAME, with their language, in the order of the pattern.
+func (p Pattern) LocalizedNames(object Object) []LocalizedName {
+	objectLang := langObject(object)
+	if objectLang == invalid {
+		return nil
+	}
+	names, langs := p.GetStrings(object), p.GetStrings(objectLang)
+	out := make([]LocalizedName, len(names))
+	for i, name := range names {
+		out[i] = LocalizedName{Name: name, Lang: "und"}
+		if i < len(langs) {
+			out[i].Lang = langs[i]
+		}
+	}
+	return out
+}
+
+// LocalizedFamily returns the family name best suited for `lang`,
+// a BCP 47 language tag like "ja" or "zh-Hant-TW".
+// The name in the same language, then in the same language but another region,
+// then in English are preferred. Otherwise, the first family is returned.
+// An empty string is returned if the pattern has no family.
+func (p Pattern) LocalizedFamily(lang string) string {
+	return p.localizedName(FAMILY, lang)
+}
+
+// LocalizedStyle is the same as `LocalizedFamily`, for the STYLE object.
+func (p Pattern) LocalizedStyle(lang string) string {
+	return p.localizedName(STYLE, lang)
+}
+
+// LocalizedFullname is the same as `LocalizedFamily`, for the FULLNAME object.
+func (p Pattern) LocalizedFullname(lang string) string {
+	return p.localizedName(FULLNAME, lang)
+}
+
+func (p Pattern) localizedName(object Object, lang string) string {
+	idx := p.getDefaultObjectLangIndex(langObject(object), langFromBCP47(lang))
+	name, _ := p.getAtString(object, idx)
+	return name
+}
+
+// langFromBCP47 converts a BCP 47 tag to the language tags found in
+// the patterns : the script subtag is removed, or converted to
+// a region for Chinese, and the extensions are ignored.
+func langFromBCP47(tag string) string {
+	subtags := strings.Split(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+	lang, region := subtags[0], ""
+	for _, subtag := range subtags[1:] {
+		if len(subtag) == 4 && region == "" && lang == "zh" { // script
+			switch subtag {
+			case "hans":
+				region = "cn"
+			case "hant":
+				region = "tw"
+			}
+		} else if len(subtag) == 2 || (len(subtag) == 3 && subtag[0] >= '0' && subtag[0] <= '9') { // region
+			region = subtag
+			break
+		} else if len(subtag) != 4 { // variants or extensions
+			break
+		}
+	}
+	if region != "" {
+		return lang + "-" + region
+	}
+	return lang
+}
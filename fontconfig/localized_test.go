@@ -0,0 +1,94 @@
+package fontconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLangFromBCP47(t *testing.T) {
+	for _, test := range [][2]string{
+		{"en", "en"},
+		{"en-US", "en-us"},
+		{"fr_CA", "fr-ca"},
+		{"ja-Jpan-JP", "ja-jp"},
+		{"zh-Hant", "zh-tw"},
+		{"zh-Hans", "zh-cn"},
+		{"zh-Hant-HK", "zh-hk"},
+		{"es-419", "es-419"},
+		{"de-CH-1996", "de-ch"},
+		{"sr-Latn", "sr"},
+	} {
+		if got := langFromBCP47(test[0]); got != test[1] {
+			t.Fatalf("for %s, expected %s, got %s", test[0], test[1], got)
+		}
+	}
+}
+
+func TestLocalizedNames(t *testing.T) {
+	p := NewPattern()
+	for _, name := range [][2]string{
+		{"Noto Sans CJK TC", "zh-tw"},
+		{"Noto Sans CJK", "en"},
+		{"思源黑體", "zh-hk"},
+		{"源ノ角ゴシック", "ja"},
+	} {
+		p.AddString(FAMILY, name[0])
+		p.AddString(FAMILYLANG, name[1])
+	}
+	p.AddString(STYLE, "Bold")
+	p.AddString(STYLELANG, "en")
+	p.AddString(STYLE, "太字")
+	p.AddString(STYLELANG, "ja")
+	p.AddString(FULLNAME, "Noto Sans CJK Bold") // without language
+
+	for _, test := range []struct {
+		lang, family, style string
+	}{
+		{"ja", "源ノ角ゴシック", "太字"},
+		{"ja-JP", "源ノ角ゴシック", "太字"},
+		{"zh-Hant-HK", "思源黑體", "Bold"},
+		{"zh-Hant", "Noto Sans CJK TC", "Bold"},
+		{"zh-Hans", "Noto Sans CJK TC", "Bold"}, // same language, other region
+		{"fr", "Noto Sans CJK", "Bold"},         // English fallback
+	} {
+		if got := p.LocalizedFamily(test.lang); got != test.family {
+			t.Fatalf("for %s, expected family %s, got %s", test.lang, test.family, got)
+		}
+		if got := p.LocalizedStyle(test.lang); got != test.style {
+			t.Fatalf("for %s, expected style %s, got %s", test.lang, test.style, got)
+		}
+	}
+
+	if got := p.LocalizedFullname("ja"); got != "Noto Sans CJK Bold" {
+		t.Fatalf("unexpected fullname %s", got)
+	}
+
+	exp := []LocalizedName{{"Bold", "en"}, {"太字", "ja"}}
+	if got := p.LocalizedNames(STYLE); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	exp = []LocalizedName{{"Noto Sans CJK Bold", "und"}}
+	if got := p.LocalizedNames(FULLNAME); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if got := p.LocalizedNames(FILE); got != nil {
+		t.Fatalf("unexpected names %v", got)
+	}
+
+	if got := NewPattern().LocalizedFamily("en"); got != "" {
+		t.Fatalf("unexpected family %s", got)
+	}
+}
+
+func TestLocalizedScan(t *testing.T) {
+	fs, err := NewConfig().ScanFontFile("test/DejaVuSerif-Italic.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fs[0].LocalizedFamily("ja"); got != "DejaVu Serif" {
+		t.Fatalf("unexpected family %s", got)
+	}
+	if got := fs[0].LocalizedStyle("en-GB"); got != "Italic" {
+		t.Fatalf("unexpected style %s", got)
+	}
+}
@@ -137,21 +137,21 @@ func comparePostScript(v1, v2 Value) (Value, float32) {
 }
 
 func compareLang(val1, val2 Value) (Value, float32) {
-	var result langResult
+	var result LangResult
 	switch v1 := val1.(type) {
 	case Langset:
 		switch v2 := val2.(type) {
 		case Langset:
-			result = langSetCompare(v1, v2)
+			result = v1.Compare(v2)
 		case String:
-			result = v1.hasLang(string(v2))
+			result = v1.HasLang(string(v2))
 		default:
 			return nil, -1.0
 		}
 	case String:
 		switch v2 := val2.(type) {
 		case Langset:
-			result = v2.hasLang(string(v1))
+			result = v2.HasLang(string(v1))
 		case String:
 			result = langCompare(string(v1), string(v2))
 		default:
@@ -163,9 +163,9 @@ func compareLang(val1, val2 Value) (Value, float32) {
 	}
 	bestValue := val2
 	switch result {
-	case langEqual:
+	case LangEqual:
 		return bestValue, 0
-	case langDifferentCountry:
+	case LangDifferentTerritory:
 		return bestValue, 1
 	default:
 		return bestValue, 2
@@ -186,7 +186,8 @@ func compareBool(val1, val2 Value) (Value, float32) {
 		bestValue = v1
 	}
 
-	if v1 == v2 {
+	// DontCare matches both true and false
+	if v1 == v2 || v1 == DontCare || v2 == DontCare {
 		return bestValue, 0
 	}
 	return bestValue, 1
@@ -265,6 +266,21 @@ func compareRange(v1, v2 Value) (Value, float32) {
 	return bestValue, 0.0
 }
 
+// compareSlant handles the ranges of variable fonts,
+// always returning an Int value
+func compareSlant(v1, v2 Value) (Value, float32) {
+	_, isRange1 := v1.(Range)
+	_, isRange2 := v2.(Range)
+	if !isRange1 && !isRange2 {
+		return compareNumber(v1, v2)
+	}
+	v, d := compareRange(v1, v2)
+	if v == nil {
+		return nil, d
+	}
+	return Int(math.Round(float64(v.(Float)))), d
+}
+
 func compareSize(v1, v2 Value) (Value, float32) {
 	var b1, e1, b2, e2 float32
 
@@ -461,7 +477,7 @@ var fcMatchers = [...]matcher{
 	{nil, STYLELANG, -1, -1},
 	{nil, FULLNAME, -1, -1},
 	{nil, FULLNAMELANG, -1, -1},
-	{compareNumber, SLANT, priSLANT_STRONG, priSLANT_WEAK},
+	{compareSlant, SLANT, priSLANT_STRONG, priSLANT_WEAK},
 	{compareRange, WEIGHT, priWEIGHT_STRONG, priWEIGHT_WEAK},
 	{compareRange, WIDTH, priWIDTH_STRONG, priWIDTH_WEAK},
 	{compareSize, SIZE, priSIZE_STRONG, priSIZE_WEAK},
@@ -669,6 +685,9 @@ func (data compareData) compare(pat, font Pattern, value []float32) (bool, Resul
 // in `pat`, elements of `pat` not appearing in `font` and the best matching
 // value from `pat` for elements appearing in both.  The result is passed to
 // `config.Substitute` with `kind = MatchResult` and then returned.
+// For variable fonts, the values resolved for the weight, width, slant and size
+// are also stored in FONT_VARIATIONS, as settings for the 'wght', 'wdth',
+// 'ital' or 'slnt', and 'opsz' axes, followed by the settings of `pat`, if any.
 func (config *Config) PrepareRender(pat, font Pattern) Pattern {
 	var (
 		variations strings.Builder
@@ -747,23 +766,8 @@ func (config *Config) PrepareRender(pat, font Pattern) Pattern {
 			new.Add(obj, v, false)
 
 			// Set font-variations settings for standard axes in variable fonts.
-			if _, isRange := fe[0].Value.(Range); variable != 0 && isRange &&
-				(obj == WEIGHT || obj == WIDTH || obj == SIZE) {
-				tag := "    "
-				num := float32(v.(Float)) //  v.type == FcTypeDouble
-				if variations.Len() != 0 {
-					variations.WriteByte(',')
-				}
-				switch obj {
-				case WEIGHT:
-					tag = "wght"
-					num = WeightToOT(num)
-				case WIDTH:
-					tag = "wdth"
-				case SIZE:
-					tag = "opsz"
-				}
-				fmt.Fprintf(&variations, "%4s=%g", tag, num)
+			if r, isRange := fe[0].Value.(Range); variable != 0 && isRange {
+				writeVariations(&variations, font, obj, v, r)
 			}
 		} else {
 			new.addList(obj, *fe.duplicate(), true)
@@ -792,6 +796,63 @@ func (config *Config) PrepareRender(pat, font Pattern) Pattern {
 	return new
 }
 
+// the oblique angle used when a slanted style is requested
+// from a variable font with a 'slnt' axis, as in CSS.
+// It is clamped to the range of the axis, stored in SLANT_AXIS.
+const defaultObliqueAngle = 14
+
+// writeVariations adds the font-variations setting for `value`,
+// resolved for the axis matching `obj`, whose range in `font` is `r`.
+// Other objects are ignored.
+func writeVariations(variations *strings.Builder, font Pattern, obj Object, value Value, r Range) {
+	var num float32
+	switch value := value.(type) {
+	case Int:
+		num = float32(value)
+	case Float:
+		num = float32(value)
+	default:
+		return
+	}
+
+	var tag string
+	switch obj {
+	case WEIGHT:
+		tag, num = "wght", WeightToOT(num)
+	case WIDTH:
+		tag = "wdth"
+	case SIZE:
+		tag = "opsz"
+	case SLANT:
+		// the font covers oblique styles only if it has a 'slnt' axis,
+		// which is then preferred, and also used for italic styles
+		italic := num >= SLANT_ITALIC
+		if r.End >= SLANT_OBLIQUE {
+			tag, num = "slnt", 0
+			if italic {
+				num = -defaultObliqueAngle
+			}
+			if axis, _ := font.GetAt(SLANT_AXIS, 0); axis != nil {
+				if axis, ok := axis.(Range); ok {
+					num = maxF(axis.Begin, minF(num, axis.End))
+				}
+			}
+		} else {
+			tag, num = "ital", 0
+			if italic {
+				num = 1
+			}
+		}
+	default:
+		return
+	}
+
+	if variations.Len() != 0 {
+		variations.WriteByte(',')
+	}
+	fmt.Fprintf(variations, "%s=%g", tag, num)
+}
+
 func (set Fontset) matchInternal(p Pattern) Pattern {
 	var (
 		score, bestscore [priorityEnd]float32
@@ -851,17 +912,21 @@ func (set Fontset) matchInternal(p Pattern) Pattern {
 // modify these patterns. Instead, they should be passed, along with
 // `p`, to `Config.PrepareRender()` which combines them into a complete pattern.
 func (set Fontset) Sort(p Pattern, trim bool) (Fontset, Charset) {
-	if debugMode {
-		fmt.Println("Sort input :", p.String())
+	nodes := set.sortNodes(p)
+	if nodes == nil {
+		return nil, Charset{}
 	}
+	return sortWalk(nodes, trim)
+}
 
-	nPatternLang := 0
-	for res := ResultMatch; res == ResultMatch; nPatternLang++ {
-		_, res = p.GetAt(LANG, nPatternLang)
+// sortNodes computes the scores of the fonts and sorts them,
+// returning nil on failure.
+func (set Fontset) sortNodes(p Pattern) []*sortNode {
+	if debugMode {
+		fmt.Println("Sort input :", p.String())
 	}
 
 	nodes := make([]*sortNode, len(set))
-	patternLangSat := make([]bool, nPatternLang)
 
 	data := p.newCompareData()
 
@@ -874,7 +939,7 @@ func (set Fontset) Sort(p Pattern, trim bool) (Fontset, Charset) {
 		newPtr.pattern = font
 		ok, _ := data.compare(p, newPtr.pattern, newPtr.score[:])
 		if !ok {
-			return nil, Charset{}
+			return nil
 		}
 		if debugMode {
 			fmt.Println("Score", newPtr.score)
@@ -882,6 +947,18 @@ func (set Fontset) Sort(p Pattern, trim bool) (Fontset, Charset) {
 		nodes[i] = newPtr
 	}
 
+	return sortScoredNodes(p, nodes)
+}
+
+// sortScoredNodes sorts the nodes, whose scores have been computed,
+// taking into account the languages satisfied by the fonts.
+func sortScoredNodes(p Pattern, nodes []*sortNode) []*sortNode {
+	nPatternLang := 0
+	for res := ResultMatch; res == ResultMatch; nPatternLang++ {
+		_, res = p.GetAt(LANG, nPatternLang)
+	}
+	patternLangSat := make([]bool, nPatternLang)
+
 	sort.Slice(nodes, func(i, j int) bool { return sortCompare(nodes[i], nodes[j]) })
 
 	for _, node := range nodes {
@@ -909,7 +986,7 @@ func (set Fontset) Sort(p Pattern, trim bool) (Fontset, Charset) {
 	// re-sort once the language issues have been settled
 	sort.Slice(nodes, func(i, j int) bool { return sortCompare(nodes[i], nodes[j]) })
 
-	return sortWalk(nodes, trim)
+	return nodes
 }
 
 // Match finds the font in `set` most closely matching
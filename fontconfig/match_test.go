@@ -89,3 +89,119 @@ func TestFamily(t *testing.T) {
 	`
 	shouldMatchPattern(t, test, pat, true)
 }
+
+func TestExplain(t *testing.T) {
+	var fs Fontset
+	for _, font := range []struct {
+		family string
+		weight float32
+	}{{"A", WEIGHT_REGULAR}, {"B", WEIGHT_REGULAR}, {"B", WEIGHT_BOLD}, {"C", WEIGHT_BOLD}} {
+		p := NewPattern()
+		p.AddString(FAMILY, font.family)
+		p.AddFloat(WEIGHT, font.weight)
+		p.AddString(FILE, font.family+fmt.Sprint(font.weight))
+		fs = append(fs, p)
+	}
+
+	query := NewPattern()
+	query.AddString(FAMILY, "C")
+	query.AddString(FAMILY, "B")
+	query.AddFloat(WEIGHT, WEIGHT_BOLD)
+	query.SubstituteDefault()
+
+	sorted, _ := fs.Sort(query, false)
+	explained := fs.Explain(query, 2)
+	if len(explained) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(explained))
+	}
+	for i, ex := range explained {
+		if ex.Font.Hash() != sorted[i].Hash() {
+			t.Fatalf("unexpected order %s", ex)
+		}
+	}
+
+	if L := len(fs.Explain(query, -1)); L != len(fs) {
+		t.Fatalf("expected %d candidates, got %d", len(fs), L)
+	}
+
+	// the second candidate is B bold, matching the second query family
+	second := explained[1]
+	familyIndex, weightIndex := -1, -1
+	for i, score := range second.Scores {
+		if score.Object == FAMILY && score.Strong {
+			familyIndex = i
+		} else if score.Object == WEIGHT {
+			weightIndex = i
+		}
+	}
+	if familyIndex == -1 || weightIndex == -1 {
+		t.Fatalf("missing scores in %s", second)
+	}
+	if familyIndex > weightIndex {
+		t.Fatalf("unexpected priority order %s", second)
+	}
+	if sc := second.Scores[familyIndex]; sc.QueryIndex != 1 || sc.QueryValue != String("B") || sc.Score != 1 {
+		t.Fatalf("unexpected family score %s", sc)
+	}
+	if sc := second.Scores[weightIndex]; sc.Score != 0 || sc.QueryIndex != 0 {
+		t.Fatalf("unexpected weight score %s", sc)
+	}
+}
+
+func TestMatchVariable(t *testing.T) {
+	static := BuildPattern(
+		PatternElement{Object: FAMILY, Value: String("Sans")},
+		PatternElement{Object: SLANT, Value: Int(SLANT_ROMAN)},
+		PatternElement{Object: WEIGHT, Value: Float(WEIGHT_MEDIUM)},
+		PatternElement{Object: WIDTH, Value: Float(WIDTH_NORMAL)},
+	)
+	italic := BuildPattern(
+		PatternElement{Object: FAMILY, Value: String("Sans")},
+		PatternElement{Object: SLANT, Value: Int(SLANT_ITALIC)},
+		PatternElement{Object: WEIGHT, Value: Float(WEIGHT_MEDIUM)},
+		PatternElement{Object: WIDTH, Value: Float(WIDTH_NORMAL)},
+	)
+	variable := func(slant Range) Pattern {
+		return BuildPattern(
+			PatternElement{Object: FAMILY, Value: String("Sans")},
+			PatternElement{Object: VARIABLE, Value: True},
+			PatternElement{Object: SLANT, Value: slant},
+			PatternElement{Object: WEIGHT, Value: Range{Begin: WeightFromOT(100), End: WeightFromOT(900)}},
+			PatternElement{Object: WIDTH, Value: Range{Begin: WIDTH_CONDENSED, End: WIDTH_NORMAL}},
+			PatternElement{Object: SIZE, Value: Range{Begin: 8, End: 72}},
+		)
+	}
+
+	// with a 'slnt' axis narrower than the default oblique angle
+	narrowSlant := variable(Range{End: SLANT_OBLIQUE})
+	narrowSlant.Add(SLANT_AXIS, Range{Begin: -10, End: 0}, true)
+
+	for _, test := range []struct {
+		fonts      Fontset
+		query      string
+		variations string
+	}{
+		{Fontset{static, italic, variable(Range{End: SLANT_ITALIC})}, "Sans-10:variable=true", "ital=0,wght=400,wdth=100,opsz=10"},
+		{Fontset{static, italic, variable(Range{End: SLANT_ITALIC})}, fmt.Sprintf("Sans-20:weight=%g:width=80:italic:variable=dontcare", WeightFromOT(530)), "ital=1,wght=530,wdth=80,opsz=20"},
+		{Fontset{static, italic, variable(Range{End: SLANT_OBLIQUE})}, "Sans:italic:variable=true", "slnt=-14,wght=400,wdth=100,opsz=12"},
+		{Fontset{static, italic, variable(Range{End: SLANT_OBLIQUE})}, "Sans:weight=1000:width=150:variable=true", "slnt=0,wght=900,wdth=100,opsz=12"},
+		{Fontset{static, italic, narrowSlant}, "Sans:oblique:variable=true", "slnt=-10,wght=400,wdth=100,opsz=12"},
+	} {
+		query, err := ParseName(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query.SubstituteDefault()
+
+		match := test.fonts.Match(query, NewConfig())
+		if b, _ := match.GetBool(VARIABLE); b != True {
+			t.Fatalf("for %s, expected the variable font, got %s", test.query, match)
+		}
+		if got, _ := match.GetString(FONT_VARIATIONS); got != test.variations {
+			t.Fatalf("for %s, expected variations %s, got %s", test.query, test.variations, got)
+		}
+		if _, ok := match.GetInt(SLANT); !ok {
+			t.Fatalf("for %s, expected a resolved slant", test.query)
+		}
+	}
+}
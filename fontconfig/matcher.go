@@ -0,0 +1,295 @@
+package fontconfig
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Matcher is a prepared version of a `Fontset`, which speeds up
+// repeated calls to `Match` and `Sort`.
+// It precomputes the following indexes:
+//   - the distinct values of each object, so that they are compared
+//     only once per query, instead of once per font
+//   - the fonts for each (normalized) family name
+//   - a bitmap of the pages of the charsets, used to compute coverage
+//
+// The results are the same as the ones of `Fontset.Match` and `Fontset.Sort`.
+// A Matcher is safe for concurrent use, but the underlying fontset
+// must not be mutated.
+type Matcher struct {
+	set Fontset
+
+	objects map[Object]*objectTable
+
+	families  map[string][]int32 // normalized family name -> fonts
+	hasFamily []bool             // for each font
+}
+
+// objectTable stores the distinct value lists of an object
+type objectTable struct {
+	classes   []valueList
+	fontClass []int32 // for each font, the index in classes, or -1 if the object is missing
+
+	pages [][]*charsetPages // for CHARSET only, the pages of each value of each class
+}
+
+// NewMatcher builds the indexes for the given fontset.
+func NewMatcher(set Fontset) *Matcher {
+	m := Matcher{
+		set:       set,
+		objects:   make(map[Object]*objectTable),
+		families:  make(map[string][]int32),
+		hasFamily: make([]bool, len(set)),
+	}
+	classKeys := make(map[Object]map[string]int32)
+	for i, font := range set {
+		for object, values := range font {
+			if object == FAMILY {
+				m.addFamilies(int32(i), *values)
+				continue
+			}
+			// objects without matcher are not used when comparing
+			if object.toMatcher(false) == nil {
+				continue
+			}
+			table := m.objects[object]
+			if table == nil {
+				table = &objectTable{fontClass: make([]int32, len(set))}
+				for j := range table.fontClass {
+					table.fontClass[j] = -1
+				}
+				m.objects[object] = table
+				classKeys[object] = make(map[string]int32)
+			}
+			key := string(values.classKey())
+			class, ok := classKeys[object][key]
+			if !ok {
+				class = int32(len(table.classes))
+				classKeys[object][key] = class
+				table.classes = append(table.classes, *values)
+				if object == CHARSET {
+					table.pages = append(table.pages, newCharsetPages(*values))
+				}
+			}
+			table.fontClass[i] = class
+		}
+	}
+	return &m
+}
+
+func (m *Matcher) addFamilies(font int32, families valueList) {
+	m.hasFamily[font] = true
+	for _, v := range families {
+		key, _ := v.Value.(String) // should be string, but we are cautious
+		normalized := ignoreBlanksAndCase(string(key))
+		fonts := m.families[normalized]
+		if len(fonts) != 0 && fonts[len(fonts)-1] == font { // duplicate family
+			continue
+		}
+		m.families[normalized] = append(fonts, font)
+	}
+}
+
+// classKey returns a string identifying the values of the list
+// (but not their bindings, which are not used for the fonts).
+func (l valueList) classKey() []byte {
+	var out []byte
+	var buf [4]byte
+	for _, v := range l {
+		h := v.hash()
+		out = append(out, fmt.Sprintf("%T", v.Value)...)
+		binary.BigEndian.PutUint32(buf[:], uint32(len(h)))
+		out = append(out, buf[:]...)
+		out = append(out, h...)
+	}
+	return out
+}
+
+// Fontset returns the fontset used to build the matcher.
+func (m *Matcher) Fontset() Fontset { return m.set }
+
+// scores computes the score of each font, or returns false
+// if one comparison fails. See `compareData.compare`.
+func (m *Matcher) scores(p Pattern) ([]sortNode, bool) {
+	nodes := make([]sortNode, len(m.set))
+	for i, font := range m.set {
+		nodes[i].pattern = font
+	}
+
+	var classScore [priorityEnd]float32
+	for object, values := range p {
+		if object == FAMILY {
+			m.scoreFamilies(p, nodes)
+			continue
+		}
+		table := m.objects[object]
+		if table == nil { // no font has this object, or it is not used
+			continue
+		}
+		match := object.toMatcher(false)
+		strong, weak := match.strong, match.weak
+
+		// compare each class once
+		classScores := make([][2]float32, len(table.classes))
+		for c, class := range table.classes {
+			for i := range classScore {
+				classScore[i] = 0
+			}
+			if object == CHARSET && table.pages[c] != nil && isCharsetList(*values) {
+				classScore[strong] += compareCharsetPages(*values, class, table.pages[c])
+			} else if _, _, _, ok := fdFromPatternList(object, match, *values, class, classScore[:]); !ok {
+				return nil, false
+			}
+			classScores[c] = [2]float32{classScore[strong], classScore[weak]}
+		}
+
+		for i, class := range table.fontClass {
+			if class == -1 {
+				continue
+			}
+			// accumulate as fdFromPatternList does,
+			// since a priority may be shared by several objects
+			if strong == weak {
+				nodes[i].score[strong] += classScores[class][0]
+			} else {
+				nodes[i].score[strong] += classScores[class][0]
+				nodes[i].score[weak] += classScores[class][1]
+			}
+		}
+	}
+	return nodes, true
+}
+
+// scoreFamilies is the same as compareFamilies, using the family index.
+// As compareFamilies, it assumes that the family priorities
+// are not used by other objects (see fcMatchers).
+func (m *Matcher) scoreFamilies(p Pattern, nodes []sortNode) {
+	for i, has := range m.hasFamily {
+		if has {
+			nodes[i].score[priFAMILY_STRONG] = math.MaxFloat32
+			nodes[i].score[priFAMILY_WEAK] = math.MaxFloat32
+		}
+	}
+	table := p.newCompareData()
+	for key, e := range table { // keys are normalized
+		for _, font := range m.families[key] {
+			score := &nodes[font].score
+			if e.strongValue < score[priFAMILY_STRONG] {
+				score[priFAMILY_STRONG] = e.strongValue
+			}
+			if e.weakValue < score[priFAMILY_WEAK] {
+				score[priFAMILY_WEAK] = e.weakValue
+			}
+		}
+	}
+}
+
+// Match is the same as `Fontset.Match`.
+func (m *Matcher) Match(p Pattern, config *Config) Pattern {
+	nodes, ok := m.scores(p)
+	if !ok || len(nodes) == 0 {
+		return nil
+	}
+	best := &nodes[0]
+	for i := range nodes[1:] {
+		if node := &nodes[i+1]; sortCompare(node, best) {
+			best = node
+		}
+	}
+	return config.PrepareRender(p, best.pattern)
+}
+
+// Sort is the same as `Fontset.Sort`.
+func (m *Matcher) Sort(p Pattern, trim bool) (Fontset, Charset) {
+	nodes, ok := m.scores(p)
+	if !ok {
+		return nil, Charset{}
+	}
+	ptrs := make([]*sortNode, len(nodes))
+	for i := range nodes {
+		ptrs[i] = &nodes[i]
+	}
+	return sortWalk(sortScoredNodes(p, ptrs), trim)
+}
+
+// charsetPages is a bitmap of the pages used by a charset,
+// for the Unicode range.
+type charsetPages [(0x10FFFF>>8)/64 + 1]uint64
+
+// newCharsetPages returns the pages of each charset in `l`,
+// or nil if one value is not a charset.
+func newCharsetPages(l valueList) []*charsetPages {
+	out := make([]*charsetPages, len(l))
+	for i, v := range l {
+		cs, ok := v.Value.(Charset)
+		if !ok {
+			return nil
+		}
+		var pages charsetPages
+		for _, page := range cs.pageNumbers {
+			if int(page)/64 < len(pages) {
+				pages[page/64] |= 1 << (page % 64)
+			}
+		}
+		out[i] = &pages
+	}
+	return out
+}
+
+func isCharsetList(l valueList) bool {
+	for _, v := range l {
+		if _, ok := v.Value.(Charset); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// has returns false if `page` is not used
+func (cp *charsetPages) has(page uint16) bool {
+	if int(page)/64 >= len(cp) { // not in the bitmap: fall back to the search
+		return true
+	}
+	return cp[page/64]&(1<<(page%64)) != 0
+}
+
+// charsetSubtractCountPages is the same as `charsetSubtractCount`,
+// using the pages of `b` to skip the missing pages
+func charsetSubtractCountPages(a, b Charset, bPages *charsetPages) uint32 {
+	var count int
+	for i, page := range a.pageNumbers {
+		am := a.pages[i]
+		var bm charPage
+		if bPages.has(page) {
+			if j := b.findLeafForward(0, page); j >= 0 {
+				bm = b.pages[j]
+			}
+		}
+		for k := range am {
+			count += bits.OnesCount32(am[k] & ^bm[k])
+		}
+	}
+	return uint32(count)
+}
+
+// compareCharsetPages returns the score given by `fdFromPatternList`
+// for the CHARSET object, whose strong and weak priorities are the same.
+func compareCharsetPages(pattern, target valueList, targetPages []*charsetPages) float32 {
+	best := float32(math.MaxFloat32)
+	for j, v1 := range pattern {
+		for k, v2 := range target {
+			v := float32(charsetSubtractCountPages(v1.Value.(Charset), v2.Value.(Charset), targetPages[k]))
+			v = v*1000 + float32(j)
+			if v < best {
+				best = v
+			}
+			// found the best possible match
+			if best < 1000 {
+				return best
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,171 @@
+package fontconfig
+
+import (
+	"testing"
+)
+
+func matcherQueries(t testing.TB, c *Config) []Pattern {
+	var out []Pattern
+	for _, family := range []string{"serif", "sans-serif", "monospace", "Helvetica", "DejaVu Sans", "Noto Sans CJK JP", "unknown"} {
+		for _, weight := range []float32{WEIGHT_REGULAR, WEIGHT_BOLD} {
+			for _, slant := range []int32{SLANT_ROMAN, SLANT_ITALIC} {
+				query := NewPattern()
+				query.AddString(FAMILY, family)
+				query.Add(WEIGHT, Float(weight), true)
+				query.Add(SLANT, Int(slant), true)
+				out = append(out, query)
+			}
+		}
+	}
+
+	// langs and charsets
+	for _, lang := range []string{"fr", "ja", "ar", "zh-tw"} {
+		query := NewPattern()
+		query.AddString(FAMILY, "sans-serif")
+		query.Add(LANG, NewLangset(lang), true)
+		out = append(out, query)
+	}
+	var cs Charset
+	for _, r := range "Hello, 世界! مرحبا" {
+		cs.AddChar(r)
+	}
+	query := NewPattern()
+	query.Add(CHARSET, cs, true)
+	query.AddFloat(SIZE, 22)
+	out = append(out, query)
+
+	for _, query := range out {
+		c.Substitute(query, nil, MatchQuery)
+		query.SubstituteDefault()
+	}
+	return out
+}
+
+func TestMatcher(t *testing.T) {
+	fs := cachedFS()
+
+	c := NewConfig()
+	if err := c.LoadFromDir("confs"); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMatcher(fs)
+	for _, query := range matcherQueries(t, c) {
+		exp, got := fs.Match(query, c), m.Match(query, c)
+		if exp.Hash() != got.Hash() {
+			t.Fatalf("for %s, expected\n%s\n, got\n%s", query, exp, got)
+		}
+
+		for _, trim := range []bool{true, false} {
+			exp, expCs := fs.Sort(query, trim)
+			got, gotCs := m.Sort(query, trim)
+			if len(exp) != len(got) {
+				t.Fatalf("for %s, expected %d fonts, got %d", query, len(exp), len(got))
+			}
+			for i := range exp {
+				if exp[i].Hash() != got[i].Hash() {
+					t.Fatalf("for %s, at %d: expected\n%s\n, got\n%s", query, i, exp[i], got[i])
+				}
+			}
+			if !charsetEqual(expCs, gotCs) {
+				t.Fatalf("for %s, different coverage", query)
+			}
+		}
+	}
+
+	// type mismatch
+	query := NewPattern()
+	query[WEIGHT] = &valueList{{Value: String("bold")}} // bypass the type check
+	if fs.Match(query, c) != nil || m.Match(query, c) != nil {
+		t.Fatal("expected failure on type mismatch")
+	}
+
+	if NewMatcher(nil).Match(NewPattern(), c) != nil {
+		t.Fatal("expected no match for empty fontset")
+	}
+}
+
+func TestMatcherSharedPriority(t *testing.T) {
+	for _, m := range fcMatchers {
+		if m.object == FAMILY || m.compare == nil {
+			continue
+		}
+		for _, pri := range [...]matcherPriority{m.strong, m.weak} {
+			if pri == priFAMILY_STRONG || pri == priFAMILY_WEAK {
+				t.Fatalf("family priority %d used by %s", pri, m.object)
+			}
+		}
+	}
+
+	// make OUTLINE and ANTIALIAS share their priority
+	saved := fcMatchers[OUTLINE]
+	defer func() { fcMatchers[OUTLINE] = saved }()
+	fcMatchers[OUTLINE].strong, fcMatchers[OUTLINE].weak = priANTIALIAS_STRONG, priANTIALIAS_WEAK
+
+	var fs Fontset
+	for _, antialias := range []Bool{False, True} {
+		for _, outline := range []Bool{False, True} {
+			font := NewPattern()
+			font.Add(ANTIALIAS, antialias, true)
+			font.Add(OUTLINE, outline, true)
+			fs = append(fs, font)
+		}
+	}
+	m := NewMatcher(fs)
+	c := NewConfig()
+	for _, antialias := range []Bool{False, True} {
+		for _, outline := range []Bool{False, True} {
+			query := NewPattern()
+			query.Add(ANTIALIAS, antialias, true)
+			query.Add(OUTLINE, outline, true)
+			exp, got := fs.Match(query, c), m.Match(query, c)
+			if exp.Hash() != got.Hash() {
+				t.Fatalf("for %s, expected\n%s\n, got\n%s", query, exp, got)
+			}
+		}
+	}
+}
+
+func TestCharsetSubtractCountPages(t *testing.T) {
+	var a, b Charset
+	for r := rune(0); r < 0x3000; r += 7 {
+		a.AddChar(r)
+	}
+	for r := rune(0); r < 0x2000; r += 3 {
+		b.AddChar(r)
+	}
+	b.AddChar(0x10FFFF)
+	pages := newCharsetPages(valueList{{Value: b}})[0]
+	if exp, got := charsetSubtractCount(a, b), charsetSubtractCountPages(a, b, pages); exp != got {
+		t.Fatalf("expected %d, got %d", exp, got)
+	}
+	if exp, got := charsetSubtractCount(b, a), charsetSubtractCountPages(b, a, newCharsetPages(valueList{{Value: a}})[0]); exp != got {
+		t.Fatalf("expected %d, got %d", exp, got)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	fs := cachedFS()
+	c := NewConfig()
+	if err := c.LoadFromDir("confs"); err != nil {
+		b.Fatal(err)
+	}
+	queries := matcherQueries(b, c)
+
+	b.Run("fontset", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, query := range queries {
+				fs.Match(query, c)
+			}
+		}
+	})
+	b.Run("matcher", func(b *testing.B) {
+		m := NewMatcher(fs)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, query := range queries {
+				m.Match(query, c)
+			}
+		}
+	})
+}
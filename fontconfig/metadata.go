@@ -0,0 +1,125 @@
+package fontconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+// Embedding licensing flags, stored in the FS_TYPE object.
+// See the fsType field of the OpenType OS/2 table for more details.
+const (
+	FS_TYPE_INSTALLABLE   = 0x0000 // the font may be embedded, and permanently installed
+	FS_TYPE_RESTRICTED    = 0x0002 // the font must not be embedded
+	FS_TYPE_PREVIEW_PRINT = 0x0004 // the font may be embedded in read only documents
+	FS_TYPE_EDITABLE      = 0x0008 // the font may be embedded in editable documents
+	FS_TYPE_NO_SUBSETTING = 0x0100 // the font must not be subsetted before embedding
+	FS_TYPE_BITMAP_ONLY   = 0x0200 // only the bitmaps of the font may be embedded
+)
+
+// Embeddable returns false if the licensing rights of the font, as stored
+// in its FS_TYPE object, forbid to embed its outlines in a document, like a PDF file.
+// As recommended by the OpenType specification, the least restrictive
+// of the usage permissions is used when several are set.
+// Fonts without FS_TYPE (like Type1 or bitmap fonts) are considered embeddable.
+func (p Pattern) Embeddable() bool {
+	fsType, ok := p.GetInt(FS_TYPE)
+	if !ok {
+		return true
+	}
+	if fsType&FS_TYPE_BITMAP_ONLY != 0 {
+		return false
+	}
+	if fsType&(FS_TYPE_PREVIEW_PRINT|FS_TYPE_EDITABLE) != 0 {
+		return true
+	}
+	return fsType&FS_TYPE_RESTRICTED == 0
+}
+
+// queryMetadata adds the objects describing the font, which are
+// not used when matching, but useful for font management:
+// OS/2 vendor, classification and licensing, some name table entries
+// and the OpenType layout tags.
+func queryMetadata(pat Pattern, face *truetype.Font) {
+	if os2 := face.OS2; os2 != nil && os2.Version != 0xffff {
+		if os2.AchVendID != 0 {
+			if vendor := strings.TrimSpace(os2.AchVendID.String()); vendor != "" {
+				pat.AddString(VENDOR_ID, vendor)
+			}
+		}
+
+		if os2.Panose != [10]byte{} {
+			digits := make([]string, len(os2.Panose))
+			for i, b := range os2.Panose {
+				digits[i] = fmt.Sprint(b)
+			}
+			pat.AddString(PANOSE, strings.Join(digits, " "))
+		}
+
+		for i, bits := range os2.UlCharRange {
+			addBits(pat, UNICODE_RANGE, 32*i, bits)
+		}
+
+		if os2.Version >= 0x0001 {
+			addBits(pat, CODEPAGE_RANGE, 0, os2.UlCodePageRange1)
+			addBits(pat, CODEPAGE_RANGE, 32, os2.UlCodePageRange2)
+		}
+
+		pat.AddInt(FS_TYPE, int32(os2.FSType))
+	}
+
+	for _, name := range [...]struct {
+		id     truetype.NameID
+		object Object
+	}{
+		{truetype.NameDesigner, DESIGNER},
+		{truetype.NameLicenseURL, LICENSE_URL},
+		{truetype.NameDescription, DESCRIPTION},
+	} {
+		if entry := face.Names.SelectEntry(name.id); entry != nil {
+			if value := strings.TrimSpace(nameTranscode(*entry)); value != "" {
+				pat.AddString(name.object, value)
+			}
+		}
+	}
+
+	layout := face.LayoutTables()
+	var scripts, features []truetype.Tag
+	for _, table := range [...]truetype.TableLayout{layout.GSUB.TableLayout, layout.GPOS.TableLayout} {
+		for _, script := range table.Scripts {
+			scripts = append(scripts, script.Tag)
+		}
+		for _, feature := range table.Features {
+			features = append(features, feature.Tag)
+		}
+	}
+	addTags(pat, OT_SCRIPT, scripts)
+	addTags(pat, OT_FEATURE, features)
+}
+
+// addBits adds the index of the bits set in `bits`, starting at `offset`
+func addBits(pat Pattern, object Object, offset int, bits uint32) {
+	for i := 0; i < 32; i++ {
+		if bits&(1<<i) != 0 {
+			pat.AddInt(object, int32(offset+i))
+		}
+	}
+}
+
+// addTags adds the sorted, unique and valid `tags`
+func addTags(pat Pattern, object Object, tags []truetype.Tag) {
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	for i, tag := range tags {
+		if i > 0 && tags[i-1] == tag {
+			continue
+		}
+		s := tag.String()
+		// skip broken tags, as fontCapabilities does
+		if !isValidScript(s[0]) || !isValidScript(s[1]) || !isValidScript(s[2]) || !isValidScript(s[3]) {
+			continue
+		}
+		pat.AddString(object, s)
+	}
+}
@@ -0,0 +1,152 @@
+package fontconfig
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func scanMetadataFonts(t *testing.T) Fontset {
+	var out Fontset
+	for _, file := range []string{
+		"test/DejaVuSerif-Italic.ttf",
+		"../pango/test/weasyprint.otf",
+		"test/fontawesome-webfont.woff2",
+	} {
+		fs, err := NewConfig().ScanFontFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, fs...)
+	}
+	return out
+}
+
+func TestScanMetadata(t *testing.T) {
+	fs := scanMetadataFonts(t)
+	dejavu, awesome := fs[0], fs[2]
+
+	if s, _ := dejavu.GetString(VENDOR_ID); s != "PfEd" {
+		t.Fatalf("unexpected vendor %s", s)
+	}
+	if s, _ := dejavu.GetString(PANOSE); s != "2 6 6 3 5 3 5 11 2 4" {
+		t.Fatalf("unexpected panose %s", s)
+	}
+	if v, ok := dejavu.GetInt(FS_TYPE); !ok || v != FS_TYPE_INSTALLABLE {
+		t.Fatalf("unexpected fsType %d", v)
+	}
+	// Basic Latin and Latin-1
+	if ranges := dejavu.GetInts(UNICODE_RANGE); len(ranges) < 2 || ranges[0] != 0 || ranges[1] != 1 {
+		t.Fatalf("unexpected unicode ranges %v", ranges)
+	}
+	// Latin 1
+	if ranges := dejavu.GetInts(CODEPAGE_RANGE); len(ranges) == 0 || ranges[0] != 0 {
+		t.Fatalf("unexpected codepage ranges %v", ranges)
+	}
+	if scripts := dejavu.GetStrings(OT_SCRIPT); !reflect.DeepEqual(scripts, []string{"DFLT", "cyrl", "grek", "latn"}) {
+		t.Fatalf("unexpected scripts %v", scripts)
+	}
+	if features := dejavu.GetStrings(OT_FEATURE); len(features) != 10 || features[0] != "aalt" {
+		t.Fatalf("unexpected features %v", features)
+	}
+
+	if s, _ := awesome.GetString(DESIGNER); s != "Dave Gandy" {
+		t.Fatalf("unexpected designer %s", s)
+	}
+	if features := awesome.GetStrings(OT_FEATURE); len(features) != 0 {
+		t.Fatalf("unexpected features %v", features)
+	}
+}
+
+func TestListMetadata(t *testing.T) {
+	fs := scanMetadataFonts(t)
+
+	query := BuildPattern(PatternElement{Object: OT_FEATURE, Value: String("smcp")})
+	list := fs.List(query, FAMILY)
+	if len(list) != 1 {
+		t.Fatalf("unexpected list %v", list)
+	}
+	if family, _ := list[0].GetString(FAMILY); family != "Ahem" {
+		t.Fatalf("unexpected family %s", family)
+	}
+
+	query = BuildPattern(PatternElement{Object: UNICODE_RANGE, Value: Int(9)}) // Cyrillic
+	if list := fs.List(query, FILE); len(list) != 1 {
+		t.Fatalf("unexpected list %v", list)
+	}
+
+	query, err := ParseName(":fstype=0:vendorid=W3C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := fs.List(query, FAMILY, DESIGNER); len(list) != 1 {
+		t.Fatalf("unexpected list %v", list)
+	}
+}
+
+func TestCacheMetadata(t *testing.T) {
+	fs := scanMetadataFonts(t)
+
+	var binary, js, gob bytes.Buffer
+	if err := fs.Serialize(&binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SerializeJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SerializeGOB(&gob); err != nil {
+		t.Fatal(err)
+	}
+
+	fromBinary, err := LoadFontset(&binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := LoadFontsetJSON(&js)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromGOB, err := LoadFontsetGOB(&gob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, back := range [...]Fontset{fromBinary, fromJSON, fromGOB} {
+		if len(back) != len(fs) {
+			t.Fatalf("unexpected length %d", len(back))
+		}
+		for i := range fs {
+			if back[i].Hash() != fs[i].Hash() {
+				t.Fatalf("metadata not preserved: %s", back[i])
+			}
+			if got, exp := back[i].GetStrings(OT_FEATURE), fs[i].GetStrings(OT_FEATURE); !reflect.DeepEqual(got, exp) {
+				t.Fatalf("expected %v, got %v", exp, got)
+			}
+		}
+	}
+}
+
+func TestEmbeddable(t *testing.T) {
+	for _, test := range []struct {
+		fsType     int32
+		embeddable bool
+	}{
+		{FS_TYPE_INSTALLABLE, true},
+		{FS_TYPE_RESTRICTED, false},
+		{FS_TYPE_PREVIEW_PRINT, true},
+		{FS_TYPE_EDITABLE | FS_TYPE_NO_SUBSETTING, true},
+		{FS_TYPE_RESTRICTED | FS_TYPE_PREVIEW_PRINT, true}, // least restrictive
+		{FS_TYPE_EDITABLE | FS_TYPE_BITMAP_ONLY, false},
+	} {
+		p := NewPattern()
+		p.AddInt(FS_TYPE, test.fsType)
+		if got := p.Embeddable(); got != test.embeddable {
+			t.Fatalf("for fsType %x, expected %v, got %v", test.fsType, test.embeddable, got)
+		}
+	}
+
+	// non SFNT fonts
+	if !NewPattern().Embeddable() {
+		t.Fatal("expected embeddable font")
+	}
+}
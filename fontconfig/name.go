@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 )
 
@@ -27,7 +28,7 @@ var objects = map[string]objectType{
 	objectNames[STYLELANG]:       {object: STYLELANG, typeInfo: typeString{}},       // String
 	objectNames[FULLNAME]:        {object: FULLNAME, typeInfo: typeString{}},        // String
 	objectNames[FULLNAMELANG]:    {object: FULLNAMELANG, typeInfo: typeString{}},    // String
-	objectNames[SLANT]:           {object: SLANT, typeInfo: typeInt{}},              // Int
+	objectNames[SLANT]:           {object: SLANT, typeInfo: typeSlant{}},            // Int or Range
 	objectNames[WEIGHT]:          {object: WEIGHT, typeInfo: typeRange{}},           // Range
 	objectNames[WIDTH]:           {object: WIDTH, typeInfo: typeRange{}},            // Range
 	objectNames[SIZE]:            {object: SIZE, typeInfo: typeRange{}},             // Range
@@ -73,6 +74,18 @@ var objects = map[string]objectType{
 	objectNames[VARIABLE]:        {object: VARIABLE, typeInfo: typeBool{}},          // Bool
 	objectNames[FONT_HAS_HINT]:   {object: FONT_HAS_HINT, typeInfo: typeBool{}},     // Bool
 	objectNames[ORDER]:           {object: ORDER, typeInfo: typeInt{}},              // Int
+	objectNames[VENDOR_ID]:       {object: VENDOR_ID, typeInfo: typeString{}},       // String
+	objectNames[PANOSE]:          {object: PANOSE, typeInfo: typeString{}},          // String
+	objectNames[UNICODE_RANGE]:   {object: UNICODE_RANGE, typeInfo: typeInt{}},      // Int
+	objectNames[CODEPAGE_RANGE]:  {object: CODEPAGE_RANGE, typeInfo: typeInt{}},     // Int
+	objectNames[FS_TYPE]:         {object: FS_TYPE, typeInfo: typeInt{}},            // Int
+	objectNames[DESIGNER]:        {object: DESIGNER, typeInfo: typeString{}},        // String
+	objectNames[LICENSE_URL]:     {object: LICENSE_URL, typeInfo: typeString{}},     // String
+	objectNames[DESCRIPTION]:     {object: DESCRIPTION, typeInfo: typeString{}},     // String
+	objectNames[OT_SCRIPT]:       {object: OT_SCRIPT, typeInfo: typeString{}},       // String
+	objectNames[OT_FEATURE]:      {object: OT_FEATURE, typeInfo: typeString{}},      // String
+	objectNames[SLANT_AXIS]:      {object: SLANT_AXIS, typeInfo: typeRange{}},       // Range
+	objectNames[FONT_WRAPPER]:    {object: FONT_WRAPPER, typeInfo: typeString{}},    // String
 }
 
 var objectNames = [...]string{
@@ -129,8 +142,22 @@ var objectNames = [...]string{
 	VARIABLE:        "variable",
 	FONT_HAS_HINT:   "fonthashint",
 	ORDER:           "order",
+	VENDOR_ID:       "vendorid",
+	PANOSE:          "panose",
+	UNICODE_RANGE:   "unicoderange",
+	CODEPAGE_RANGE:  "codepagerange",
+	FS_TYPE:         "fstype",
+	DESIGNER:        "designer",
+	LICENSE_URL:     "licenseurl",
+	DESCRIPTION:     "description",
+	OT_SCRIPT:       "otscript",
+	OT_FEATURE:      "otfeature",
+	SLANT_AXIS:      "slantaxis",
+	FONT_WRAPPER:    "fontwrapper",
 }
 
+// String returns the name of builtin objects.
+// See `Config.ObjectName` for custom objects.
 func (object Object) String() string {
 	if int(object) < len(objectNames) { // common case for buitlin objects
 		return objectNames[object]
@@ -154,23 +181,13 @@ func (object Object) String() string {
 // the + 20 is to leave some room for future added internal objects
 const nextId = FirstCustomObject + 20
 
-func (c *Config) lookupCustomObject(object string) objectType {
-	if o, ok := c.customObjects[object]; ok {
-		return objectType{object: o} // parser is nil for unknown type
+func (c *Config) lookupCustomObject(name string) objectType {
+	object, ok := c.customObjects[name]
+	if !ok {
+		object = customObject(name)
+		c.customObjects[name] = object
 	}
-
-	// we add new objects
-	id := nextId
-	for _, o := range c.customObjects {
-		if o > id {
-			id = o
-		}
-	}
-	if id+1 == 0 {
-		panic("implementation limit for the number of custom objects reached")
-	}
-	c.customObjects[object] = id + 1
-	return objectType{object: id + 1}
+	return objectType{object: object, typeInfo: c.customTypes[object]} // typeInfo is nil for unknown type
 }
 
 // Return the object type for the pattern element named object
@@ -271,6 +288,30 @@ func nameConstant(str String) (int, bool) {
 	return 0, false
 }
 
+// nameConfig records the custom objects found by the package level `ParseName`
+var nameConfig = struct {
+	sync.Mutex
+	config *Config
+}{config: NewConfig()}
+
+// ParseName converts `name` from the standard fontconfig text format
+// into a pattern, for instance 'Foo,Bar-12:weight=bold:slant=italic'.
+// Objects which are not builtin are stored as strings.
+// See `Pattern.Unparse` for the inverse operation.
+func ParseName(name string) (Pattern, error) {
+	nameConfig.Lock()
+	defer nameConfig.Unlock()
+	return nameConfig.config.parseName([]byte(name))
+}
+
+// ParseName is the same as the package level `ParseName`, but the values of the custom
+// objects registered in `config` are parsed according to their type.
+// The other objects which are not builtin are added to the custom objects of `config`.
+// See `Config.Unparse` for the inverse operation.
+func (config *Config) ParseName(name string) (Pattern, error) {
+	return config.parseName([]byte(name))
+}
+
 // parseName converts `name` from the standard text format Described above into a pattern.
 func (c *Config) parseName(name []byte) (Pattern, error) {
 	var (
@@ -305,6 +346,9 @@ func (c *Config) parseName(name []byte) (Pattern, error) {
 		if len(save) != 0 {
 			if delim == '=' || delim == '_' {
 				t := c.getRegisterObjectType(save)
+				if t.typeInfo == nil { // custom object
+					t.typeInfo = typeString{}
+				}
 				for {
 					delim, name, save = nameFindNext(name, ":,")
 					v, err := t.typeInfo.parse(save, t.object)
@@ -317,11 +361,11 @@ func (c *Config) parseName(name []byte) (Pattern, error) {
 					}
 				}
 			} else {
-				if co := nameGetConstant(save); c != nil {
+				if co := nameGetConstant(save); co != nil {
 					t := c.getRegisterObjectType(objectNames[co.object])
 
 					switch t.typeInfo.(type) {
-					case typeInt, typeFloat, typeRange:
+					case typeInt, typeSlant, typeFloat, typeRange:
 						pat.Add(co.object, Int(co.value), true)
 					case typeBool:
 						pat.Add(co.object, Bool(co.value), true)
@@ -334,6 +378,154 @@ func (c *Config) parseName(name []byte) (Pattern, error) {
 	return pat, nil
 }
 
+const (
+	escapeFixed    = "\\-:,"
+	escapeVariable = "\\=_:,"
+)
+
+// Unparse returns the canonical fontconfig name of the pattern,
+// which may be parsed back with `ParseName`.
+// The family names and the size come first, and
+// the other objects follow, as 'object=value1,value2'.
+// Custom objects are written with their name, whatever the configuration
+// which defined them.
+func (p Pattern) Unparse() string {
+	return p.unparse(nil)
+}
+
+// Unparse is the same as `Pattern.Unparse`, but only writes the
+// custom objects known by `config` (see `Config.ObjectName`).
+func (config *Config) Unparse(p Pattern) string {
+	return p.unparse(config)
+}
+
+// unparse writes all the custom objects if `config` is nil
+func (p Pattern) unparse(config *Config) string {
+	var buf strings.Builder
+
+	for i, v := range p.getVals(FAMILY) {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		unparseValue(&buf, v.Value, FAMILY, escapeFixed)
+	}
+
+	sizes := p.getVals(SIZE)
+	sizeInFamily := true
+	for _, v := range sizes {
+		switch v.Value.(type) {
+		case Int, Float:
+		default:
+			sizeInFamily = false
+		}
+	}
+	if sizeInFamily && len(sizes) != 0 {
+		buf.WriteByte('-')
+		for i, v := range sizes {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			unparseValue(&buf, v.Value, SIZE, escapeFixed)
+		}
+	}
+
+	for _, object := range p.sortedKeys() {
+		if object == FAMILY || (object == SIZE && sizeInFamily) {
+			continue
+		}
+		var (
+			name string
+			ok   bool
+		)
+		if config == nil {
+			name, ok = objectName(object)
+		} else {
+			name, ok = config.ObjectName(object)
+		}
+		if !ok {
+			continue
+		}
+		vals := p.getVals(object)
+		if len(vals) == 0 {
+			continue
+		}
+		buf.WriteByte(':')
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		for i, v := range vals {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			unparseValue(&buf, v.Value, object, escapeVariable)
+		}
+	}
+	return buf.String()
+}
+
+// return the name of the constant matching the value, or an empty string
+func nameUnparseConstant(object Object, value float64) string {
+	for _, c := range baseConstants {
+		if c.object == object && float64(c.value) == value {
+			return c.name
+		}
+	}
+	return ""
+}
+
+func unparseString(buf *strings.Builder, str, escape string) {
+	for i := 0; i < len(str); i++ {
+		if strings.IndexByte(escape, str[i]) != -1 {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(str[i])
+	}
+}
+
+func formatFloat(f float32) string { return strconv.FormatFloat(float64(f), 'g', -1, 32) }
+
+// object is only used to lookup constants: use `invalid`
+// to always write numbers
+func unparseValue(buf *strings.Builder, value Value, object Object, escape string) {
+	switch value := value.(type) {
+	case Int:
+		if c := nameUnparseConstant(object, float64(value)); c != "" {
+			buf.WriteString(c)
+		} else {
+			buf.WriteString(strconv.Itoa(int(value)))
+		}
+	case Float:
+		if c := nameUnparseConstant(object, float64(value)); c != "" {
+			buf.WriteString(c)
+		} else {
+			buf.WriteString(formatFloat(float32(value)))
+		}
+	case String:
+		unparseString(buf, string(value), escape)
+	case Bool:
+		switch value {
+		case False:
+			buf.WriteString("False")
+		case True:
+			buf.WriteString("True")
+		default:
+			buf.WriteString("DontCare")
+		}
+	case Matrix:
+		fmt.Fprintf(buf, "%s %s %s %s", formatFloat(value.Xx), formatFloat(value.Xy), formatFloat(value.Yx), formatFloat(value.Yy))
+	case Range:
+		fmt.Fprintf(buf, "[%s %s]", formatFloat(value.Begin), formatFloat(value.End))
+	case Charset:
+		buf.WriteString(value.Text())
+	case Langset:
+		for i, l := range value.Langs() {
+			if i != 0 {
+				buf.WriteByte('|')
+			}
+			unparseString(buf, l, escape)
+		}
+	}
+}
+
 func nameFindNext(cur []byte, delim string) (byte, []byte, string) {
 	cur = bytes.TrimLeftFunc(cur, unicode.IsSpace)
 	i := 0
@@ -408,6 +600,16 @@ func (typeInt) parse(str string, object Object) (Value, error) {
 	return Int(v), err
 }
 
+// typeSlant is an Int, or a Range for variable fonts
+type typeSlant struct{}
+
+func (typeSlant) parse(str string, object Object) (Value, error) {
+	if strings.HasPrefix(str, "[") {
+		return typeRange{}.parse(str, object)
+	}
+	return typeInt{}.parse(str, object)
+}
+
 type typeString struct{}
 
 func (typeString) parse(str string, object Object) (Value, error) { return String(str), nil }
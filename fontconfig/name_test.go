@@ -2,6 +2,7 @@ package fontconfig
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -109,3 +110,162 @@ func TestParseName(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestUnparse(t *testing.T) {
+	var cs Charset
+	for r := rune(0x20); r <= 0x7e; r++ {
+		cs.AddChar(r)
+	}
+	cs.AddChar(0x1F600)
+
+	p := NewPattern()
+	p.Add(FAMILY, String("Foo-Bar"), true)
+	p.Add(FAMILY, String("Baz:Qux"), true)
+	p.Add(SIZE, Float(12.5), true)
+	p.Add(WEIGHT, Int(WEIGHT_BOLD), true)
+	p.Add(SLANT, Int(SLANT_ITALIC), true)
+	p.Add(WIDTH, Range{Begin: 75, End: 100}, true)
+	p.Add(ANTIALIAS, DontCare, true)
+	p.Add(STYLE, String("Bold_Italic=1,2"), true)
+	p.Add(MATRIX, Matrix{1, 0.2, -0.5, 1}, true)
+	p.Add(CHARSET, cs, true)
+	p.Add(LANG, NewLangset("fr|en|xx-yy"), true)
+	p.Add(FILE, String("/usr/share/fonts/a.ttf"), true)
+	p.Add(ORDER, Int(3), true)
+
+	name := p.Unparse()
+	expected := `Foo\-Bar,Baz\:Qux-12.5:style=Bold\_Italic\=1\,2:slant=italic:weight=bold:width=[75 100]:antialias=DontCare:file=/usr/share/fonts/a.ttf:matrix=1 0.2 -0.5 1:charset=20-7e 1f600:lang=en|fr|xx-yy:order=3`
+	if name != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, name)
+	}
+
+	back, err := ParseName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Hash() != p.Hash() {
+		t.Fatalf("round trip failed: expected %s, got %s", p, back)
+	}
+	if back.Unparse() != name {
+		t.Fatalf("unstable name %s", back.Unparse())
+	}
+}
+
+func TestUnparseVariable(t *testing.T) {
+	// as scanned for a variable font
+	p := NewPattern()
+	p.Add(FAMILY, String("Roboto Flex"), true)
+	p.Add(SLANT, Range{Begin: SLANT_ROMAN, End: SLANT_OBLIQUE}, true)
+	p.Add(WEIGHT, Range{Begin: WEIGHT_THIN, End: WEIGHT_BLACK}, true)
+	p.Add(VARIABLE, True, true)
+
+	name := p.Unparse()
+	back, err := ParseName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Hash() != p.Hash() {
+		t.Fatalf("round trip failed: expected %s, got %s", p, back)
+	}
+	if r, _ := back.GetAt(SLANT, 0); r != (Range{Begin: SLANT_ROMAN, End: SLANT_OBLIQUE}) {
+		t.Fatalf("unexpected slant %v", r)
+	}
+
+	// scalar slants are still Int values
+	for _, name := range []string{":slant=italic", ":slant=110"} {
+		p, err := ParseName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p.GetInt(SLANT); !ok {
+			t.Fatalf("expected Int slant for %s, got %s", name, p)
+		}
+	}
+
+	config := NewConfig()
+	err = config.LoadFromMemory(strings.NewReader(`<fontconfig><match>
+		<test name="slant" compare="eq"><const>italic</const></test>
+		<edit name="slant"><range><int>0</int><int>100</int></range></edit>
+	</match></fontconfig>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.LoadFromMemory(strings.NewReader(`<fontconfig><match>
+		<edit name="slant"><string>italic</string></edit>
+	</match></fontconfig>`))
+	if err == nil {
+		t.Fatal("expected type error for slant")
+	}
+}
+
+func TestParseNameRoundTrip(t *testing.T) {
+	for _, name := range []string{
+		"",
+		"sans\\-serif",
+		"Foo,Bar-10,12",
+		"Foo:weight=medium",
+		":size=[10 12]",
+		":weight=[0.45 48.88]",
+		":antialias=False:autohint=True:scalable=DontCare",
+		":pixelsize=45.78:foundry=5456s4d:order=7845",
+		":charset=41-5a 61-7a e9",
+	} {
+		p, err := ParseName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p2, err := ParseName(p.Unparse())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Hash() != p2.Hash() {
+			t.Errorf("round trip failed for %s: got %s", name, p.Unparse())
+		}
+	}
+}
+
+func TestParseNameCustom(t *testing.T) {
+	config := NewConfig()
+	p, err := config.ParseName("Foo:mycustom=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 2 {
+		t.Fatalf("unexpected pattern %s", p)
+	}
+	// custom objects are unparsed with their name
+	if s := config.Unparse(p); s != "Foo:mycustom=value" {
+		t.Fatalf("unexpected name %s", s)
+	}
+}
+
+func TestUnparseCustom(t *testing.T) {
+	p, err := ParseName("Foo:mycustom=value:other=a\\:b,c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := p.Unparse()
+	if name != "Foo:mycustom=value:other=a\\:b,c" {
+		t.Fatalf("unexpected name %s", name)
+	}
+	back, err := ParseName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Hash() != p.Hash() {
+		t.Fatalf("round trip failed: expected %s, got %s", p, back)
+	}
+
+	// custom objects defined by another configuration are also kept
+	config := NewConfig()
+	tier, err := config.RegisterObject("tier", KindInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = NewPattern()
+	p.AddString(FAMILY, "Foo")
+	p.AddInt(tier, 2)
+	if s := p.Unparse(); s != "Foo:tier=2" {
+		t.Fatalf("unexpected name %s", s)
+	}
+}
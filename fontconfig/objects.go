@@ -0,0 +1,247 @@
+package fontconfig
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ValueKind is the type of the values of a pattern object.
+type ValueKind uint8
+
+const (
+	KindString  ValueKind = iota // String
+	KindInt                      // Int
+	KindFloat                    // Float (Int values are also accepted)
+	KindBool                     // Bool
+	KindRange                    // Range (Int and Float values are also accepted)
+	KindMatrix                   // Matrix
+	KindCharset                  // Charset
+	KindLangset                  // Langset (String values are also accepted)
+)
+
+var valueKindTypes = [...]typeMeta{
+	KindString:  typeString{},
+	KindInt:     typeInt{},
+	KindFloat:   typeFloat{},
+	KindBool:    typeBool{},
+	KindRange:   typeRange{},
+	KindMatrix:  typeMatrix{},
+	KindCharset: typeCharSet{},
+	KindLangset: typeLangSet{},
+}
+
+func (kind ValueKind) String() string {
+	switch kind {
+	case KindString:
+		return "string"
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float"
+	case KindBool:
+		return "bool"
+	case KindRange:
+		return "range"
+	case KindMatrix:
+		return "matrix"
+	case KindCharset:
+		return "charset"
+	case KindLangset:
+		return "langset"
+	default:
+		return fmt.Sprintf("<kind %d>", kind)
+	}
+}
+
+// typeAccepts returns true if `value` may be stored
+// in an object with type `type_`
+func typeAccepts(type_ typeMeta, value Value) bool {
+	switch value.(type) {
+	case Int:
+		switch type_.(type) {
+		case typeInt, typeFloat, typeRange:
+			return true
+		}
+	case Float:
+		switch type_.(type) {
+		case typeFloat, typeRange:
+			return true
+		}
+	case String:
+		switch type_.(type) {
+		case typeString, typeLangSet:
+			return true
+		}
+	case Bool:
+		return type_ == typeBool{}
+	case Range:
+		return type_ == typeRange{}
+	case Matrix:
+		return type_ == typeMatrix{}
+	case Charset:
+		return type_ == typeCharSet{}
+	case Langset:
+		return type_ == typeLangSet{}
+	}
+	return false
+}
+
+// customIDs allocates the custom objects, shared by all the configurations,
+// so that a name is always associated to the same object, as in the C library,
+// and that patterns (and their caches) may be used with any configuration.
+// The names and the types of the objects used by a configuration
+// are stored in the configuration.
+var customIDs = struct {
+	sync.RWMutex
+	byName map[string]Object
+	names  map[Object]string
+	last   Object // the last allocated object
+}{
+	byName: make(map[string]Object),
+	names:  make(map[Object]string),
+	last:   nextId,
+}
+
+func init() {
+	// the objects used in the standard configuration are fixed
+	for name, object := range Standard.customObjects {
+		addCustomObject(name, object)
+	}
+}
+
+// addCustomObject must be called with the lock held, or during init
+func addCustomObject(name string, object Object) {
+	customIDs.byName[name] = object
+	customIDs.names[object] = name
+	if object > customIDs.last {
+		customIDs.last = object
+	}
+}
+
+// customObject returns the object for `name`,
+// allocating a new one if needed
+func customObject(name string) Object {
+	customIDs.RLock()
+	object, ok := customIDs.byName[name]
+	customIDs.RUnlock()
+	if ok {
+		return object
+	}
+
+	customIDs.Lock()
+	defer customIDs.Unlock()
+	if object, ok := customIDs.byName[name]; ok { // allocated in the meantime
+		return object
+	}
+	if customIDs.last+1 == 0 {
+		panic("implementation limit for the number of custom objects reached")
+	}
+	addCustomObject(name, customIDs.last+1)
+	return customIDs.last
+}
+
+// customObjectName returns the name used to allocate a custom object
+func customObjectName(object Object) (string, bool) {
+	customIDs.RLock()
+	defer customIDs.RUnlock()
+	name, ok := customIDs.names[object]
+	return name, ok
+}
+
+// objectName returns the name of a builtin object, or of a custom
+// object, whatever the configuration using it
+func objectName(object Object) (string, bool) {
+	if int(object) < len(objectNames) {
+		return objectNames[object], object != invalid
+	}
+	return customObjectName(object)
+}
+
+// lookupObject returns the builtin object called `name`, or the
+// custom object with this name, whatever the configuration using it
+func lookupObject(name string) (Object, bool) {
+	if builtin, ok := objects[name]; ok {
+		return builtin.object, true
+	}
+	customIDs.RLock()
+	defer customIDs.RUnlock()
+	object, ok := customIDs.byName[name]
+	return object, ok
+}
+
+// objectType returns the type of builtin objects, or of the custom objects
+// registered in `config` with `RegisterObject`, or nil.
+func (config *Config) objectType(object Object) typeMeta {
+	if int(object) < len(objectNames) {
+		return objects[objectNames[object]].typeInfo
+	}
+	return config.customTypes[object]
+}
+
+// hasValidType also checks the registered custom objects
+func (config *Config) hasValidType(object Object, value Value) bool {
+	if type_ := config.customTypes[object]; type_ != nil {
+		return typeAccepts(type_, value)
+	}
+	return object.hasValidType(value)
+}
+
+// RegisterObject registers a custom object called `name`, whose values have type `kind`,
+// and returns it.
+// The object may then be used in patterns (see also `Config.LookupObject`) and in the substitution
+// rules. Its values are type checked when parsing names with `Config.ParseName`,
+// and when loading XML configurations or adding rules in `config`. They are also
+// preserved by the font set caches.
+// The types are specific to `config`, but registering `name` in several
+// configurations returns the same object, so that the patterns may be shared.
+// An error is returned if `name` is a builtin object, or if it has already been
+// registered in `config` with another kind.
+func (config *Config) RegisterObject(name string, kind ValueKind) (Object, error) {
+	if int(kind) >= len(valueKindTypes) {
+		return invalid, fmt.Errorf("fontconfig: invalid value kind %d for object %s", kind, name)
+	}
+	if name == "" {
+		return invalid, fmt.Errorf("fontconfig: empty object name")
+	}
+	if _, ok := objects[name]; ok {
+		return invalid, fmt.Errorf("fontconfig: %s is a builtin object", name)
+	}
+
+	object := config.getRegisterObjectType(name).object
+	if type_ := config.customTypes[object]; type_ != nil && type_ != valueKindTypes[kind] {
+		return invalid, fmt.Errorf("fontconfig: object %s is already registered with another type", name)
+	}
+	if config.customTypes == nil {
+		config.customTypes = make(map[Object]typeMeta)
+	}
+	config.customTypes[object] = valueKindTypes[kind]
+	return object, nil
+}
+
+// LookupObject returns the builtin object called `name`,
+// or the custom object registered with `RegisterObject` or used in the
+// XML configurations loaded by `config`.
+// See `ObjectName` for the inverse operation.
+func (config *Config) LookupObject(name string) (Object, bool) {
+	if builtin, ok := objects[name]; ok {
+		return builtin.object, true
+	}
+	object, ok := config.customObjects[name]
+	return object, ok
+}
+
+// ObjectName returns the name of a builtin object,
+// or of a custom object registered with `RegisterObject` or used in the
+// XML configurations loaded by `config`.
+// Note that `Object.String` only knows the builtin objects.
+func (config *Config) ObjectName(object Object) (string, bool) {
+	if int(object) < len(objectNames) {
+		return objectNames[object], object != invalid
+	}
+	for name, custom := range config.customObjects {
+		if custom == object {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,161 @@
+package fontconfig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegisterObject(t *testing.T) {
+	config := Standard.Copy()
+
+	tier, err := config.RegisterObject("tier", KindInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tier < FirstCustomObject {
+		t.Fatalf("unexpected object %d", tier)
+	}
+	if name, ok := config.ObjectName(tier); !ok || name != "tier" {
+		t.Fatalf("unexpected name %s", name)
+	}
+	if name, ok := config.ObjectName(FAMILY); !ok || name != "family" {
+		t.Fatalf("unexpected name %s", name)
+	}
+	if obj, ok := config.LookupObject("tier"); !ok || obj != tier {
+		t.Fatalf("unexpected lookup %d %v", obj, ok)
+	}
+	if obj, ok := config.LookupObject("family"); !ok || obj != FAMILY {
+		t.Fatalf("unexpected lookup %d %v", obj, ok)
+	}
+	if _, ok := config.LookupObject("not-registered"); ok {
+		t.Fatal("expected unknown object")
+	}
+
+	// registering again is allowed, with the same kind
+	if again, err := config.RegisterObject("tier", KindInt); err != nil || again != tier {
+		t.Fatalf("unexpected registration %d %v", again, err)
+	}
+	if _, err := config.RegisterObject("tier", KindString); err == nil {
+		t.Fatal("expected error for conflicting kind")
+	}
+	// the types are specific to each configuration, not the objects
+	if other, err := Standard.Copy().RegisterObject("tier", KindString); err != nil || other != tier {
+		t.Fatalf("unexpected registration %d %v", other, err)
+	}
+	if _, err := config.RegisterObject("family", KindString); err == nil {
+		t.Fatal("expected error for builtin object")
+	}
+	if _, err := config.RegisterObject("", KindString); err == nil {
+		t.Fatal("expected error for empty name")
+	}
+	if _, err := config.RegisterObject("badkind", KindLangset+1); err == nil {
+		t.Fatal("expected error for invalid kind")
+	}
+
+	// values are type checked
+	if _, err := config.ParseName(":tier=high"); err == nil {
+		t.Fatal("expected invalid value to be rejected")
+	}
+	p, err := config.ParseName(":tier=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.GetInt(tier); v != 2 {
+		t.Fatalf("unexpected value %d", v)
+	}
+	if s := config.Unparse(p); s != ":tier=2" {
+		t.Fatalf("unexpected name %s", s)
+	}
+
+	// other configurations are not affected
+	if _, ok := Standard.LookupObject("tier"); ok {
+		t.Fatal("unexpected object in Standard")
+	}
+	if tier.String() == "tier" {
+		t.Fatal("unexpected global name")
+	}
+	if p, err := NewConfig().ParseName(":tier=high"); err != nil || p[tier] == nil {
+		t.Fatalf("unexpected pattern %v %v", p, err)
+	}
+}
+
+func TestRegisterObjectName(t *testing.T) {
+	config := Standard.Copy()
+	weightClass, err := config.RegisterObject("weightclass", KindFloat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := config.ParseName("Foo:weightclass=2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := p.GetFloat(weightClass); v != 2.5 {
+		t.Fatalf("unexpected value %v", p)
+	}
+	if s := config.Unparse(p); s != "Foo:weightclass=2.5" {
+		t.Fatalf("unexpected name %s", s)
+	}
+
+	if _, err = config.ParseName("Foo:weightclass=heavy"); err == nil {
+		t.Fatal("expected error for invalid value")
+	}
+}
+
+func TestRegisterObjectXML(t *testing.T) {
+	config := Standard.Copy()
+	if _, err := config.RegisterObject("priority", KindInt); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `<fontconfig>
+		<match target="pattern">
+			<test name="priority" compare="more"><int>1</int></test>
+			<edit name="priority" mode="assign"><int>3</int></edit>
+		</match>
+	</fontconfig>`
+	if err := config.LoadFromMemory(strings.NewReader(valid)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []string{
+		`<fontconfig><match><edit name="priority"><string>high</string></edit></match></fontconfig>`,
+		`<fontconfig><match><test name="priority"><bool>true</bool></test></match></fontconfig>`,
+	} {
+		if err := config.Copy().LoadFromMemory(strings.NewReader(invalid)); err == nil {
+			t.Fatalf("expected type error for %s", invalid)
+		}
+		// the type is only registered in config
+		if err := Standard.Copy().LoadFromMemory(strings.NewReader(invalid)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegisterObjectCache(t *testing.T) {
+	foundry, err := Standard.Copy().RegisterObject("vendorfoundry", KindString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPattern()
+	p.AddString(FAMILY, "Foo")
+	p.AddString(foundry, "Acme")
+	var buf bytes.Buffer
+	if err = (Fontset{p}).Serialize(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// the object is stored with its name
+	if !bytes.Contains(buf.Bytes(), []byte("vendorfoundry")) {
+		t.Fatal("missing object name in cache")
+	}
+
+	back, err := LoadFontset(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 1 || back[0].Hash() != p.Hash() {
+		t.Fatalf("unexpected fontset %v", back)
+	}
+}
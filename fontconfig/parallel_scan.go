@@ -0,0 +1,226 @@
+package fontconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// ScanProgress describes the state of a parallel scan.
+type ScanProgress struct {
+	Seen   int    // number of font files found so far
+	Parsed int    // number of font files parsed so far
+	Path   string // the file which has just been found or parsed
+}
+
+// ScanOptions configures the parallel scans.
+type ScanOptions struct {
+	// Workers is the number of files parsed concurrently.
+	// It defaults to runtime.NumCPU().
+	Workers int
+
+	// Progress, if not nil, is called each time a font file
+	// is found or parsed. The calls are serialized, but may
+	// happen on different goroutines.
+	Progress func(ScanProgress)
+}
+
+// ScanFontDirectoriesParallel is the same as `ScanFontDirectories`, but
+// parses the font files with a pool of workers. The returned fontset is the same,
+// in the same order.
+// The scan is stopped when `ctx` is done, in which case `ctx.Err()` is returned.
+func (config *Config) ScanFontDirectoriesParallel(ctx context.Context, opts ScanOptions, dirs ...string) (Fontset, error) {
+	results, err := walkParallel(ctx, opts, dirs, config.acceptFilename, func(path string) (interface{}, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		fonts := scanOneFontFile(file, path, config)
+		file.Close()
+
+		if debugMode {
+			if len(fonts) == 0 {
+				fmt.Println("invalid font file", path)
+			}
+		}
+
+		// pattern selector
+		var out Fontset
+		for _, f := range fonts {
+			if config.acceptFont(f) {
+				out = append(out, f)
+			}
+		}
+		return out, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out Fontset
+	for _, res := range results {
+		fs, _ := res.(Fontset)
+		out = append(out, fs...)
+	}
+	return out, nil
+}
+
+// PartialScanFontDirectoriesParallel is the same as `PartialScanFontDirectories`,
+// but parses the font files with a pool of workers (see `ScanFontDirectoriesParallel`).
+func PartialScanFontDirectoriesParallel(ctx context.Context, opts ScanOptions, dirs ...string) ([]fonts.FaceDescription, error) {
+	results, err := walkParallel(ctx, opts, dirs, nil, func(path string) (interface{}, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		fds, _ := scanFontFile(file)
+		file.Close()
+
+		out := make([]fonts.FaceDescription, len(fds))
+		for i, fd := range fds {
+			out[i] = fonts.FaceDescription{Family: fd.Family()}
+		}
+		return out, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []fonts.FaceDescription
+	for _, res := range results {
+		fds, _ := res.([]fonts.FaceDescription)
+		out = append(out, fds...)
+	}
+	return out, nil
+}
+
+// scanJob stores the result of the parsing of one file
+type scanJob struct {
+	path   string
+	result interface{}
+	err    error
+}
+
+// walkParallel recursively walks `dirs`, and calls `parse` on each valid font file
+// (optionnaly filtered by `accept`), using a pool of workers.
+// The results are returned in the order of the walk, and the first
+// error returned by `parse` (in the same order) aborts the scan.
+func walkParallel(ctx context.Context, opts ScanOptions, dirs []string,
+	accept func(path string) bool, parse func(path string) (interface{}, error)) ([]interface{}, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		mu       sync.Mutex // protects progress
+		progress ScanProgress
+	)
+	report := func(path string, parsed bool) {
+		if opts.Progress == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if parsed {
+			progress.Parsed++
+		} else {
+			progress.Seen++
+		}
+		progress.Path = path
+		opts.Progress(progress)
+	}
+
+	queue := make(chan *scanJob)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				if ctx.Err() != nil { // drain the queue
+					continue
+				}
+				job.result, job.err = parse(job.path)
+				report(job.path, true)
+			}
+		}()
+	}
+
+	// the walk happens on the calling goroutine, and is
+	// the only one to write to `jobs`
+	var jobs []*scanJob
+	seen := make(strSet) // keep track of visited dirs to avoid double includes
+	walkFn := func(path string, info os.FileInfo, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("invalid font location: %s", err)
+		}
+		if info.IsDir() { // keep going
+			if seen[path] {
+				return filepath.SkipDir
+			}
+			seen[path] = true
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			path, err = filepath.EvalSymlinks(path)
+			if err != nil {
+				return err
+			}
+		}
+		if !validFontFile(info.Name()) {
+			return nil
+		}
+
+		// path selector
+		if accept != nil && !accept(path) {
+			return nil
+		}
+
+		job := &scanJob{path: path}
+		jobs = append(jobs, job)
+		report(path, false)
+		select {
+		case queue <- job:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var err error
+	for _, dir := range dirs {
+		if debugMode {
+			fmt.Println("adding fonts from", dir)
+		}
+		if err = filepath.Walk(dir, walkFn); err != nil {
+			break
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]interface{}, len(jobs))
+	for i, job := range jobs {
+		if job.err != nil {
+			return nil, job.err
+		}
+		out[i] = job.result
+	}
+	return out, nil
+}
@@ -0,0 +1,84 @@
+package fontconfig
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanParallel(t *testing.T) {
+	c := NewConfig()
+	if err := c.LoadFromMemory(strings.NewReader(`<?xml version="1.0"?>
+		<fontconfig><selectfont><rejectfont><glob>*.pcf</glob></rejectfont></selectfont></fontconfig>`)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := c.ScanFontDirectories("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last ScanProgress
+	for _, workers := range []int{0, 1, 3} {
+		got, err := c.ScanFontDirectoriesParallel(context.Background(), ScanOptions{
+			Workers:  workers,
+			Progress: func(p ScanProgress) { last = p },
+		}, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d fonts, got %d", len(expected), len(got))
+		}
+		for i := range got {
+			if got[i].Hash() != expected[i].Hash() {
+				t.Fatalf("font %d: expected\n%s\n, got\n%s", i, expected[i], got[i])
+			}
+		}
+		if last.Seen == 0 || last.Seen != last.Parsed {
+			t.Fatalf("unexpected progress %v", last)
+		}
+	}
+
+	expectedPartial, err := PartialScanFontDirectories("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPartial, err := PartialScanFontDirectoriesParallel(context.Background(), ScanOptions{Workers: 2}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expectedPartial, gotPartial) {
+		t.Fatalf("expected %v, got %v", expectedPartial, gotPartial)
+	}
+
+	if _, err = c.ScanFontDirectoriesParallel(context.Background(), ScanOptions{}, "missing"); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
+
+func TestScanParallelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewConfig().ScanFontDirectoriesParallel(ctx, ScanOptions{}, "test"); err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+
+	// cancel during the scan
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	parsed := 0
+	_, err := PartialScanFontDirectoriesParallel(ctx, ScanOptions{Workers: 1, Progress: func(p ScanProgress) {
+		parsed = p.Parsed
+		if p.Parsed == 1 {
+			cancel()
+		}
+	}}, "test")
+	if err != context.Canceled {
+		t.Fatalf("expected cancellation, got %v", err)
+	}
+	if parsed != 1 {
+		t.Fatalf("expected scan to stop after the first file, got %d", parsed)
+	}
+}
@@ -1,6 +1,7 @@
 package fontconfig
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,8 +14,18 @@ import (
 )
 
 func scanFontFile(file fonts.Resource) ([]fonts.FontDescriptor, FontFormat) {
+	if sfnt, err := readWOFF2(file); err == nil {
+		out, err := truetype.ScanFont(bytes.NewReader(sfnt))
+		if err == nil {
+			return out, WOFF2
+		}
+		return nil, ""
+	}
 	out, err := truetype.ScanFont(file)
 	if err == nil {
+		if sig, _ := sniffSignature(file); sig == truetype.SignatureWOFF {
+			return out, WOFF
+		}
 		return out, TrueType
 	}
 	out, err = type1.ScanFont(file)
@@ -25,6 +36,12 @@ func scanFontFile(file fonts.Resource) ([]fonts.FontDescriptor, FontFormat) {
 	if err == nil {
 		return out, PCF
 	}
+	if pcf, err := readBDF(file); err == nil {
+		out, err = bitmap.ScanFont(bytes.NewReader(pcf))
+		if err == nil {
+			return out, BDF
+		}
+	}
 	return nil, ""
 }
 
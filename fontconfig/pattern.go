@@ -3,12 +3,12 @@ package fontconfig
 import (
 	"encoding/binary"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"unicode"
 
 	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // An Pattern holds a set of names with associated value lists; each name refers to a
@@ -21,8 +21,13 @@ type Pattern map[Object]*valueList
 // NewPattern returns an empty, initalized pattern
 func NewPattern() Pattern { return make(map[Object]*valueList) }
 
-// Format returns the font format of this pattern.
+// Format returns the font format of this pattern, which is the
+// format of the file (see `FontFormat.Loader`): for web fonts, FONTFORMAT
+// stores the format of the compressed font and FONT_WRAPPER the format of the file.
 func (p Pattern) Format() FontFormat {
+	if wrapper, _ := p.GetString(FONT_WRAPPER); wrapper == string(WOFF) || wrapper == string(WOFF2) {
+		return FontFormat(wrapper)
+	}
 	f, _ := p.GetString(FONTFORMAT)
 	return FontFormat(f)
 }
@@ -92,7 +97,7 @@ func (p Pattern) addList(object Object, list valueList, appendMode bool) {
 	// Make sure the stored type is valid for built-in objects
 	for _, value := range list {
 		if !object.hasValidType(value.Value) {
-			log.Printf("fontconfig: pattern object %s does not accept value %v", object, value.Value)
+			report(diagnostics.Diagnostic{Category: diagnostics.InvalidValue, Message: fmt.Sprintf("pattern object %s does not accept value %v", object, value.Value)})
 			return
 		}
 	}
@@ -508,7 +513,7 @@ func (pattern Pattern) SubstituteDefault() {
 	/* Set "en-us" instead of "en" to avoid giving higher score to "en".
 	 * This is a hack for the case that the orth is not like ll-cc, because,
 	 * if no namelang isn't explicitly set, it will has something like ll-cc
-	 * according to current locale. which may causes langDifferentTerritory
+	 * according to current locale. which may causes LangDifferentTerritory
 	 * at langCompare(). thus, the English name is selected so that
 	 * exact matched "en" has higher score than ll-cc.
 	 */
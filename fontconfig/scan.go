@@ -10,6 +10,7 @@ import (
 	"github.com/benoitkugler/textlayout/fonts/bitmap"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 	"github.com/benoitkugler/textlayout/fonts/type1"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/japanese"
@@ -25,9 +26,12 @@ var loaders = [...]struct {
 	loader fonts.FontLoader
 	format FontFormat
 }{
+	{loadWOFF, "WOFF"}, // before TrueType, which also accepts WOFF files
+	{loadWOFF2, "WOFF2"},
 	{truetype.Load, "TrueType"},
 	{bitmap.Load, "PCF"},
 	{type1.Load, "Type 1"},
+	{loadBDF, "BDF"},
 }
 
 // FontFormat identifies the supported font file types.
@@ -37,6 +41,9 @@ const (
 	TrueType FontFormat = "TrueType"
 	PCF      FontFormat = "PCF"
 	Type1    FontFormat = "Type 1"
+	WOFF     FontFormat = "WOFF"
+	WOFF2    FontFormat = "WOFF2"
+	BDF      FontFormat = "BDF"
 )
 
 // Loader returns the loader for the font format.
@@ -48,6 +55,12 @@ func (ff FontFormat) Loader() fonts.FontLoader {
 		return bitmap.Load
 	case "Type 1":
 		return type1.Load
+	case "WOFF":
+		return loadWOFF
+	case "WOFF2":
+		return loadWOFF2
+	case "BDF":
+		return loadBDF
 	default:
 		return nil
 	}
@@ -63,6 +76,7 @@ func scanOneFontFile(file fonts.Resource, fileID string, config *Config) Fontset
 
 	faces, format := ReadFontFile(file)
 	if format == "" {
+		config.report(diagnostics.Diagnostic{Category: diagnostics.InvalidFont, Message: "unsupported or invalid font file", File: fileID})
 		return nil
 	}
 
@@ -98,6 +112,14 @@ func scanOneFontFile(file fonts.Resource, fileID string, config *Config) Fontset
 	}
 
 	for _, font := range set {
+		switch format {
+		case WOFF, WOFF2: // the faces of web fonts are TrueType faces
+			font.AddString(FONT_WRAPPER, string(format))
+		case BDF: // BDF fonts are converted to PCF: record the actual format of the file
+			font.Del(FONTFORMAT)
+			font.AddString(FONTFORMAT, string(format))
+		}
+
 		// Edit pattern with user-defined rules
 		config.Substitute(font, nil, MatchScan)
 
@@ -1277,6 +1299,8 @@ var (
 	wght = truetype.MustNewTag("wght")
 	wdth = truetype.MustNewTag("wdth")
 	opsz = truetype.MustNewTag("opsz")
+	slnt = truetype.MustNewTag("slnt")
+	ital = truetype.MustNewTag("ital")
 )
 
 func hasHint(face *truetype.Font) bool { return face.HasHint }
@@ -1312,6 +1336,7 @@ type sharedSets struct {
 func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Pattern, *sharedSets) {
 	var (
 		variableWeight, variableWidth, variableSize, variable bool
+		variableSlant                                         Range   // empty if the slant is not variable
 		weight, width                                         float32 = -1., -1.
 		slant                                                 int32   = -1
 
@@ -1372,6 +1397,23 @@ func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Patte
 					obj = SIZE
 					// Values in 'opsz' match Fontconfig SIZE, both are in points.
 					variableSize = true
+
+				case ital:
+					// 'ital' goes from upright (0) to italic (1)
+					if minValue <= 0 && maxValue >= 1 {
+						variableSlant.End = maxF(variableSlant.End, SLANT_ITALIC)
+						isValid = true
+					}
+
+				case slnt:
+					// 'slnt' is in counter-clockwise degrees, so that oblique
+					// fonts use negative values
+					if minValue < 0 {
+						variableSlant.End = maxF(variableSlant.End, SLANT_OBLIQUE)
+						// record the axis, to resolve the oblique angle when matching
+						pat.Add(SLANT_AXIS, Range{Begin: minValue, End: maxValue}, true)
+						isValid = true
+					}
 				}
 
 				if obj != invalid {
@@ -1404,6 +1446,16 @@ func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Patte
 
 				case opsz:
 					pat.AddFloat(SIZE, float32(value))
+
+				case ital:
+					if value >= 1 {
+						slant = SLANT_ITALIC
+					}
+
+				case slnt:
+					if value < 0 && slant == -1 {
+						slant = SLANT_OBLIQUE
+					}
 				}
 			}
 
@@ -1733,6 +1785,8 @@ func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Patte
 		}
 
 		pat.AddBool(FONT_HAS_HINT, hasHint(face))
+
+		queryMetadata(pat, face)
 	}
 
 	if !variableSize && os2 != nil && os2.Version >= 0x0005 && os2.Version != 0xffff {
@@ -1835,7 +1889,11 @@ func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Patte
 		foundry = "unknown"
 	}
 
-	pat.AddInt(SLANT, slant)
+	if variableSlant.End != 0 {
+		pat.Add(SLANT, variableSlant, true)
+	} else {
+		pat.AddInt(SLANT, slant)
+	}
 
 	if !variableWeight {
 		pat.AddFloat(WEIGHT, weight)
@@ -1900,7 +1958,7 @@ func queryFace(face fonts.Face, file string, id uint32, sets *sharedSets) (Patte
 			fmt.Printf("\tfont charset: %v \n", cs)
 		}
 		if sets == nil {
-			ls = buildLangSet(cs, exclusiveLang)
+			ls = BuildLangset(cs, exclusiveLang)
 		} else {
 			ls = sets.ls.Copy()
 		}
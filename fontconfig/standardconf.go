@@ -15129,5 +15129,5 @@ var Standard = &Config{
 		Pattern{1 /* family */ : &valueList{valueElt{Value: String("TeXGyreTermes"), Binding: 1}}, 37 /* fontformat */ : &valueList{valueElt{Value: String("Type 1"), Binding: 1}}},
 		Pattern{24 /* outline */ : &valueList{valueElt{Value: Bool(0), Binding: 1}}},
 		Pattern{24 /* outline */ : &valueList{valueElt{Value: Bool(0), Binding: 1}}, 25 /* scalable */ : &valueList{valueElt{Value: Bool(0), Binding: 1}}}},
-	maxObjects: 22,
+	maxObjects: 10,
 }
@@ -205,6 +205,15 @@ type ruleSet struct {
 	subst       [matchKindEnd][]directive
 }
 
+func (rs *ruleSet) isEmpty() bool {
+	for _, v := range rs.subst {
+		if len(v) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 func (rs *ruleSet) String() string {
 	lines := []string{"RuleSet from " + rs.name}
 	for i, v := range rs.subst {
@@ -395,7 +404,7 @@ func matchValueList(p, pPat Pattern, kind matchKind,
 func substituteLang(p Pattern) {
 	strs := getDefaultLangs()
 	var lsund Langset
-	lsund.add("und")
+	lsund.Add("und")
 
 	for lang := range strs {
 		for _, ll := range p.getVals(LANG) {
@@ -403,13 +412,13 @@ func substituteLang(p Pattern) {
 
 			if vv, ok := vvL.(Langset); ok {
 				var ls Langset
-				ls.add(lang)
+				ls.Add(lang)
 
-				b := vv.includes(ls)
+				b := vv.Includes(ls)
 				if b {
 					return
 				}
-				if vv.includes(lsund) {
+				if vv.Includes(lsund) {
 					return
 				}
 			} else {
@@ -431,6 +440,30 @@ func substituteLang(p Pattern) {
 // if `kind` is MatchResult, those tagged as font operations are applied and
 // `testPattern` is used for <test> elements with target=pattern.
 func (config *Config) Substitute(p, testPattern Pattern, kind matchKind) {
+	config.substitute(p, testPattern, kind, nil)
+}
+
+// SubstituteTrace is the same as `Substitute`, but calls `trace`
+// (if not nil) for each rule applied, that is for each rule whose tests
+// all matched, in the order of application.
+// The tracer registered with `SetTracer`, if any, is also called.
+func (config *Config) SubstituteTrace(p, testPattern Pattern, kind matchKind, trace func(RuleTrace)) {
+	config.substitute(p, testPattern, kind, trace)
+}
+
+func (config *Config) substitute(p, testPattern Pattern, kind matchKind, trace func(RuleTrace)) {
+	if tracer := config.tracer; tracer != nil {
+		if trace == nil {
+			trace = tracer
+		} else {
+			explicit := trace
+			trace = func(rule RuleTrace) {
+				explicit(rule)
+				tracer(rule)
+			}
+		}
+	}
+
 	if kind == MatchQuery {
 		substituteLang(p)
 
@@ -462,7 +495,7 @@ func (config *Config) Substitute(p, testPattern Pattern, kind matchKind) {
 		}
 
 	subsLoop:
-		for _, rule := range rulesList {
+		for ruleIndex, rule := range rulesList {
 			for i := range valuePos { // reset the edits locations
 				targets[i] = nil
 				valuePos[i] = -1
@@ -481,15 +514,32 @@ func (config *Config) Substitute(p, testPattern Pattern, kind matchKind) {
 					continue subsLoop
 				}
 			}
+			var ruleTrace RuleTrace
+			if trace != nil {
+				ruleTrace = newRuleTrace(rs, kind, ruleIndex, rule)
+			}
 			for _, edit := range rule.edits {
 				if debugMode {
 					fmt.Println("\t\tsubstitute edit", edit)
 				}
+				var before Pattern
+				if trace != nil {
+					before = p.Duplicate()
+				}
+
 				edit.edit(kind, p, testPattern, table, valuePos, targets, tst)
 
 				if debugMode {
 					fmt.Println("\t\tafter edit", p.String())
 				}
+				if trace != nil {
+					ruleTrace.Edits = append(ruleTrace.Edits, EditTrace{
+						Object: edit.object, Edit: edit.String(), Before: before, After: p.Duplicate(),
+					})
+				}
+			}
+			if trace != nil {
+				trace(ruleTrace)
 			}
 		}
 	}
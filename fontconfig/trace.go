@@ -0,0 +1,65 @@
+package fontconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleTrace describes a rule applied during `Config.SubstituteTrace`.
+type RuleTrace struct {
+	RuleSet     string    // name of the rule set (typically, the configuration file)
+	Description string    // description of the rule set, if any
+	Kind        matchKind // MatchQuery, MatchResult or MatchScan
+	Index       int       // index of the rule among the rules of the same kind in the rule set
+	Tests       []string  // the tests of the rule, which all matched
+	Edits       []EditTrace
+}
+
+// EditTrace describes an edit applied during `Config.SubstituteTrace`.
+type EditTrace struct {
+	Object Object
+	Edit   string // human friendly representation of the edit
+
+	// copies of the pattern, before and after the edit
+	Before, After Pattern
+}
+
+// Changed returns true if the edit actually modified the pattern.
+func (edit EditTrace) Changed() bool {
+	return edit.Before.Hash() != edit.After.Hash()
+}
+
+// SetTracer registers a function called for each rule applied by
+// the configuration, including the substitutions performed internally
+// (like in `PrepareRender` or when scanning font files).
+// Passing nil disables tracing.
+// See also `Config.SubstituteTrace`.
+func (config *Config) SetTracer(tracer func(RuleTrace)) { config.tracer = tracer }
+
+func newRuleTrace(rs ruleSet, kind matchKind, index int, rule directive) RuleTrace {
+	out := RuleTrace{
+		RuleSet:     rs.name,
+		Description: rs.description,
+		Kind:        kind,
+		Index:       index,
+		Tests:       make([]string, len(rule.tests)),
+	}
+	for i, test := range rule.tests {
+		out.Tests[i] = test.String()
+	}
+	return out
+}
+
+// String returns a human friendly summary of the rule, showing
+// the values of the edited objects.
+func (rule RuleTrace) String() string {
+	lines := []string{fmt.Sprintf("rule %d (%s) from %s", rule.Index, rule.Kind, rule.RuleSet)}
+	for _, test := range rule.Tests {
+		lines = append(lines, "\ttest "+test)
+	}
+	for _, edit := range rule.Edits {
+		lines = append(lines, fmt.Sprintf("\tedit %s: %s -> %s", edit.Edit,
+			edit.Before.getVals(edit.Object), edit.After.getVals(edit.Object)))
+	}
+	return strings.Join(lines, "\n")
+}
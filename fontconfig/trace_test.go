@@ -0,0 +1,138 @@
+package fontconfig
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func hasFamily(p Pattern, family string) bool {
+	for _, v := range p.getVals(FAMILY) {
+		if v.Value == String(family) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSubstituteTrace(t *testing.T) {
+	query := NewPattern()
+	query.AddString(FAMILY, "sans-serif")
+	p1, p2 := query.Duplicate(), query.Duplicate()
+
+	var rules []RuleTrace
+	Standard.Substitute(p1, nil, MatchQuery)
+	Standard.SubstituteTrace(p2, nil, MatchQuery, func(r RuleTrace) { rules = append(rules, r) })
+	if p1.Hash() != p2.Hash() {
+		t.Fatalf("expected %s, got %s", p1, p2)
+	}
+
+	// find the rule adding DejaVu Sans
+	var culprit *EditTrace
+	for _, rule := range rules {
+		if rule.Kind != MatchQuery {
+			t.Fatalf("unexpected kind %s", rule.Kind)
+		}
+		for i, edit := range rule.Edits {
+			if edit.Object != FAMILY || !edit.Changed() {
+				continue
+			}
+			if !hasFamily(edit.Before, "DejaVu Sans") && hasFamily(edit.After, "DejaVu Sans") {
+				culprit = &rule.Edits[i]
+				if !strings.HasSuffix(rule.RuleSet, "60-latin.conf") {
+					t.Fatalf("unexpected rule set %s", rule.RuleSet)
+				}
+			}
+		}
+	}
+	if culprit == nil {
+		t.Fatal("missing rule adding DejaVu Sans")
+	}
+}
+
+func TestSubstituteTraceFont(t *testing.T) {
+	doc := []byte(`<fontconfig>
+		<description>test</description>
+		<match target="font">
+			<test name="family" target="pattern"><string>Fancy</string></test>
+			<edit name="embolden" mode="assign"><bool>true</bool></edit>
+			<edit name="hinting" mode="assign"><bool>false</bool></edit>
+		</match>
+		<match target="font">
+			<test name="family" target="pattern"><string>Other</string></test>
+			<edit name="hinting" mode="assign"><bool>true</bool></edit>
+		</match>
+	</fontconfig>`)
+	config := NewConfig()
+	if err := config.LoadFromMemory(bytes.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	query, font := NewPattern(), NewPattern()
+	query.AddString(FAMILY, "Fancy")
+	font.AddString(FAMILY, "DejaVu Sans")
+	font.Add(HINTING, True, true)
+
+	var rules []RuleTrace
+	config.SubstituteTrace(font, query, MatchResult, func(r RuleTrace) { rules = append(rules, r) })
+	if len(rules) != 1 {
+		t.Fatalf("expected one rule, got %d", len(rules))
+	}
+	rule := rules[0]
+	if rule.Description != "test" || rule.Index != 0 || len(rule.Tests) != 1 || len(rule.Edits) != 2 {
+		t.Fatalf("unexpected trace %v", rule)
+	}
+	if b, _ := rule.Edits[1].Before.GetBool(HINTING); b != True {
+		t.Fatalf("unexpected pattern before edit %s", rule.Edits[1].Before)
+	}
+	if b, _ := rule.Edits[1].After.GetBool(HINTING); b != False {
+		t.Fatalf("unexpected pattern after edit %s", rule.Edits[1].After)
+	}
+	if s := rule.String(); !strings.Contains(s, "embolden") || !strings.Contains(s, "hinting") {
+		t.Fatalf("unexpected summary %s", s)
+	}
+}
+
+func TestSetTracer(t *testing.T) {
+	doc := []byte(`<fontconfig>
+		<match target="scan">
+			<edit name="family" mode="assign_replace"><string>Scanned</string></edit>
+		</match>
+		<match target="font">
+			<edit name="embolden" mode="assign"><bool>true</bool></edit>
+		</match>
+	</fontconfig>`)
+	config := NewConfig()
+	if err := config.LoadFromMemory(bytes.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []matchKind
+	config.SetTracer(func(r RuleTrace) { kinds = append(kinds, r.Kind) })
+
+	fs, err := config.ScanFontFile("test/8x16.bdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fs) == 0 || !hasFamily(fs[0], "Scanned") {
+		t.Fatalf("scan rule not applied: %v", fs)
+	}
+	config.PrepareRender(NewPattern(), fs[0])
+
+	var explicit int
+	config.SubstituteTrace(NewPattern(), fs[0], MatchResult, func(RuleTrace) { explicit++ })
+	if explicit != 1 {
+		t.Fatalf("expected one explicitly traced rule, got %d", explicit)
+	}
+
+	if exp := []matchKind{MatchScan, MatchResult, MatchResult}; !reflect.DeepEqual(kinds, exp) {
+		t.Fatalf("expected %v, got %v", exp, kinds)
+	}
+
+	config.SetTracer(nil)
+	config.Substitute(NewPattern(), fs[0], MatchResult)
+	if len(kinds) != 3 {
+		t.Fatalf("unexpected trace after SetTracer(nil): %v", kinds)
+	}
+}
@@ -3,8 +3,9 @@ package fontconfig
 import (
 	"fmt"
 	"io"
-	"log"
 	"strings"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 var Identity = Matrix{1, 0, 0, 1}
@@ -23,7 +24,7 @@ const (
 	STYLELANG              // with type String
 	FULLNAME               // with type String
 	FULLNAMELANG           // with type String
-	SLANT                  // with type Int
+	SLANT                  // with type Int (or Range for variable fonts)
 	WEIGHT                 // with type Range
 	WIDTH                  // with type Range
 	SIZE                   // with type Range
@@ -69,6 +70,18 @@ const (
 	VARIABLE               // with type Bool
 	FONT_HAS_HINT          // with type Bool
 	ORDER                  // with type Int
+	VENDOR_ID              // with type String
+	PANOSE                 // with type String
+	UNICODE_RANGE          // with type Int
+	CODEPAGE_RANGE         // with type Int
+	FS_TYPE                // with type Int
+	DESIGNER               // with type String
+	LICENSE_URL            // with type String
+	DESCRIPTION            // with type String
+	OT_SCRIPT              // with type String
+	OT_FEATURE             // with type String
+	SLANT_AXIS             // with type Range
+	FONT_WRAPPER           // with type String
 	// Custom objects should be defined starting from this value
 	FirstCustomObject
 )
@@ -192,13 +205,17 @@ func (object Object) hasValidType(val Value) bool {
 	switch object {
 	case FAMILY, FAMILYLANG, STYLE, STYLELANG, FULLNAME, FULLNAMELANG, FOUNDRY,
 		RASTERIZER, CAPABILITY, NAMELANG, FONT_FEATURES, PRGNAME, HASH, POSTSCRIPT_NAME,
-		FONTFORMAT, FILE, FONT_VARIATIONS: // string
+		FONTFORMAT, FILE, FONT_VARIATIONS, VENDOR_ID, PANOSE, DESIGNER, LICENSE_URL, DESCRIPTION,
+		OT_SCRIPT, OT_FEATURE, FONT_WRAPPER: // string
 		_, isString := val.(String)
 		return isString
-	case ORDER, SLANT, SPACING, HINT_STYLE, RGBA, INDEX,
-		CHARWIDTH, LCD_FILTER, FONTVERSION, CHAR_HEIGHT: // Int
+	case SLANT: // Int, or Range for variable fonts
+		_, isRange := val.(Range)
+		return isInt || isRange
+	case ORDER, SPACING, HINT_STYLE, RGBA, INDEX,
+		CHARWIDTH, LCD_FILTER, FONTVERSION, CHAR_HEIGHT, UNICODE_RANGE, CODEPAGE_RANGE, FS_TYPE: // Int
 		return isInt
-	case WEIGHT, WIDTH, SIZE: // range
+	case WEIGHT, WIDTH, SIZE, SLANT_AXIS: // range
 		_, isRange := val.(Range)
 		return isInt || isFloat || isRange
 	case ASPECT, PIXEL_SIZE, SCALE, DPI: // float
@@ -218,7 +235,8 @@ func (object Object) hasValidType(val Value) bool {
 		_, isString := val.(String)
 		return isLangSet || isString
 	default:
-		// no validation
+		// custom objects are validated by the configurations,
+		// see Config.RegisterObject
 		return true
 	}
 }
@@ -259,7 +277,7 @@ func valueEqual(va, vb Value) bool {
 		}
 	case Langset:
 		if vb, ok := vb.(Langset); ok {
-			return langsetEqual(va, vb)
+			return va.Equal(vb)
 		}
 	case Range:
 		if vb, ok := vb.(Range); ok {
@@ -362,7 +380,7 @@ func (head *valueList) insert(position int, appendMode bool, newList valueList,
 	// Make sure the stored type is valid for built-in objects
 	for _, l := range newList {
 		if !object.hasValidType(l.Value) {
-			log.Printf("fontconfig: pattern object %s does not accept value %v", object, l.Value)
+			report(diagnostics.Diagnostic{Category: diagnostics.InvalidValue, Message: fmt.Sprintf("pattern object %s does not accept value %v", object, l.Value)})
 			return false
 		}
 	}
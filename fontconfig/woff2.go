@@ -0,0 +1,914 @@
+package fontconfig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+// This file implements the decompression of WOFF2 files into
+// plain sfnt (TrueType/OpenType) files, as described in
+// https://www.w3.org/TR/WOFF2/, including the glyf, loca and hmtx transforms.
+
+var signatureWOFF2 = truetype.MustNewTag("wOF2")
+
+// sniffSignature returns the 4 first bytes of the file
+func sniffSignature(file fonts.Resource) (truetype.Tag, error) {
+	var buf [4]byte
+	if _, err := file.ReadAt(buf[:], 0); err != nil {
+		return 0, err
+	}
+	return truetype.Tag(binary.BigEndian.Uint32(buf[:])), nil
+}
+
+// loadWOFF only accepts WOFF files, which are
+// then handled by the TrueType loader.
+func loadWOFF(file fonts.Resource) (fonts.Faces, error) {
+	if sig, err := sniffSignature(file); err != nil || sig != truetype.SignatureWOFF {
+		return nil, errors.New("not a WOFF file")
+	}
+	return truetype.Load(file)
+}
+
+// loadWOFF2 decompresses the WOFF2 file and load
+// the resulting sfnt file with the TrueType loader.
+func loadWOFF2(file fonts.Resource) (fonts.Faces, error) {
+	sfnt, err := readWOFF2(file)
+	if err != nil {
+		return nil, err
+	}
+	return truetype.Load(bytes.NewReader(sfnt))
+}
+
+// readWOFF2 checks the signature and returns the decompressed sfnt content.
+func readWOFF2(file fonts.Resource) ([]byte, error) {
+	if sig, err := sniffSignature(file); err != nil || sig != signatureWOFF2 {
+		return nil, errors.New("not a WOFF2 file")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWOFF2(data)
+}
+
+const woff2HeaderSize = 48
+
+// woff2KnownTags is indexed by the 6 lower bits of the table flags
+var woff2KnownTags = [63]string{
+	"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post",
+	"cvt ", "fpgm", "glyf", "loca", "prep", "CFF ", "VORG", "EBDT",
+	"EBLC", "gasp", "hdmx", "kern", "LTSH", "PCLT", "VDMX", "vhea",
+	"vmtx", "BASE", "GDEF", "GPOS", "GSUB", "EBSC", "JSTF", "MATH",
+	"CBDT", "CBLC", "COLR", "CPAL", "SVG ", "sbix", "acnt", "avar",
+	"bdat", "bloc", "bsln", "cvar", "fdsc", "feat", "fmtx", "fvar",
+	"gvar", "hsty", "just", "lcar", "mort", "morx", "opbd", "prop",
+	"trak", "Zapf", "Silf", "Glat", "Gloc", "Feat", "Sill",
+}
+
+var (
+	tagGlyf = truetype.MustNewTag("glyf")
+	tagLoca = truetype.MustNewTag("loca")
+	tagHmtx = truetype.MustNewTag("hmtx")
+	tagHhea = truetype.MustNewTag("hhea")
+	tagHead = truetype.MustNewTag("head")
+	tagTTCF = truetype.MustNewTag("ttcf")
+)
+
+type woff2Table struct {
+	tag         truetype.Tag
+	transformed bool
+	origLength  uint32
+	// length of the data in the decompressed stream
+	// (which is origLength for non transformed tables)
+	streamLength uint32
+
+	data []byte // reconstructed table
+
+	// set when reconstructing the transformed tables,
+	// which may be shared between the fonts of a collection
+	xMins      []int16     // for glyf tables, used by the hmtx transform
+	hmtxSource *hmtxSource // for hmtx tables
+}
+
+// hmtxSource identifies the data used to reconstruct a transformed hmtx table
+type hmtxSource struct {
+	glyf        int // index of the glyf table
+	numHMetrics int
+}
+
+type woff2Font struct {
+	flavor uint32
+	tables []int // indices into the tables directory
+}
+
+// woff2Reader is a simple cursor over a byte slice,
+// recording the first error.
+type woff2Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *woff2Reader) fail() {
+	if r.err == nil {
+		r.err = errors.New("invalid WOFF2 data: unexpected end of input")
+	}
+}
+
+func (r *woff2Reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data)-r.pos < n {
+		r.fail()
+		return nil
+	}
+	out := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return out
+}
+
+func (r *woff2Reader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *woff2Reader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *woff2Reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// uintBase128 reads a variable-length encoded uint32
+func (r *woff2Reader) uintBase128() uint32 {
+	var accum uint32
+	for i := 0; i < 5; i++ {
+		b := r.u8()
+		if r.err != nil {
+			return 0
+		}
+		// leading zeros are invalid
+		if i == 0 && b == 0x80 {
+			r.err = errors.New("invalid WOFF2 data: invalid UIntBase128 value")
+			return 0
+		}
+		// check for overflow
+		if accum&0xFE000000 != 0 {
+			r.err = errors.New("invalid WOFF2 data: overflowing UIntBase128 value")
+			return 0
+		}
+		accum = accum<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return accum
+		}
+	}
+	r.err = errors.New("invalid WOFF2 data: UIntBase128 value too long")
+	return 0
+}
+
+// read255UInt16 reads a variable-length encoded uint16
+func (r *woff2Reader) read255UInt16() uint16 {
+	const (
+		oneMoreByteCode1 = 255
+		oneMoreByteCode2 = 254
+		wordCode         = 253
+		lowestUCode      = 253
+	)
+	switch code := r.u8(); code {
+	case wordCode:
+		return r.u16()
+	case oneMoreByteCode1:
+		return uint16(r.u8()) + lowestUCode
+	case oneMoreByteCode2:
+		return uint16(r.u8()) + lowestUCode*2
+	default:
+		return uint16(code)
+	}
+}
+
+// decodeWOFF2 returns the sfnt (or TrueType collection) file
+// compressed in `data`.
+func decodeWOFF2(data []byte) ([]byte, error) {
+	r := woff2Reader{data: data}
+	if truetype.Tag(r.u32()) != signatureWOFF2 {
+		return nil, errors.New("not a WOFF2 file")
+	}
+	flavor := r.u32()
+	if length := r.u32(); int(length) != len(data) {
+		return nil, fmt.Errorf("invalid WOFF2 file: length mismatch (%d != %d)", length, len(data))
+	}
+	numTables := r.u16()
+	r.u16() // reserved
+	r.u32() // totalSfntSize
+	totalCompressedSize := r.u32()
+	r.bytes(woff2HeaderSize - r.pos) // versions, metadata and private data
+	if r.err != nil {
+		return nil, r.err
+	}
+	if numTables == 0 {
+		return nil, errors.New("invalid WOFF2 file: no tables")
+	}
+
+	tables := make([]woff2Table, numTables)
+	var streamSize uint64
+	for i := range tables {
+		flags := r.u8()
+		if index := flags & 0x3F; index == 63 {
+			tables[i].tag = truetype.Tag(r.u32())
+		} else {
+			tables[i].tag = truetype.MustNewTag(woff2KnownTags[index])
+		}
+		version := flags >> 6
+		if tag := tables[i].tag; tag == tagGlyf || tag == tagLoca {
+			tables[i].transformed = version == 0 // 3 is the null transform
+		} else {
+			tables[i].transformed = version != 0
+		}
+		tables[i].origLength = r.uintBase128()
+		tables[i].streamLength = tables[i].origLength
+		if tables[i].transformed {
+			tables[i].streamLength = r.uintBase128()
+			if tables[i].tag == tagLoca && tables[i].streamLength != 0 {
+				return nil, errors.New("invalid WOFF2 file: transformed loca table must be empty")
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		streamSize += uint64(tables[i].streamLength)
+	}
+
+	var fontsDir []woff2Font
+	isCollection := truetype.Tag(flavor) == tagTTCF
+	var ttcVersion uint32
+	if isCollection {
+		ttcVersion = r.u32()
+		fontsDir = make([]woff2Font, r.read255UInt16())
+		for i := range fontsDir {
+			fontsDir[i].tables = make([]int, r.read255UInt16())
+			fontsDir[i].flavor = r.u32()
+			for j := range fontsDir[i].tables {
+				index := int(r.read255UInt16())
+				if index >= len(tables) {
+					return nil, fmt.Errorf("invalid WOFF2 file: invalid table index %d", index)
+				}
+				fontsDir[i].tables[j] = index
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		if len(fontsDir) == 0 {
+			return nil, errors.New("invalid WOFF2 file: empty collection")
+		}
+	} else {
+		font := woff2Font{flavor: flavor, tables: make([]int, len(tables))}
+		for i := range font.tables {
+			font.tables[i] = i
+		}
+		fontsDir = []woff2Font{font}
+	}
+
+	compressed := r.bytes(int(totalCompressedSize))
+	if r.err != nil {
+		return nil, r.err
+	}
+	if streamSize > 1<<30 { // guard against malicious files with hard limit
+		return nil, fmt.Errorf("invalid WOFF2 file: unsupported decompressed size %d", streamSize)
+	}
+	stream := make([]byte, streamSize)
+	if _, err := io.ReadFull(brotli.NewReader(bytes.NewReader(compressed)), stream); err != nil {
+		return nil, fmt.Errorf("invalid WOFF2 file: %s", err)
+	}
+
+	var offset uint32
+	for i := range tables {
+		tables[i].data = stream[offset : offset+tables[i].streamLength]
+		offset += tables[i].streamLength
+	}
+
+	for _, font := range fontsDir {
+		if err := reconstructWOFF2Font(tables, font); err != nil {
+			return nil, err
+		}
+	}
+
+	if isCollection {
+		return writeCollection(tables, fontsDir, ttcVersion), nil
+	}
+	return writeSfnt(tables, fontsDir[0]), nil
+}
+
+// reconstructWOFF2Font applies the inverse transforms on the tables of `font`.
+// Tables shared between fonts are only reconstructed once: a transformed hmtx table
+// may only be shared by fonts with the same glyf table and number of horizontal metrics.
+func reconstructWOFF2Font(tables []woff2Table, font woff2Font) error {
+	glyf, loca, hhea, hmtx := -1, -1, -1, -1
+	for _, index := range font.tables {
+		switch tables[index].tag {
+		case tagGlyf:
+			glyf = index
+		case tagLoca:
+			loca = index
+		case tagHhea:
+			hhea = index
+		case tagHmtx:
+			hmtx = index
+		}
+	}
+
+	if (glyf == -1) != (loca == -1) {
+		return errors.New("invalid WOFF2 file: glyf and loca tables must be both present or both absent")
+	}
+
+	if glyf != -1 {
+		if tables[glyf].transformed != tables[loca].transformed {
+			return errors.New("invalid WOFF2 file: glyf and loca tables must be both transformed or both not transformed")
+		}
+		if tables[glyf].transformed {
+			glyfData, locaData, xMins, err := reconstructGlyf(tables[glyf].data)
+			if err != nil {
+				return err
+			}
+			if uint32(len(locaData)) != tables[loca].origLength {
+				return errors.New("invalid WOFF2 file: invalid loca table length")
+			}
+			tables[glyf].data, tables[glyf].transformed, tables[glyf].xMins = glyfData, false, xMins
+			tables[loca].data, tables[loca].transformed = locaData, false
+		}
+	}
+
+	if hmtx != -1 && (tables[hmtx].transformed || tables[hmtx].hmtxSource != nil) {
+		// xMins is kept when the glyf table is shared with a previous font
+		if glyf == -1 || tables[glyf].xMins == nil || hhea == -1 {
+			return errors.New("invalid WOFF2 file: transformed hmtx table requires transformed glyf and hhea tables")
+		}
+		hheaData := tables[hhea].data
+		if len(hheaData) < 36 {
+			return errors.New("invalid WOFF2 file: invalid hhea table")
+		}
+		source := hmtxSource{glyf: glyf, numHMetrics: int(binary.BigEndian.Uint16(hheaData[34:]))}
+		if tables[hmtx].hmtxSource != nil { // already reconstructed for a previous font
+			if *tables[hmtx].hmtxSource != source {
+				return errors.New("invalid WOFF2 file: transformed hmtx table shared by fonts with different glyf or hhea tables")
+			}
+		} else {
+			hmtxData, err := reconstructHmtx(tables[hmtx].data, source.numHMetrics, tables[glyf].xMins)
+			if err != nil {
+				return err
+			}
+			tables[hmtx].data, tables[hmtx].transformed, tables[hmtx].hmtxSource = hmtxData, false, &source
+		}
+	}
+
+	for _, index := range font.tables {
+		if tables[index].transformed {
+			return fmt.Errorf("invalid WOFF2 file: unsupported transform for table %s", tables[index].tag)
+		}
+	}
+	return nil
+}
+
+// glyph flags
+const (
+	glyfOnCurve     = 0x01
+	glyfXShort      = 0x02
+	glyfYShort      = 0x04
+	glyfRepeat      = 0x08
+	glyfXSame       = 0x10
+	glyfYSame       = 0x20
+	glyfOverlapSimp = 0x40
+)
+
+// composite glyph flags
+const (
+	compArgsAreWords    = 0x0001
+	compHaveScale       = 0x0008
+	compMoreComponents  = 0x0020
+	compHaveXYScale     = 0x0040
+	compHaveTwoByTwo    = 0x0080
+	compHaveInstruction = 0x0100
+)
+
+// reconstructGlyf returns the glyf and loca tables, and the xMin of each glyph.
+func reconstructGlyf(data []byte) (glyf, loca []byte, xMins []int16, err error) {
+	header := woff2Reader{data: data}
+	header.u16() // reserved
+	optionFlags := header.u16()
+	numGlyphs := int(header.u16())
+	indexFormat := header.u16()
+	var sizes [7]uint32
+	for i := range sizes {
+		sizes[i] = header.u32()
+	}
+	if header.err != nil {
+		return nil, nil, nil, header.err
+	}
+
+	var streams [7]woff2Reader // nContour, nPoints, flag, glyph, composite, bbox, instruction
+	for i, size := range sizes {
+		streams[i].data = header.bytes(int(size))
+	}
+	var overlapBitmap []byte
+	if optionFlags&1 != 0 {
+		overlapBitmap = header.bytes((numGlyphs + 7) / 8)
+	}
+	if header.err != nil {
+		return nil, nil, nil, header.err
+	}
+	nContourStream, nPointsStream, flagStream := &streams[0], &streams[1], &streams[2]
+	glyphStream, compositeStream, bboxStream, instructionStream := &streams[3], &streams[4], &streams[5], &streams[6]
+
+	bboxBitmap := bboxStream.bytes(4 * ((numGlyphs + 31) / 32))
+	if bboxStream.err != nil {
+		return nil, nil, nil, bboxStream.err
+	}
+	hasBbox := func(i int) bool { return bboxBitmap[i>>3]&(0x80>>uint(i&7)) != 0 }
+
+	var (
+		out     bytes.Buffer
+		offsets = make([]uint32, numGlyphs+1)
+		tmp     [2]byte
+	)
+	xMins = make([]int16, numGlyphs)
+	writeU16 := func(v uint16) {
+		binary.BigEndian.PutUint16(tmp[:], v)
+		out.Write(tmp[:])
+	}
+	for i := 0; i < numGlyphs; i++ {
+		nContours := int16(nContourStream.u16())
+		if nContourStream.err != nil {
+			return nil, nil, nil, nContourStream.err
+		}
+		switch {
+		case nContours == 0: // empty glyph
+			if hasBbox(i) {
+				return nil, nil, nil, errors.New("invalid WOFF2 file: empty glyph with bounding box")
+			}
+		case nContours == -1: // composite glyph
+			if !hasBbox(i) {
+				return nil, nil, nil, errors.New("invalid WOFF2 file: composite glyph without bounding box")
+			}
+			bbox := bboxStream.bytes(8)
+			if bboxStream.err != nil {
+				return nil, nil, nil, bboxStream.err
+			}
+			start := compositeStream.pos
+			haveInstructions := false
+			for flags := uint16(compMoreComponents); flags&compMoreComponents != 0; {
+				flags = compositeStream.u16()
+				haveInstructions = haveInstructions || flags&compHaveInstruction != 0
+				size := 2 // glyph index
+				if flags&compArgsAreWords != 0 {
+					size += 4
+				} else {
+					size += 2
+				}
+				if flags&compHaveScale != 0 {
+					size += 2
+				} else if flags&compHaveXYScale != 0 {
+					size += 4
+				} else if flags&compHaveTwoByTwo != 0 {
+					size += 8
+				}
+				compositeStream.bytes(size)
+				if compositeStream.err != nil {
+					return nil, nil, nil, compositeStream.err
+				}
+			}
+			writeU16(uint16(nContours))
+			out.Write(bbox)
+			out.Write(compositeStream.data[start:compositeStream.pos])
+			if haveInstructions {
+				instructionLength := glyphStream.read255UInt16()
+				if glyphStream.err != nil {
+					return nil, nil, nil, glyphStream.err
+				}
+				instructions := instructionStream.bytes(int(instructionLength))
+				if instructionStream.err != nil {
+					return nil, nil, nil, instructionStream.err
+				}
+				writeU16(instructionLength)
+				out.Write(instructions)
+			}
+			xMins[i] = int16(binary.BigEndian.Uint16(bbox))
+		case nContours > 0: // simple glyph
+			endPoints := make([]uint16, nContours)
+			nPoints := 0
+			for c := range endPoints {
+				nPoints += int(nPointsStream.read255UInt16())
+				endPoints[c] = uint16(nPoints - 1)
+			}
+			if nPointsStream.err != nil {
+				return nil, nil, nil, nPointsStream.err
+			}
+			if nPoints > 0xFFFF {
+				return nil, nil, nil, errors.New("invalid WOFF2 file: too many points in glyph")
+			}
+
+			flags := flagStream.bytes(nPoints)
+			if flagStream.err != nil {
+				return nil, nil, nil, flagStream.err
+			}
+			points, err := decodeTriplets(flags, glyphStream)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			instructionLength := glyphStream.read255UInt16()
+
+			var bbox [4]int16 // xMin, yMin, xMax, yMax
+			if hasBbox(i) {
+				r := bboxStream.bytes(8)
+				if bboxStream.err != nil {
+					return nil, nil, nil, bboxStream.err
+				}
+				for j := range bbox {
+					bbox[j] = int16(binary.BigEndian.Uint16(r[2*j:]))
+				}
+			} else if len(points) != 0 {
+				bbox = [4]int16{points[0].x, points[0].y, points[0].x, points[0].y}
+				for _, p := range points[1:] {
+					if p.x < bbox[0] {
+						bbox[0] = p.x
+					}
+					if p.y < bbox[1] {
+						bbox[1] = p.y
+					}
+					if p.x > bbox[2] {
+						bbox[2] = p.x
+					}
+					if p.y > bbox[3] {
+						bbox[3] = p.y
+					}
+				}
+			}
+			xMins[i] = bbox[0]
+
+			writeU16(uint16(nContours))
+			for _, v := range bbox {
+				writeU16(uint16(v))
+			}
+			for _, v := range endPoints {
+				writeU16(v)
+			}
+			writeU16(instructionLength)
+			out.Write(instructionStream.bytes(int(instructionLength)))
+			overlap := overlapBitmap != nil && overlapBitmap[i>>3]&(0x80>>uint(i&7)) != 0
+			out.Write(encodePoints(points, overlap))
+		default:
+			return nil, nil, nil, fmt.Errorf("invalid WOFF2 file: invalid number of contours %d", nContours)
+		}
+
+		for _, s := range streams {
+			if s.err != nil {
+				return nil, nil, nil, s.err
+			}
+		}
+
+		// pad to 4 bytes
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+		offsets[i+1] = uint32(out.Len())
+	}
+
+	glyf = out.Bytes()
+	switch indexFormat {
+	case 0:
+		if len(glyf) > 0x1FFFF {
+			return nil, nil, nil, errors.New("invalid WOFF2 file: glyf table too large for short loca format")
+		}
+		loca = make([]byte, 2*len(offsets))
+		for i, o := range offsets {
+			binary.BigEndian.PutUint16(loca[2*i:], uint16(o/2))
+		}
+	case 1:
+		loca = make([]byte, 4*len(offsets))
+		for i, o := range offsets {
+			binary.BigEndian.PutUint32(loca[4*i:], o)
+		}
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid WOFF2 file: invalid loca index format %d", indexFormat)
+	}
+	return glyf, loca, xMins, nil
+}
+
+type glyfPoint struct {
+	x, y    int16
+	onCurve bool
+}
+
+func withSign(flag uint8, baseval int) int {
+	if flag&1 != 0 {
+		return baseval
+	}
+	return -baseval
+}
+
+// decodeTriplets reads the coordinates of the points,
+// as described in section 5.2 of the specification
+func decodeTriplets(flags []byte, glyphStream *woff2Reader) ([]glyfPoint, error) {
+	out := make([]glyfPoint, len(flags))
+	var x, y int
+	for i, flag := range flags {
+		onCurve := flag>>7 == 0
+		flag &= 0x7F
+		var nBytes int
+		switch {
+		case flag < 84:
+			nBytes = 1
+		case flag < 120:
+			nBytes = 2
+		case flag < 124:
+			nBytes = 3
+		default:
+			nBytes = 4
+		}
+		in := glyphStream.bytes(nBytes)
+		if glyphStream.err != nil {
+			return nil, glyphStream.err
+		}
+		var dx, dy int
+		switch {
+		case flag < 10:
+			dy = withSign(flag, int(flag&14)<<7+int(in[0]))
+		case flag < 20:
+			dx = withSign(flag, int((flag-10)&14)<<7+int(in[0]))
+		case flag < 84:
+			b0, b1 := int(flag-20), int(in[0])
+			dx = withSign(flag, 1+(b0&0x30)+(b1>>4))
+			dy = withSign(flag>>1, 1+(b0&0x0c)<<2+(b1&0x0f))
+		case flag < 120:
+			b0 := int(flag - 84)
+			dx = withSign(flag, 1+(b0/12)<<8+int(in[0]))
+			dy = withSign(flag>>1, 1+((b0%12)>>2)<<8+int(in[1]))
+		case flag < 124:
+			b2 := int(in[1])
+			dx = withSign(flag, int(in[0])<<4+b2>>4)
+			dy = withSign(flag>>1, (b2&0x0f)<<8+int(in[2]))
+		default:
+			dx = withSign(flag, int(in[0])<<8+int(in[1]))
+			dy = withSign(flag>>1, int(in[2])<<8+int(in[3]))
+		}
+		x += dx
+		y += dy
+		out[i] = glyfPoint{x: int16(x), y: int16(y), onCurve: onCurve}
+	}
+	return out, nil
+}
+
+// encodePoints returns the flags and coordinates of a simple glyph,
+// in the glyf table format
+func encodePoints(points []glyfPoint, overlap bool) []byte {
+	var (
+		flags, xs, ys []byte
+		lastX, lastY  int
+		lastFlag      = -1
+		lastFlagIndex int
+		repeat        int
+	)
+	for i, p := range points {
+		var flag uint8
+		if p.onCurve {
+			flag = glyfOnCurve
+		}
+		if i == 0 && overlap {
+			flag |= glyfOverlapSimp
+		}
+
+		dx := int(p.x) - lastX
+		switch {
+		case dx == 0:
+			flag |= glyfXSame
+		case -256 < dx && dx < 256:
+			flag |= glyfXShort
+			if dx > 0 {
+				flag |= glyfXSame
+			} else {
+				dx = -dx
+			}
+			xs = append(xs, uint8(dx))
+		default:
+			xs = append(xs, uint8(uint16(dx)>>8), uint8(dx))
+		}
+
+		dy := int(p.y) - lastY
+		switch {
+		case dy == 0:
+			flag |= glyfYSame
+		case -256 < dy && dy < 256:
+			flag |= glyfYShort
+			if dy > 0 {
+				flag |= glyfYSame
+			} else {
+				dy = -dy
+			}
+			ys = append(ys, uint8(dy))
+		default:
+			ys = append(ys, uint8(uint16(dy)>>8), uint8(dy))
+		}
+
+		if int(flag) == lastFlag && repeat != 255 {
+			flags[lastFlagIndex] |= glyfRepeat
+			repeat++
+		} else {
+			if repeat != 0 {
+				flags = append(flags, uint8(repeat))
+				repeat = 0
+			}
+			lastFlagIndex = len(flags)
+			flags = append(flags, flag)
+		}
+		lastFlag = int(flag)
+		lastX, lastY = int(p.x), int(p.y)
+	}
+	if repeat != 0 {
+		flags = append(flags, uint8(repeat))
+	}
+
+	out := make([]byte, 0, len(flags)+len(xs)+len(ys))
+	out = append(out, flags...)
+	out = append(out, xs...)
+	return append(out, ys...)
+}
+
+// reconstructHmtx applies the inverse of the hmtx transform
+// described in section 5.4 of the specification.
+func reconstructHmtx(data []byte, numHMetrics int, xMins []int16) ([]byte, error) {
+	numGlyphs := len(xMins)
+	if numHMetrics < 1 || numHMetrics > numGlyphs {
+		return nil, errors.New("invalid WOFF2 file: invalid number of horizontal metrics")
+	}
+	r := woff2Reader{data: data}
+	flags := r.u8()
+	if flags&0xFC != 0 {
+		return nil, errors.New("invalid WOFF2 file: invalid hmtx transform flags")
+	}
+
+	advances := make([]uint16, numHMetrics)
+	for i := range advances {
+		advances[i] = r.u16()
+	}
+	lsbs := make([]uint16, numGlyphs)
+	for i := range lsbs {
+		if i < numHMetrics && flags&1 != 0 || i >= numHMetrics && flags&2 != 0 {
+			lsbs[i] = uint16(xMins[i])
+		} else {
+			lsbs[i] = r.u16()
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	out := make([]byte, 0, 4*numHMetrics+2*(numGlyphs-numHMetrics))
+	var tmp [2]byte
+	for i, lsb := range lsbs {
+		if i < numHMetrics {
+			binary.BigEndian.PutUint16(tmp[:], advances[i])
+			out = append(out, tmp[:]...)
+		}
+		binary.BigEndian.PutUint16(tmp[:], lsb)
+		out = append(out, tmp[:]...)
+	}
+	return out, nil
+}
+
+func tableChecksum(data []byte) uint32 {
+	var sum uint32
+	for len(data) >= 4 {
+		sum += binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	if len(data) != 0 {
+		var last [4]byte
+		copy(last[:], data)
+		sum += binary.BigEndian.Uint32(last[:])
+	}
+	return sum
+}
+
+func pad4(n int) int { return (n + 3) &^ 3 }
+
+// writeOffsetTable writes the sfnt header and the table records of `font`,
+// using `offsets` for the position of the tables data
+func writeOffsetTable(dst []byte, tables []woff2Table, font woff2Font, offsets []uint32) []byte {
+	numTables := len(font.tables)
+	entrySelector := 0
+	for 1<<uint(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := 16 << uint(entrySelector)
+
+	var tmp [16]byte
+	binary.BigEndian.PutUint32(tmp[0:], font.flavor)
+	binary.BigEndian.PutUint16(tmp[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(tmp[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(tmp[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(tmp[10:], uint16(numTables*16-searchRange))
+	dst = append(dst, tmp[:12]...)
+
+	// table records must be sorted by tag
+	indices := append([]int(nil), font.tables...)
+	sort.Slice(indices, func(i, j int) bool { return tables[indices[i]].tag < tables[indices[j]].tag })
+	for _, index := range indices {
+		table := tables[index]
+		binary.BigEndian.PutUint32(tmp[0:], uint32(table.tag))
+		binary.BigEndian.PutUint32(tmp[4:], tableChecksum(table.data))
+		binary.BigEndian.PutUint32(tmp[8:], offsets[index])
+		binary.BigEndian.PutUint32(tmp[12:], uint32(len(table.data)))
+		dst = append(dst, tmp[:]...)
+	}
+	return dst
+}
+
+// appendTablesData writes the tables content, each table
+// being aligned on 4 bytes, starting at `start`, and returns the offsets
+func appendTablesData(dst []byte, tables []woff2Table, start int) ([]byte, []uint32) {
+	offsets := make([]uint32, len(tables))
+	for i, table := range tables {
+		offsets[i] = uint32(start + len(dst))
+		dst = append(dst, table.data...)
+		for len(dst)%4 != 0 {
+			dst = append(dst, 0)
+		}
+	}
+	return dst, offsets
+}
+
+func offsetTableSize(font woff2Font) int { return 12 + 16*len(font.tables) }
+
+// clearChecksumAdjustment sets the checksum adjustment field of the 'head' table
+// to zero, since the checksums are recomputed
+func clearChecksumAdjustment(tables []woff2Table) {
+	for i := range tables {
+		if tables[i].tag == tagHead && len(tables[i].data) >= 12 {
+			head := append([]byte(nil), tables[i].data...)
+			binary.BigEndian.PutUint32(head[8:], 0)
+			tables[i].data = head
+		}
+	}
+}
+
+func writeSfnt(tables []woff2Table, font woff2Font) []byte {
+	clearChecksumAdjustment(tables)
+	headerSize := offsetTableSize(font)
+	data, offsets := appendTablesData(nil, tables, headerSize)
+	out := make([]byte, 0, headerSize+len(data))
+	out = writeOffsetTable(out, tables, font, offsets)
+	out = append(out, data...)
+
+	// update the checksum adjustment of the head table
+	for _, index := range font.tables {
+		if tables[index].tag == tagHead && len(tables[index].data) >= 12 {
+			binary.BigEndian.PutUint32(out[offsets[index]+8:], 0xB1B0AFBA-tableChecksum(out))
+		}
+	}
+	return out
+}
+
+func writeCollection(tables []woff2Table, fontsDir []woff2Font, ttcVersion uint32) []byte {
+	clearChecksumAdjustment(tables)
+	headerSize := 12 + 4*len(fontsDir)
+	if ttcVersion == 0x00020000 {
+		headerSize += 12 // empty DSIG fields
+	}
+	fontOffsets := make([]int, len(fontsDir))
+	start := headerSize
+	for i, font := range fontsDir {
+		fontOffsets[i] = start
+		start += offsetTableSize(font)
+	}
+	data, offsets := appendTablesData(nil, tables, start)
+
+	out := make([]byte, headerSize, start+len(data))
+	binary.BigEndian.PutUint32(out[0:], uint32(tagTTCF))
+	binary.BigEndian.PutUint32(out[4:], ttcVersion)
+	binary.BigEndian.PutUint32(out[8:], uint32(len(fontsDir)))
+	for i, offset := range fontOffsets {
+		binary.BigEndian.PutUint32(out[12+4*i:], uint32(offset))
+	}
+	for _, font := range fontsDir {
+		out = writeOffsetTable(out, tables, font, offsets)
+	}
+	return append(out, data...)
+}
@@ -0,0 +1,385 @@
+package fontconfig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+func TestScanWebFonts(t *testing.T) {
+	c := NewConfig()
+	var patterns [2]Pattern
+	for i, file := range []string{"test/fontawesome-webfont.woff", "test/fontawesome-webfont.woff2"} {
+		fs, err := c.ScanFontFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fs) != 1 {
+			t.Fatalf("expected one face in %s, got %d", file, len(fs))
+		}
+		patterns[i] = fs[0]
+	}
+
+	if f := patterns[0].Format(); f != WOFF {
+		t.Fatalf("expected format WOFF, got %s", f)
+	}
+	if f := patterns[1].Format(); f != WOFF2 {
+		t.Fatalf("expected format WOFF2, got %s", f)
+	}
+	for i, wrapper := range []string{"WOFF", "WOFF2"} {
+		if f, _ := patterns[i].GetString(FONTFORMAT); f != string(TrueType) {
+			t.Fatalf("expected font format TrueType, got %s", f)
+		}
+		if w, _ := patterns[i].GetString(FONT_WRAPPER); w != wrapper {
+			t.Fatalf("expected wrapper %s, got %s", wrapper, w)
+		}
+	}
+	if fam, _ := patterns[1].GetString(FAMILY); fam != "FontAwesome" {
+		t.Fatalf("unexpected family %s", fam)
+	}
+
+	// besides the file, both fonts should be described by the same pattern
+	for _, p := range patterns {
+		p.Del(FILE)
+		p.Del(FONT_WRAPPER)
+	}
+	if patterns[0].Hash() != patterns[1].Hash() {
+		t.Fatalf("different patterns:\n%s\n%s", patterns[0], patterns[1])
+	}
+}
+
+func loadTestFace(t *testing.T, file string, format FontFormat) *truetype.Font {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	faces, err := format.Loader()(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return faces[0].(*truetype.Font)
+}
+
+func TestLoadWOFF2(t *testing.T) {
+	// the glyf and loca tables are transformed in the WOFF2 file:
+	// compare the reconstructed glyphs with the WOFF version
+	woff := loadTestFace(t, "test/fontawesome-webfont.woff", WOFF)
+	woff2 := loadTestFace(t, "test/fontawesome-webfont.woff2", WOFF2)
+
+	if woff.NumGlyphs != woff2.NumGlyphs {
+		t.Fatalf("expected %d glyphs, got %d", woff.NumGlyphs, woff2.NumGlyphs)
+	}
+	for gid := fonts.GID(0); int(gid) < woff.NumGlyphs; gid++ {
+		if a1, a2 := woff.HorizontalAdvance(gid), woff2.HorizontalAdvance(gid); a1 != a2 {
+			t.Fatalf("glyph %d: expected advance %g, got %g", gid, a1, a2)
+		}
+		e1, _ := woff.GlyphExtents(gid, 0, 0)
+		e2, _ := woff2.GlyphExtents(gid, 0, 0)
+		if e1 != e2 {
+			t.Fatalf("glyph %d: expected extents %v, got %v", gid, e1, e2)
+		}
+		if d1, d2 := woff.GlyphData(gid, 0, 0), woff2.GlyphData(gid, 0, 0); !reflect.DeepEqual(d1, d2) {
+			t.Fatalf("glyph %d: expected outlines %v, got %v", gid, d1, d2)
+		}
+	}
+}
+
+func TestInvalidWOFF2(t *testing.T) {
+	data, err := os.ReadFile("test/fontawesome-webfont.woff2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []int{0, 10, 48, 100, len(data) / 2, len(data) - 1} {
+		if _, err := decodeWOFF2(data[:l]); err == nil {
+			t.Fatalf("expected error for truncated file (%d bytes)", l)
+		}
+	}
+	if _, err := decodeWOFF2(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// buildTransformedGlyf returns a transformed glyf table
+// with one glyph, made of the given streams
+func buildTransformedGlyf(nContour, glyph, composite, bbox, instruction []byte) []byte {
+	var buf bytes.Buffer
+	for _, v := range []uint16{0, 0, 1, 0} { // reserved, optionFlags, numGlyphs, indexFormat
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	streams := [7][]byte{nContour, nil, nil, glyph, composite, bbox, instruction}
+	for _, stream := range streams {
+		binary.Write(&buf, binary.BigEndian, uint32(len(stream)))
+	}
+	for _, stream := range streams {
+		buf.Write(stream)
+	}
+	return buf.Bytes()
+}
+
+func TestWOFF2TruncatedCompositeGlyph(t *testing.T) {
+	composite := []byte{0xFF, 0xFF}                    // one composite glyph
+	bboxBitmap := []byte{0x80, 0, 0, 0}                // with a bounding box
+	bbox := append(bboxBitmap, 0, 1, 0, 2, 0, 3, 0, 4) // xMin, yMin, xMax, yMax
+	component := []byte{0, 0, 0, 1, 0, 0}              // flags, glyph index, args as bytes
+	withInstructions := []byte{compHaveInstruction >> 8, 0, 0, 1, 0, 0}
+
+	if _, _, _, err := reconstructGlyf(buildTransformedGlyf(composite, nil, component, bbox, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := reconstructGlyf(buildTransformedGlyf(composite, []byte{2}, withInstructions, bbox, []byte{1, 2})); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range [][]byte{
+		buildTransformedGlyf(composite[:1], nil, component, bbox, nil),                    // truncated nContour
+		buildTransformedGlyf(composite, nil, component, bboxBitmap, nil),                  // truncated bbox
+		buildTransformedGlyf(composite, nil, component, bbox[:8], nil),                    // truncated bbox
+		buildTransformedGlyf(composite, nil, component[:3], bbox, nil),                    // truncated composite
+		buildTransformedGlyf(composite, nil, withInstructions, bbox, nil),                 // truncated glyph
+		buildTransformedGlyf(composite, []byte{10}, withInstructions, bbox, []byte{1, 2}), // truncated instructions
+	} {
+		if _, _, _, err := reconstructGlyf(data); err == nil {
+			t.Fatalf("expected error for truncated streams")
+		}
+	}
+}
+
+func TestWOFF2Numbers(t *testing.T) {
+	for _, test := range []struct {
+		data     []byte
+		expected uint32
+	}{
+		{[]byte{0x3F}, 63},
+		{[]byte{0x81, 0x00}, 128},
+		{[]byte{0x8F, 0xFF, 0xFF, 0xFF, 0x7F}, 0xFFFFFFFF},
+	} {
+		r := woff2Reader{data: test.data}
+		if v := r.uintBase128(); r.err != nil || v != test.expected {
+			t.Fatalf("UIntBase128 %v: expected %d, got %d (%v)", test.data, test.expected, v, r.err)
+		}
+	}
+	for _, data := range [][]byte{{0x80, 0x01}, {0x90, 0x80, 0x80, 0x80, 0x00}, {0x81, 0x81, 0x81, 0x81, 0x81, 0x01}} {
+		r := woff2Reader{data: data}
+		if r.uintBase128(); r.err == nil {
+			t.Fatalf("UIntBase128 %v: expected error", data)
+		}
+	}
+
+	for _, test := range []struct {
+		data     []byte
+		expected uint16
+	}{
+		{[]byte{252}, 252},
+		{[]byte{255, 0}, 253},
+		{[]byte{254, 0}, 506},
+		{[]byte{253, 0x12, 0x34}, 0x1234},
+	} {
+		r := woff2Reader{data: test.data}
+		if v := r.read255UInt16(); r.err != nil || v != test.expected {
+			t.Fatalf("255UInt16 %v: expected %d, got %d", test.data, test.expected, v)
+		}
+	}
+}
+
+// woff2Entry is an entry of the table directory of a WOFF2 file
+type woff2Entry struct {
+	raw          []byte // encoded entry
+	tag          truetype.Tag
+	streamLength uint32
+}
+
+// splitWOFF2 returns the table directory and the decompressed stream of a WOFF2 font
+func splitWOFF2(t *testing.T, data []byte) ([]woff2Entry, []byte) {
+	r := woff2Reader{data: data}
+	r.bytes(12) // signature, flavor, length
+	numTables := r.u16()
+	r.bytes(6) // reserved, totalSfntSize
+	compressedSize := r.u32()
+	r.pos = woff2HeaderSize
+
+	entries := make([]woff2Entry, numTables)
+	for i := range entries {
+		start := r.pos
+		flags := r.u8()
+		if index := flags & 0x3F; index == 63 {
+			entries[i].tag = truetype.Tag(r.u32())
+		} else {
+			entries[i].tag = truetype.MustNewTag(woff2KnownTags[index])
+		}
+		version := flags >> 6
+		transformed := version != 0
+		if tag := entries[i].tag; tag == tagGlyf || tag == tagLoca {
+			transformed = version == 0
+		}
+		entries[i].streamLength = r.uintBase128()
+		if transformed {
+			entries[i].streamLength = r.uintBase128()
+		}
+		entries[i].raw = data[start:r.pos]
+	}
+	compressed := r.bytes(int(compressedSize))
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	stream, err := ioutil.ReadAll(brotli.NewReader(bytes.NewReader(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, stream
+}
+
+// buildWOFF2Collection returns a WOFF2 collection, whose fonts use the given tables
+// (there must be less than 253 tables and fonts)
+func buildWOFF2Collection(entries []woff2Entry, stream []byte, fonts [][]int) []byte {
+	var compressed bytes.Buffer
+	w := brotli.NewWriter(&compressed)
+	w.Write(stream)
+	w.Close()
+
+	var dir bytes.Buffer
+	for _, entry := range entries {
+		dir.Write(entry.raw)
+	}
+	binary.Write(&dir, binary.BigEndian, uint32(0x00010000)) // TTC version
+	dir.WriteByte(byte(len(fonts)))
+	for _, font := range fonts {
+		dir.WriteByte(byte(len(font)))
+		binary.Write(&dir, binary.BigEndian, uint32(0x00010000)) // flavor
+		for _, index := range font {
+			dir.WriteByte(byte(index))
+		}
+	}
+
+	length := woff2HeaderSize + dir.Len() + compressed.Len()
+	var out bytes.Buffer
+	for _, v := range []uint32{uint32(signatureWOFF2), uint32(tagTTCF), uint32(length)} {
+		binary.Write(&out, binary.BigEndian, v)
+	}
+	binary.Write(&out, binary.BigEndian, uint16(len(entries)))
+	binary.Write(&out, binary.BigEndian, uint16(0))                // reserved
+	binary.Write(&out, binary.BigEndian, uint32(0))                // totalSfntSize
+	binary.Write(&out, binary.BigEndian, uint32(compressed.Len())) // totalCompressedSize
+	out.Write(make([]byte, woff2HeaderSize-out.Len()))             // versions, metadata and private data
+	out.Write(dir.Bytes())
+	out.Write(compressed.Bytes())
+	return out.Bytes()
+}
+
+// appendBase128 appends `v` encoded as UIntBase128
+func appendBase128(dst []byte, v uint32) []byte {
+	var tmp []byte
+	for {
+		tmp = append([]byte{byte(v & 0x7F)}, tmp...)
+		if v >>= 7; v == 0 {
+			break
+		}
+	}
+	for i := range tmp[:len(tmp)-1] {
+		tmp[i] |= 0x80
+	}
+	return append(dst, tmp...)
+}
+
+// transformHmtx returns the hmtx table transformed (without omitting
+// the left side bearings), and its directory entry
+func transformHmtx(hmtx []byte, numHMetrics int) ([]byte, woff2Entry) {
+	transformed := []byte{0} // flags
+	for i := 0; i < numHMetrics; i++ {
+		transformed = append(transformed, hmtx[4*i:4*i+2]...)
+	}
+	for i := 0; i < numHMetrics; i++ {
+		transformed = append(transformed, hmtx[4*i+2:4*i+4]...)
+	}
+	transformed = append(transformed, hmtx[4*numHMetrics:]...)
+
+	raw := []byte{3 | 1<<6} // hmtx, with transform version 1
+	raw = appendBase128(raw, uint32(len(hmtx)))
+	raw = appendBase128(raw, uint32(len(transformed)))
+	return transformed, woff2Entry{raw: raw, tag: tagHmtx, streamLength: uint32(len(transformed))}
+}
+
+func TestWOFF2CollectionSharedHmtx(t *testing.T) {
+	data, err := os.ReadFile("test/fontawesome-webfont.woff2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, stream := splitWOFF2(t, data)
+	numTables := len(entries)
+	tablesData := make([][]byte, numTables)
+	hmtx, hhea := -1, -1
+	var offset uint32
+	for i, entry := range entries {
+		tablesData[i] = stream[offset : offset+entry.streamLength]
+		offset += entry.streamLength
+		switch entry.tag {
+		case tagHmtx:
+			hmtx = i
+		case tagHhea:
+			hhea = i
+		}
+	}
+	if hmtx == -1 || hhea == -1 {
+		t.Fatal("missing hmtx or hhea table")
+	}
+
+	// the hmtx table of the test file is not transformed
+	numHMetrics := binary.BigEndian.Uint16(tablesData[hhea][34:])
+	tablesData[hmtx], entries[hmtx] = transformHmtx(tablesData[hmtx], int(numHMetrics))
+	// add a copy of the transformed hmtx table, and an hhea table
+	// with a different number of metrics
+	modifiedHhea := append([]byte(nil), tablesData[hhea]...)
+	binary.BigEndian.PutUint16(modifiedHhea[34:], numHMetrics-1)
+	entries = append(entries, entries[hmtx], entries[hhea])
+	tablesData = append(tablesData, tablesData[hmtx], modifiedHhea)
+	stream = bytes.Join(tablesData, nil)
+
+	// fontTables returns the original tables, with the table `replaced` changed to `by`
+	fontTables := func(replaced, by int) []int {
+		out := make([]int, numTables)
+		for i := range out {
+			out[i] = i
+			if i == replaced {
+				out[i] = by
+			}
+		}
+		return out
+	}
+	original := fontTables(-1, -1)
+
+	woff := loadTestFace(t, "test/fontawesome-webfont.woff", WOFF)
+	for _, fontsDir := range [][][]int{
+		{original, original},                    // all tables shared
+		{original, fontTables(hmtx, numTables)}, // glyf shared, but not hmtx
+	} {
+		faces, err := loadWOFF2(bytes.NewReader(buildWOFF2Collection(entries, stream, fontsDir)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(faces) != 2 {
+			t.Fatalf("expected 2 faces, got %d", len(faces))
+		}
+		for _, face := range faces {
+			face := face.(*truetype.Font)
+			for gid := fonts.GID(0); int(gid) < woff.NumGlyphs; gid++ {
+				if a1, a2 := woff.HorizontalAdvance(gid), face.HorizontalAdvance(gid); a1 != a2 {
+					t.Fatalf("glyph %d: expected advance %g, got %g", gid, a1, a2)
+				}
+			}
+		}
+	}
+
+	// the shared transformed hmtx table can't be reconstructed for both fonts
+	collection := buildWOFF2Collection(entries, stream, [][]int{original, fontTables(hhea, numTables+1)})
+	if _, err := decodeWOFF2(collection); err == nil {
+		t.Fatal("expected error for hmtx table shared with different hhea tables")
+	}
+}
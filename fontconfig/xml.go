@@ -13,15 +13,27 @@ import (
 var errOldSyntax = errors.New("element no longer supported")
 
 func (config *Config) parseAndLoadFromMemory(filename string, content io.Reader) error {
+	return config.parseAndLoad(filename, content, nil)
+}
+
+// if `includes` is not nil, <include>, <dir> and <cachedir> elements are supported.
+func (config *Config) parseAndLoad(filename string, content io.Reader, includes *includeContext) error {
 	if debugMode {
 		fmt.Printf("Processing config file from %s", filename)
 	}
 
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("fontconfig: cannot read config file from %s: %s", filename, err)
+	}
+
 	parser := newConfigParser(filename, config)
+	parser.includes = includes
+	parser.data = data
 
-	err := xml.NewDecoder(content).Decode(parser)
+	err = parser.decode(xml.NewDecoder(bytes.NewReader(data)))
 	if err != nil {
-		return fmt.Errorf("cannot process config file from %s: %s", filename, err)
+		return err
 	}
 
 	config.subst = append(config.subst, parser.ruleset)
@@ -83,6 +95,15 @@ const (
 	elementCeil
 	elementRound
 	elementTrunc
+
+	// only supported when following includes
+	elementInclude
+	elementDir
+	elementCacheDir
+	elementRemapDir
+	elementResetDirs
+	elementConfig
+	elementRescan
 )
 
 var elementMap = [...]string{
@@ -135,6 +156,14 @@ var elementMap = [...]string{
 	elementCeil:        "ceil",
 	elementRound:       "round",
 	elementTrunc:       "trunc",
+
+	elementInclude:   "include",
+	elementDir:       "dir",
+	elementCacheDir:  "cachedir",
+	elementRemapDir:  "remap-dir",
+	elementResetDirs: "reset-dirs",
+	elementConfig:    "config",
+	elementRescan:    "rescan",
 }
 
 var elementIgnoreName = [...]string{
@@ -169,6 +198,9 @@ type pStack struct {
 	attr    []xml.Attr
 	values  []vstack // the top of the stack is at the end of the slice
 	element elemTag
+
+	name   string // as found in the file, used in errors
+	offset int64  // offset of the start tag in the file
 }
 
 // kind of the value: sometimes the type is not enough
@@ -215,6 +247,10 @@ type configParser struct {
 	ruleset ruleSet
 
 	pstack []pStack // the top of the stack is at the end of the slice
+
+	data []byte // content of the file, used to locate errors
+
+	includes *includeContext // nil if includes are not supported
 }
 
 func newConfigParser(name string, config *Config) *configParser {
@@ -227,25 +263,32 @@ func newConfigParser(name string, config *Config) *configParser {
 	return &parser
 }
 
-func (parse *configParser) error(format string, args ...interface{}) error {
-	var s string
-	s = fmt.Sprintf(`fontconfig %s: "%s": `, s, parse.name)
-	s += fmt.Sprintf(format+"\n", args...)
-	return errors.New(s)
+func (parser *configParser) decode(d *xml.Decoder) error {
+	for {
+		offset := d.InputOffset()
+		token, err := d.Token()
+		if err != nil {
+			return parser.errorAt(offset, "%s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return parser.parseElement(d, start, offset)
+		}
+	}
 }
 
-func (parser *configParser) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+func (parser *configParser) parseElement(d *xml.Decoder, start xml.StartElement, offset int64) error {
 	// start by handling the new element
-	err := parser.startElement(start.Name, start.Attr)
+	err := parser.startElement(start.Name, start.Attr, offset)
 	if err != nil {
 		return err
 	}
 
 	// then process the inner content: text or kid element
 	for {
+		offset := d.InputOffset()
 		next, err := d.Token()
 		if err != nil {
-			return err
+			return parser.errorAt(offset, "%s", err)
 		}
 		// Token is one of StartElement, EndElement, CharData, Comment, ProcInst, or Directive
 		switch next := next.(type) {
@@ -258,7 +301,7 @@ func (parser *configParser) UnmarshalXML(d *xml.Decoder, start xml.StartElement)
 			return err
 		case xml.StartElement:
 			// new kid: recurse and keep going for other kids or text
-			err := parser.UnmarshalXML(d, next)
+			err := parser.parseElement(d, next, offset)
 			if err != nil {
 				return err
 			}
@@ -302,27 +345,34 @@ func (parser *configParser) createVAndPush() *vstack {
 	return nil
 }
 
-func (parse *configParser) startElement(name xml.Name, attr []xml.Attr) error {
+func (parse *configParser) startElement(name xml.Name, attr []xml.Attr, offset int64) error {
+	element, err := elemFromName(name)
+	// push the element first, so that errors point to it
+	parse.pstackPush(element, name.Local, attr, offset)
+
 	switch name.Local {
-	case "dir", "cachedir", "cache", "include", "config", "remap-dir", "reset-dirs", "rescan":
-		return errOldSyntax
+	case "cache":
+		return parse.wrapError(errOldSyntax)
+	case "dir", "cachedir", "include", "config", "remap-dir", "reset-dirs", "rescan":
+		if parse.includes == nil {
+			return parse.wrapError(errOldSyntax)
+		}
 	}
 
-	element, err := elemFromName(name)
 	if err != nil {
 		return parse.error("start element: %s", err)
 	}
-
-	parse.pstackPush(element, attr)
 	return nil
 }
 
 // push at the end of the slice
-func (parse *configParser) pstackPush(element elemTag, attr []xml.Attr) {
+func (parse *configParser) pstackPush(element elemTag, name string, attr []xml.Attr, offset int64) {
 	new := pStack{
 		element: element,
 		attr:    attr,
 		str:     new(bytes.Buffer),
+		name:    name,
+		offset:  offset,
 	}
 	parse.pstack = append(parse.pstack, new)
 }
@@ -450,9 +500,17 @@ func (parser *configParser) endElement() error {
 		parser.parseUnary(opRound)
 	case elementTrunc:
 		parser.parseUnary(opTrunc)
+	case elementInclude:
+		err = parser.parseInclude()
+	case elementDir, elementRemapDir, elementCacheDir:
+		parser.parseDir(last.element)
+	case elementResetDirs:
+		parser.config.fontDirs = nil
+	case elementRescan:
+		last.values = nil // ignored
 	}
 	if err != nil {
-		return err
+		return parser.wrapError(err)
 	}
 
 	return parser.pstackPop()
@@ -495,6 +553,28 @@ func (parser *configParser) lexBinding(bindingString string) (valueBinding, erro
 	}
 }
 
+// matchTarget returns the target of the enclosing <match> element, without
+// marking its attribute as used, or matchDefault if there is none
+func (parser *configParser) matchTarget() matchKind {
+	for i := len(parser.pstack) - 1; i >= 0; i-- {
+		if parser.pstack[i].element != elementMatch {
+			continue
+		}
+		for _, attr := range parser.pstack[i].attr {
+			if attr.Name.Local == "target" {
+				switch attr.Value {
+				case "font":
+					return MatchResult
+				case "scan":
+					return MatchScan
+				}
+			}
+		}
+		return MatchQuery
+	}
+	return matchDefault
+}
+
 func (parse *configParser) parseMatch() error {
 	var kind matchKind
 	kindName := parse.p().getAttr("target")
@@ -635,14 +715,13 @@ func (parser *configParser) newTest(kind matchKind, qual uint8,
 	object Object, compare opKind, expr *expression) (ruleTest, error) {
 	test := ruleTest{kind: kind, qual: qual, op: opKind(compare), expr: expr}
 
-	o := objects[object.String()]
 	test.object = object
 	var err error
-	if o.typeInfo != nil {
-		err = parser.typecheckExpr(expr, o.typeInfo)
+	if type_ := parser.config.objectType(object); type_ != nil {
+		err = parser.typecheckExpr(expr, type_)
 	}
 	if err != nil {
-		return test, fmt.Errorf("; for object %s", object)
+		return test, parser.error("%s; for object %s", err, object)
 	}
 	return test, nil
 }
@@ -650,11 +729,11 @@ func (parser *configParser) newTest(kind matchKind, qual uint8,
 func (parser *configParser) newEdit(object Object, op opKind, expr *expression, binding valueBinding) (ruleEdit, error) {
 	e := ruleEdit{object: object, op: op, expr: expr, binding: binding}
 	var err error
-	if o := objects[object.String()]; o.typeInfo != nil {
-		err = parser.typecheckExpr(expr, o.typeInfo)
+	if type_ := parser.config.objectType(object); type_ != nil {
+		err = parser.typecheckExpr(expr, type_)
 	}
 	if err != nil {
-		return e, fmt.Errorf("; for object %s", object)
+		return e, parser.error("%s; for object %s", err, object)
 	}
 	return e, err
 }
@@ -822,6 +901,13 @@ func (parser *configParser) parseName() error {
 	if last == nil {
 		return nil
 	}
+	if kind == MatchResult && parser.matchTarget() == MatchQuery {
+		// the name is ignored when applying the rule
+		if err := parser.warning(`<name> tag has target="font" in a <match target="pattern">`); err != nil {
+			return err
+		}
+	}
+
 	s := last.str.String()
 	last.str.Reset()
 	object := parser.config.getRegisterObjectType(s)
@@ -953,7 +1039,7 @@ func (parser *configParser) parseLangSet() error {
 		switch vstack.tag {
 		case vstackString:
 			s := vstack.u.(String)
-			langset.add(string(s))
+			langset.Add(string(s))
 			n++
 		default:
 			return errors.New("invalid element in langset")
@@ -1209,6 +1295,12 @@ func (parser *configParser) popValue() (Value, error) {
 		if i, ok := nameConstant(vstack.u.(String)); ok {
 			value = Int(i)
 		}
+	case vstackMatrix:
+		m, ok := vstack.u.(exprMatrix).constant()
+		if !ok {
+			return nil, parser.error("non constant matrix in pattern element")
+		}
+		value = m
 	default:
 		return nil, parser.error("unknown pattern element %d", vstack.tag)
 	}
@@ -0,0 +1,156 @@
+package fontconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
+)
+
+// Severity indicates if a problem found in an XML configuration
+// prevents it from being loaded.
+type Severity uint8
+
+const (
+	// The configuration is usable, but may not work as expected.
+	// Warnings are only reported as errors in strict mode (see `Config.SetStrict`).
+	SeverityWarning Severity = iota
+	// The configuration is invalid.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("<severity %d>", s)
+	}
+}
+
+// ConfigError describes a problem found when loading an XML configuration.
+// It is the type of the errors returned by `Config.LoadFromMemory` and the other
+// loading methods, and of the warnings returned by `Config.Warnings`.
+type ConfigError struct {
+	Source string // name of the configuration, typically the file path
+
+	// 1-based position of the faulty element in the source,
+	// or 0 if unknown. The column is expressed in runes.
+	Line, Column int
+
+	// path of the faulty element, such as "fontconfig/match/test",
+	// or an empty string if unknown
+	Path string
+
+	Severity Severity
+	Message  string
+
+	err error // underlying error, if any
+}
+
+func (err *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString("fontconfig: ")
+	if err.Severity == SeverityWarning {
+		b.WriteString("warning: ")
+	}
+	b.WriteString(err.Source)
+	if err.Line != 0 {
+		fmt.Fprintf(&b, ":%d:%d", err.Line, err.Column)
+	}
+	if err.Path != "" {
+		fmt.Fprintf(&b, ": <%s>", err.Path)
+	}
+	b.WriteString(": ")
+	b.WriteString(err.Message)
+	return b.String()
+}
+
+// Unwrap returns the underlying error, if any.
+func (err *ConfigError) Unwrap() error { return err.err }
+
+// SetStrict enables or disables the strict mode, which is disabled by default.
+// In strict mode, the warnings found when loading XML configurations (such as
+// a <name> which is ignored when applying a rule) are returned as errors,
+// instead of being collected and reported by `Warnings`.
+func (config *Config) SetStrict(strict bool) { config.strict = strict }
+
+// Warnings returns the problems found when loading the XML configurations,
+// which did not prevent the loading. The returned slice must not be modified.
+func (config *Config) Warnings() []*ConfigError { return config.warnings }
+
+// position returns the 1-based line and column for the given offset in `data`
+func position(data []byte, offset int64) (line, column int) {
+	if offset < 0 || offset > int64(len(data)) {
+		return 0, 0
+	}
+	data = data[:offset]
+	line = bytes.Count(data, []byte{'\n'}) + 1
+	column = utf8.RuneCount(data[bytes.LastIndexByte(data, '\n')+1:]) + 1
+	return line, column
+}
+
+// newError returns an error located at `offset`, with the path of the current element.
+// If `offset` is negative, the offset of the current element is used.
+func (parser *configParser) newError(severity Severity, offset int64, format string, args ...interface{}) *ConfigError {
+	out := &ConfigError{
+		Source:   parser.name,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	names := make([]string, len(parser.pstack))
+	for i, p := range parser.pstack {
+		names[i] = p.name
+	}
+	out.Path = strings.Join(names, "/")
+	if offset < 0 && len(parser.pstack) != 0 {
+		offset = parser.p().offset
+	}
+	if parser.data != nil {
+		out.Line, out.Column = position(parser.data, offset)
+	}
+	return out
+}
+
+// error returns an error located at the current element
+func (parser *configParser) error(format string, args ...interface{}) error {
+	return parser.newError(SeverityError, -1, format, args...)
+}
+
+// errorAt returns an error located at `offset`
+func (parser *configParser) errorAt(offset int64, format string, args ...interface{}) error {
+	return parser.newError(SeverityError, offset, format, args...)
+}
+
+// wrapError locates `err` at the current element,
+// if it is not already a *ConfigError
+func (parser *configParser) wrapError(err error) error {
+	var ce *ConfigError
+	if errors.As(err, &ce) {
+		return err
+	}
+	out := parser.newError(SeverityError, -1, "%s", err)
+	out.err = err
+	return out
+}
+
+// warning records a warning located at the current element, and returns nil,
+// or returns it as an error in strict mode
+func (parser *configParser) warning(format string, args ...interface{}) error {
+	w := parser.newError(SeverityWarning, -1, format, args...)
+	if parser.config.strict {
+		return w
+	}
+	parser.config.warnings = append(parser.config.warnings, w)
+	parser.config.report(diagnostics.Diagnostic{
+		Category: diagnostics.InvalidConfig,
+		Message:  fmt.Sprintf("%d:%d: <%s>: %s", w.Line, w.Column, w.Path, w.Message),
+		File:     w.Source,
+	})
+	return nil
+}
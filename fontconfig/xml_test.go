@@ -2,8 +2,12 @@ package fontconfig
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 // ported from fontconfig/test/test-bz1744377.c: 2000 Keith Packard
@@ -30,3 +34,109 @@ func TestParseConfs(t *testing.T) {
 	}
 	fmt.Println(len(cfg.acceptPatterns), len(cfg.rejectPatterns), len(cfg.subst))
 }
+
+func TestConfigError(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<fontconfig>
+	<match>
+		<test name="family" qual="invalid"><string>Arial</string></test>
+		<edit name="family"><string>Helvetica</string></edit>
+	</match>
+</fontconfig>`
+	err := NewConfig().parseAndLoadFromMemory("custom.conf", strings.NewReader(doc))
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConfigError, got %v", err)
+	}
+	exp := ConfigError{Source: "custom.conf", Line: 4, Column: 3, Path: "fontconfig/match/test",
+		Severity: SeverityError, Message: `invalid test qual "invalid"`}
+	if *ce != exp {
+		t.Fatalf("expected %v, got %v", &exp, ce)
+	}
+
+	// syntax errors
+	err = NewConfig().LoadFromMemory(strings.NewReader("<fontconfig>\n<match></fontconfig>"))
+	if !errors.As(err, &ce) || ce.Line != 2 || ce.Severity != SeverityError {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// old syntax
+	err = NewConfig().LoadFromMemory(strings.NewReader("<fontconfig>\n\t<dir>/usr/share/fonts</dir>\n</fontconfig>"))
+	if !errors.Is(err, errOldSyntax) || !errors.As(err, &ce) || ce.Line != 2 || ce.Column != 2 || ce.Path != "fontconfig/dir" {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// errors in included files
+	err = NewConfig().LoadWithIncludes(fstest.MapFS{
+		"fonts.conf":     confFile(`<include>conf.d</include>`),
+		"conf.d/10.conf": confFile(`<match><test name="family" compare="invalid"><string>a</string></test></match>`),
+	}, "fonts.conf", nil)
+	if !errors.As(err, &ce) || ce.Source != "conf.d/10.conf" || ce.Line != 3 {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestConfigWarnings(t *testing.T) {
+	doc := `<fontconfig>
+	<match target="pattern">
+		<test name="family"><string>A</string></test>
+		<edit name="size"><name target="font">pixelsize</name></edit>
+	</match>
+	<match target="font">
+		<edit name="size"><name target="font">pixelsize</name></edit>
+	</match>
+	<match>
+		<edit name="dpi"><name target="font">dpi</name></edit>
+	</match>
+</fontconfig>`
+
+	config := NewConfig()
+	if err := config.LoadFromMemory(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for _, w := range config.Warnings() {
+		if w.Severity != SeverityWarning {
+			t.Fatalf("unexpected severity for %s", w)
+		}
+		lines = append(lines, w.Line)
+	}
+	if exp := []int{4, 10}; !reflect.DeepEqual(lines, exp) {
+		t.Fatalf("expected warnings at lines %v, got %v (%v)", exp, lines, config.Warnings())
+	}
+	rules := config.subst[0].subst
+	if len(rules[MatchResult]) != 1 || len(rules[MatchQuery]) != 2 {
+		t.Fatalf("unexpected rules %v", config.subst[0].String())
+	}
+
+	config = NewConfig()
+	config.SetStrict(true)
+	err := config.LoadFromMemory(strings.NewReader(doc))
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Severity != SeverityWarning || ce.Line != 4 || ce.Path != "fontconfig/match/edit/name" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(config.Warnings()) != 0 {
+		t.Fatal("unexpected warnings in strict mode")
+	}
+}
+
+func TestConfigErrorsNotWarnings(t *testing.T) {
+	for _, doc := range []string{
+		`<fontconfig><unknown>ignored</unknown></fontconfig>`,
+		`<fontconfig><match target="font" unused="yes"><edit name="embolden"><bool>true</bool></edit></match></fontconfig>`,
+		`<fontconfig><match><test name="family"><string>A</string><string>B</string></test><edit name="size"><int>1</int></edit></match></fontconfig>`,
+		`<fontconfig><match><edit name="embolden" mode="delete"><bool>true</bool></edit></match></fontconfig>`,
+		`<fontconfig><alias><family>A</family><family>B</family><prefer><family>C</family></prefer></alias></fontconfig>`,
+	} {
+		config := NewConfig()
+		err := config.LoadFromMemory(strings.NewReader(doc))
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Severity != SeverityError {
+			t.Fatalf("expected error for %s, got %v", doc, err)
+		}
+		if len(config.Warnings()) != 0 {
+			t.Fatalf("unexpected warnings %v", config.Warnings())
+		}
+	}
+}
@@ -0,0 +1,466 @@
+package fontconfig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// this file implements the serialization of a Config
+// to the XML fontconfig format.
+
+const xmlHeader = `<?xml version="1.0"?>
+<!DOCTYPE fontconfig SYSTEM "urn:fontconfig:fonts.dtd">
+`
+
+var matchKindNames = [matchKindEnd]string{
+	MatchQuery:  "pattern",
+	MatchResult: "font",
+	MatchScan:   "scan",
+}
+
+var qualNames = [...]string{
+	qualAny:      "any",
+	qualAll:      "all",
+	qualFirst:    "first",
+	qualNotFirst: "not_first",
+}
+
+var bindingNames = [...]string{
+	vbWeak:   "weak",
+	vbStrong: "strong",
+	vbSame:   "same",
+}
+
+// element used for the operators
+var opElements = map[opKind]elemTag{
+	opQuest:       elementIf,
+	opOr:          elementOr,
+	opAnd:         elementAnd,
+	opEqual:       elementEq,
+	opNotEqual:    elementNotEq,
+	opLess:        elementLess,
+	opLessEqual:   elementLessEq,
+	opMore:        elementMore,
+	opMoreEqual:   elementMoreEq,
+	opContains:    elementContains,
+	opNotContains: elementNotContains,
+	opPlus:        elementPlus,
+	opMinus:       elementMinus,
+	opTimes:       elementTimes,
+	opDivide:      elementDivide,
+	opNot:         elementNot,
+	opFloor:       elementFloor,
+	opCeil:        elementCeil,
+	opRound:       elementRound,
+	opTrunc:       elementTrunc,
+}
+
+// reverse the given map
+func opNames(ops map[string]opKind) map[opKind]string {
+	out := make(map[opKind]string, len(ops))
+	for name, op := range ops {
+		out[op] = name
+	}
+	return out
+}
+
+// WriteXMLDir writes the configuration in the XML fontconfig format,
+// accepted by `LoadFromDir` and by the C library, in the directory `dir`,
+// which is created if needed.
+// Each rule set is written in its own file, so that loading the directory
+// gives back the same rule sets, in the same order, with their descriptions.
+// The files are named after the position and the base name of the rule sets,
+// like "03-10-autohint.conf", and start with a comment containing the full name.
+// User-defined objects are written with their names.
+// The font selectors (see `AcceptGlob` and `AcceptPattern`), which do not
+// belong to a rule set, are written at the end of the last file.
+func (config *Config) WriteXMLDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	rules := config.subst
+	if len(rules) == 0 && config.hasSelectors() {
+		rules = []ruleSet{{name: "selectfont"}}
+	}
+	width := len(strconv.Itoa(len(rules) - 1))
+	if width < 2 {
+		width = 2
+	}
+	for i, rs := range rules {
+		var buf bytes.Buffer
+		if err := config.writeXML(&buf, rs, i == len(rules)-1); err != nil {
+			return err
+		}
+		name := fmt.Sprintf("%0*d-%s.conf", width, i, ruleSetFileName(rs.name))
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ruleSetFileName returns a file name (without extension)
+// for the rule set `name`, usually a file path
+func ruleSetFileName(name string) string {
+	name = strings.TrimSuffix(filepath.Base(name), ".conf")
+	name = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || '0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "rules"
+	}
+	return name
+}
+
+// writeXML writes one document containing the rules of `rs`,
+// and the font selectors if `selectors` is true
+func (config *Config) writeXML(w io.Writer, rs ruleSet, selectors bool) error {
+	if _, err := io.WriteString(w, xmlHeader); err != nil {
+		return err
+	}
+
+	wr := newXMLWriter(w, config)
+	wr.start(elementFontconfig)
+	wr.writeRuleSet(rs)
+	if selectors {
+		wr.writeSelectfont(config)
+	}
+	wr.end(elementFontconfig)
+
+	if wr.err != nil {
+		return fmt.Errorf("fontconfig: writing XML config: %s", wr.err)
+	}
+	if err := wr.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newXMLWriter(w io.Writer, config *Config) *xmlWriter {
+	wr := xmlWriter{
+		w:           w,
+		enc:         xml.NewEncoder(w),
+		customNames: make(map[Object]string, len(config.customObjects)),
+		compareOps:  opNames(compareOps),
+		modeOps:     opNames(modeOps),
+	}
+	wr.enc.Indent("", "\t")
+	for name, object := range config.customObjects {
+		wr.customNames[object] = name
+	}
+	return &wr
+}
+
+// xmlWriter stores the first error encountered
+type xmlWriter struct {
+	w           io.Writer
+	enc         *xml.Encoder
+	err         error
+	customNames map[Object]string
+
+	compareOps, modeOps map[opKind]string
+}
+
+func (wr *xmlWriter) token(t xml.Token) {
+	if wr.err != nil {
+		return
+	}
+	wr.err = wr.enc.EncodeToken(t)
+}
+
+// attrs are given as (name, value) pairs
+func (wr *xmlWriter) start(element elemTag, attrs ...string) {
+	start := xml.StartElement{Name: xml.Name{Local: element.String()}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: attrs[i]}, Value: attrs[i+1]})
+	}
+	wr.token(start)
+}
+
+func (wr *xmlWriter) end(element elemTag) {
+	wr.token(xml.EndElement{Name: xml.Name{Local: element.String()}})
+}
+
+// writes an element containing only text
+func (wr *xmlWriter) text(element elemTag, text string, attrs ...string) {
+	wr.start(element, attrs...)
+	wr.token(xml.CharData(text))
+	wr.end(element)
+}
+
+func (wr *xmlWriter) objectName(object Object) string {
+	if name, ok := wr.customNames[object]; ok {
+		return name
+	}
+	return object.String()
+}
+
+func (wr *xmlWriter) writeRuleSet(rs ruleSet) {
+	// "--" is not allowed in comments
+	name := strings.ReplaceAll(rs.name, "--", "- -")
+	// comments are not indented by the encoder
+	if wr.err == nil {
+		wr.err = wr.enc.Flush()
+	}
+	if wr.err == nil {
+		_, wr.err = fmt.Fprintf(wr.w, "\n\t<!-- %s -->", name)
+	}
+	if rs.description != "" {
+		var attrs []string
+		if rs.domain != "" {
+			attrs = []string{"domain", rs.domain}
+		}
+		wr.text(elementDescription, rs.description, attrs...)
+	}
+	// the order between the kinds is not relevant
+	for kind, directives := range rs.subst {
+		for _, d := range directives {
+			wr.writeDirective(d, matchKind(kind))
+		}
+	}
+}
+
+func (wr *xmlWriter) writeDirective(d directive, kind matchKind) {
+	wr.start(elementMatch, "target", matchKindNames[kind])
+	for _, test := range d.tests {
+		wr.writeTest(test, kind)
+	}
+	for _, edit := range d.edits {
+		wr.writeEdit(edit)
+	}
+	wr.end(elementMatch)
+}
+
+func (wr *xmlWriter) writeTest(test ruleTest, kind matchKind) {
+	attrs := []string{"name", wr.objectName(test.object)}
+	if test.kind != kind {
+		attrs = append(attrs, "target", matchKindNames[test.kind])
+	}
+	if test.qual != qualAny {
+		attrs = append(attrs, "qual", qualNames[test.qual])
+	}
+	if op := test.op.getOp(); op != opEqual {
+		name, ok := wr.compareOps[op]
+		if !ok && wr.err == nil {
+			wr.err = fmt.Errorf("invalid test operator %s", op)
+		}
+		attrs = append(attrs, "compare", name)
+	}
+	if test.op.getFlags()&opFlagIgnoreBlanks != 0 {
+		attrs = append(attrs, "ignore-blanks", "true")
+	}
+	wr.start(elementTest, attrs...)
+	wr.writeExpr(test.expr)
+	wr.end(elementTest)
+}
+
+func (wr *xmlWriter) writeEdit(edit ruleEdit) {
+	attrs := []string{"name", wr.objectName(edit.object)}
+	if edit.op != opAssign {
+		name, ok := wr.modeOps[edit.op]
+		if !ok && wr.err == nil {
+			wr.err = fmt.Errorf("invalid edit mode %s", edit.op)
+		}
+		attrs = append(attrs, "mode", name)
+	}
+	if edit.binding != vbWeak {
+		attrs = append(attrs, "binding", bindingNames[edit.binding])
+	}
+	wr.start(elementEdit, attrs...)
+	// a list of values is written as consecutive elements
+	expr := edit.expr
+	for ; expr != nil && expr.op == opComma; expr = expr.u.(exprTree).right {
+		wr.writeExpr(expr.u.(exprTree).left)
+	}
+	if expr != nil {
+		wr.writeExpr(expr)
+	}
+	wr.end(elementEdit)
+}
+
+func boolName(b Bool) string {
+	switch b {
+	case False:
+		return "false"
+	case True:
+		return "true"
+	default:
+		return "dontcare"
+	}
+}
+
+func (wr *xmlWriter) writeRange(r Range) {
+	wr.start(elementRange)
+	wr.text(elementDouble, formatFloat(r.Begin))
+	wr.text(elementDouble, formatFloat(r.End))
+	wr.end(elementRange)
+}
+
+func (wr *xmlWriter) writeCharset(cs Charset) {
+	wr.start(elementCharSet)
+	for _, ra := range cs.Ranges() {
+		if ra[0] == ra[1] {
+			wr.text(elementInt, strconv.Itoa(int(ra[0])))
+			continue
+		}
+		wr.start(elementRange)
+		wr.text(elementInt, strconv.Itoa(int(ra[0])))
+		wr.text(elementInt, strconv.Itoa(int(ra[1])))
+		wr.end(elementRange)
+	}
+	wr.end(elementCharSet)
+}
+
+func (wr *xmlWriter) writeLangset(ls Langset) {
+	wr.start(elementLangSet)
+	for _, lang := range ls.Langs() {
+		wr.text(elementString, lang)
+	}
+	wr.end(elementLangSet)
+}
+
+// writeValue writes the values which may be used in a <patelt>
+func (wr *xmlWriter) writeValue(v Value) {
+	switch v := v.(type) {
+	case Int:
+		wr.text(elementInt, strconv.Itoa(int(v)))
+	case Float:
+		wr.text(elementDouble, strconv.FormatFloat(float64(v), 'g', -1, 64))
+	case String:
+		wr.text(elementString, string(v))
+	case Bool:
+		wr.text(elementBool, boolName(v))
+	case Range:
+		wr.writeRange(v)
+	case Charset:
+		wr.writeCharset(v)
+	case Langset:
+		wr.writeLangset(v)
+	case Matrix:
+		wr.start(elementMatrix)
+		for _, f := range [...]float32{v.Xx, v.Xy, v.Yx, v.Yy} {
+			wr.text(elementDouble, strconv.FormatFloat(float64(f), 'g', -1, 32))
+		}
+		wr.end(elementMatrix)
+	default:
+		if wr.err == nil {
+			wr.err = fmt.Errorf("unsupported value %v (%T) in pattern", v, v)
+		}
+	}
+}
+
+func (wr *xmlWriter) writeExpr(e *expression) {
+	if e == nil {
+		return
+	}
+	switch op := e.op.getOp(); op {
+	case opInt, opDouble, opString, opBool, opRange, opCharSet, opLangSet:
+		wr.writeValue(e.u.(Value))
+	case opMatrix:
+		m := e.u.(exprMatrix)
+		wr.start(elementMatrix)
+		wr.writeExpr(m.xx)
+		wr.writeExpr(m.xy)
+		wr.writeExpr(m.yx)
+		wr.writeExpr(m.yy)
+		wr.end(elementMatrix)
+	case opField:
+		name := e.u.(exprName)
+		var attrs []string
+		if name.kind != matchDefault {
+			attrs = []string{"target", matchKindNames[name.kind]}
+		}
+		wr.text(elementName, wr.objectName(name.object), attrs...)
+	case opConst:
+		wr.text(elementConst, string(e.u.(String)))
+	case opNot, opFloor, opCeil, opRound, opTrunc:
+		element := opElements[op]
+		wr.start(element)
+		wr.writeExpr(e.u.(exprTree).left)
+		wr.end(element)
+	default:
+		element, ok := opElements[op]
+		if !ok {
+			if wr.err == nil {
+				wr.err = fmt.Errorf("unsupported expression %s", e.op)
+			}
+			return
+		}
+		// the parser builds right associative trees, so that
+		// op(a, op(b, c)) is written as <op>a b c</op>
+		// (this also handles <if>, stored as quest(cond, quest(then, else)))
+		wr.start(element)
+		for ; e != nil && e.op == op; e = e.u.(exprTree).right {
+			wr.writeExpr(e.u.(exprTree).left)
+		}
+		wr.writeExpr(e)
+		wr.end(element)
+	}
+}
+
+func (wr *xmlWriter) writePatterns(patterns Fontset) {
+	for _, pattern := range patterns {
+		wr.start(elementPattern)
+		for _, object := range pattern.sortedKeys() {
+			wr.start(elementPatelt, "name", wr.objectName(object))
+			// the values of a <patelt> are added in reverse order
+			values := *pattern[object]
+			for i := range values {
+				wr.writeValue(values[len(values)-1-i].Value)
+			}
+			wr.end(elementPatelt)
+		}
+		wr.end(elementPattern)
+	}
+}
+
+func sortedGlobs(globs strSet) []string {
+	out := make([]string, 0, len(globs))
+	for glob := range globs {
+		out = append(out, glob)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (config *Config) hasSelectors() bool {
+	return len(config.acceptGlobs)+len(config.rejectGlobs)+len(config.acceptPatterns)+len(config.rejectPatterns) != 0
+}
+
+func (wr *xmlWriter) writeSelectfont(config *Config) {
+	if !config.hasSelectors() {
+		return
+	}
+	wr.start(elementSelectfont)
+	for _, sel := range [...]struct {
+		element  elemTag
+		globs    strSet
+		patterns Fontset
+	}{
+		{elementAcceptfont, config.acceptGlobs, config.acceptPatterns},
+		{elementRejectfont, config.rejectGlobs, config.rejectPatterns},
+	} {
+		if len(sel.globs)+len(sel.patterns) == 0 {
+			continue
+		}
+		wr.start(sel.element)
+		for _, glob := range sortedGlobs(sel.globs) {
+			wr.text(elementGlob, glob)
+		}
+		wr.writePatterns(sel.patterns)
+		wr.end(sel.element)
+	}
+	wr.end(elementSelectfont)
+}
@@ -0,0 +1,155 @@
+package fontconfig
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteXML(t *testing.T) {
+	config := NewConfig()
+	if err := config.LoadFromDir("confs"); err != nil {
+		t.Fatal(err)
+	}
+	doc := []byte(`<fontconfig>
+		<description domain="fontconfig-conf">custom rules</description>
+		<match target="font">
+			<test name="mycustom" compare="more" target="pattern"><double>1.5</double></test>
+			<edit name="mycustom" mode="append" binding="strong">
+				<if><name target="pattern">antialias</name><plus><name>mycustom</name><int>1</int></plus><double>0.1</double></if>
+				<charset><int>65</int><range><int>97</int><int>122</int></range></charset>
+			</edit>
+			<edit name="matrix"><times><name>matrix</name><matrix><double>1</double><double>0.2</double><double>0</double><double>1</double></matrix></times></edit>
+		</match>
+		<match>
+			<test name="lang" compare="contains"><langset><string>fr</string><string>x-custom</string></langset></test>
+			<test name="family" qual="all" ignore-blanks="true"><string>A &amp; B</string></test>
+			<edit name="size" mode="delete_all" />
+		</match>
+		<selectfont>
+			<acceptfont><glob>/usr/share/fonts/a.ttf</glob></acceptfont>
+			<rejectfont>
+				<pattern><patelt name="scalable"><bool>false</bool></patelt><patelt name="pixelsize"><range><double>8</double><double>12.5</double></range></patelt></pattern>
+			</rejectfont>
+		</selectfont>
+	</fontconfig>`)
+	if err := config.LoadFromMemory(bytes.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := writeAndReload(t, config)
+
+	if len(reloaded.subst) != len(config.subst) {
+		t.Fatalf("expected %d rule sets, got %d", len(config.subst), len(reloaded.subst))
+	}
+	for i, rs := range config.subst {
+		got := reloaded.subst[i]
+		if !reflect.DeepEqual(rs.subst, got.subst) {
+			t.Fatalf("different rules for %s", rs.name)
+		}
+		if got.description != rs.description || got.domain != rs.domain {
+			t.Fatalf("unexpected description %q (%s) for %s", got.description, got.domain, rs.name)
+		}
+	}
+	if rs := reloaded.subst[len(reloaded.subst)-1]; rs.description != "custom rules" || rs.domain != "fontconfig-conf" {
+		t.Fatalf("unexpected description %q (%s)", rs.description, rs.domain)
+	}
+	if _, ok := reloaded.customObjects["mycustom"]; !ok {
+		t.Fatal("missing custom object")
+	}
+	if !reflect.DeepEqual(config.acceptGlobs, reloaded.acceptGlobs) || !reflect.DeepEqual(config.rejectPatterns, reloaded.rejectPatterns) {
+		t.Fatal("different font selectors")
+	}
+}
+
+// writeAndReload writes `config` in a temporary directory and loads it back
+func writeAndReload(t *testing.T, config *Config) *Config {
+	dir := t.TempDir()
+	if err := config.WriteXMLDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewConfig()
+	if err := reloaded.LoadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return reloaded
+}
+
+func TestWriteXMLDescriptions(t *testing.T) {
+	config := NewConfig()
+	for _, file := range [][3]string{
+		{"10-a.conf", "first rules", "test"},
+		{"conf.d/10-a.conf", "second rules", "other"},
+		{"memory", "", ""},
+	} {
+		doc := `<fontconfig><description domain="` + file[2] + `">` + file[1] + `</description>
+			<match><edit name="size"><double>12</double></edit></match></fontconfig>`
+		if err := config.parseAndLoadFromMemory(file[0], strings.NewReader(doc)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := config.WriteXMLDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	if exp := []string{"00-10-a.conf", "01-10-a.conf", "02-memory.conf"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected files %v, got %v", exp, names)
+	}
+
+	reloaded := NewConfig()
+	if err := reloaded.LoadFromDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.subst) != 3 {
+		t.Fatalf("expected 3 rule sets, got %d", len(reloaded.subst))
+	}
+	for i, rs := range config.subst {
+		if got := reloaded.subst[i]; got.description != rs.description || got.domain != rs.domain {
+			t.Fatalf("unexpected description %q (%s)", got.description, got.domain)
+		}
+	}
+}
+
+func TestWriteXMLMatrixPattern(t *testing.T) {
+	config := NewConfig()
+	p := NewPattern()
+	p.Add(MATRIX, Matrix{Xx: 1, Xy: 0.2, Yy: 1}, true)
+	p.AddString(FAMILY, "Oblique")
+	config.RejectPattern(p)
+
+	reloaded := writeAndReload(t, config)
+	if len(reloaded.subst) != 1 || !reloaded.subst[0].isEmpty() {
+		t.Fatalf("unexpected rule sets %v", reloaded.subst)
+	}
+	if !reflect.DeepEqual(config.rejectPatterns, reloaded.rejectPatterns) {
+		t.Fatalf("expected %v, got %v", config.rejectPatterns, reloaded.rejectPatterns)
+	}
+}
+
+func TestWriteXMLStandard(t *testing.T) {
+	reloaded := writeAndReload(t, Standard)
+	if len(reloaded.subst) != len(Standard.subst) {
+		t.Fatalf("expected %d rule sets, got %d", len(Standard.subst), len(reloaded.subst))
+	}
+
+	query := NewPattern()
+	query.AddString(FAMILY, "sans-serif")
+	query.Add(LANG, NewLangset("fr"), true)
+	p1, p2 := query.Duplicate(), query.Duplicate()
+	Standard.Substitute(p1, nil, MatchQuery)
+	reloaded.Substitute(p2, nil, MatchQuery)
+	if p1.Hash() != p2.Hash() {
+		t.Fatalf("expected %s, got %s", p1, p2)
+	}
+}
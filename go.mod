@@ -3,6 +3,7 @@ module github.com/benoitkugler/textprocessing
 go 1.16
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/benoitkugler/textlayout v0.3.1
 	golang.org/x/text v0.3.7
 )
@@ -1,9 +1,9 @@
 package pango
 
 import (
-	"log"
 	"sync"
 
+	"github.com/benoitkugler/textprocessing/diagnostics"
 	"github.com/benoitkugler/textprocessing/fribidi"
 )
 
@@ -247,8 +247,10 @@ func (iterator *attrIterator) advance_attr_iterator_to(start_index int) bool {
 	}
 
 	if start_range > start_index {
-		log.Println("In pango_itemize(), the cached iterator passed in " +
-			"had already moved beyond the start_index")
+		report(nil, diagnostics.Diagnostic{
+			Category: diagnostics.InvalidArgument,
+			Message:  "in Itemize, the cached iterator passed in had already moved beyond the start index",
+		})
 	}
 
 	return true
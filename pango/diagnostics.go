@@ -0,0 +1,29 @@
+package pango
+
+import "github.com/benoitkugler/textprocessing/diagnostics"
+
+var packageDiagnostics diagnostics.Var
+
+// SetDiagnostics sets the sink receiving the problems found by this package,
+// such as the scripts for which no font is found.
+// By default, nothing is reported.
+// A font map may use its own sink instead, by implementing
+// a `Diagnostics() diagnostics.Sink` method returning a non nil value,
+// as fcfonts.FontMap does.
+func SetDiagnostics(sink diagnostics.Sink) { packageDiagnostics.Set(sink) }
+
+// Diagnostics returns the sink set by `SetDiagnostics`, or nil.
+func Diagnostics() diagnostics.Sink { return packageDiagnostics.Get() }
+
+// report sends `d` to the sink of `fontmap` (which may be nil),
+// or to the package sink
+func report(fontmap FontMap, d diagnostics.Diagnostic) {
+	d.Package = "pango"
+	if fm, ok := fontmap.(interface{ Diagnostics() diagnostics.Sink }); ok {
+		if sink := fm.Diagnostics(); sink != nil {
+			sink.Report(d)
+			return
+		}
+	}
+	packageDiagnostics.Report(d)
+}
@@ -0,0 +1,41 @@
+package pango_test
+
+import (
+	"testing"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
+	"github.com/benoitkugler/textprocessing/pango"
+	"github.com/benoitkugler/textprocessing/pango/fcfonts"
+)
+
+type recorder []diagnostics.Diagnostic
+
+func (r *recorder) Report(d diagnostics.Diagnostic) { *r = append(*r, d) }
+
+func TestDiagnostics(t *testing.T) {
+	var pkg, fm recorder
+	pango.SetDiagnostics(&pkg)
+	defer pango.SetDiagnostics(nil)
+
+	pango.NewFontDescriptionFrom("Sans 2000000")
+	if len(pkg) != 1 || pkg[0].Category != diagnostics.InvalidValue || pkg[0].Font != "Sans 2000000" {
+		t.Fatalf("unexpected diagnostics %v", pkg)
+	}
+
+	// a font which can't be loaded
+	pattern := fc.NewPattern()
+	pattern.AddString(fc.FAMILY, "Broken")
+	pattern.AddString(fc.FILE, "testdata/missing.ttf")
+	fontmap := fcfonts.NewFontMap(fc.Standard, fc.Fontset{pattern})
+	fontmap.SetDiagnostics(&fm)
+	context := pango.NewContext(fontmap)
+	desc := pango.NewFontDescriptionFrom("Broken 12")
+	pango.LoadFont(fontmap, context, &desc)
+	if len(fm) == 0 || fm[0].Category != diagnostics.InvalidFont || fm[0].File != "testdata/missing.ttf" {
+		t.Fatalf("unexpected diagnostics %v", fm)
+	}
+	if len(pkg) != 1 {
+		t.Fatalf("unexpected diagnostics %v", pkg)
+	}
+}
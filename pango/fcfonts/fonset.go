@@ -1,8 +1,8 @@
 package fcfonts
 
 import (
-	"log"
-
+	"github.com/benoitkugler/textprocessing/diagnostics"
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
 	"github.com/benoitkugler/textprocessing/pango"
 )
 
@@ -42,7 +42,12 @@ func (fs *Fontset) loadNextFont() *Font {
 
 	font, err := fs.key.fontmap.newFont(*fs.key, fontPattern)
 	if err != nil {
-		log.Println(err)
+		file, _ := fontPattern.GetString(fc.FILE)
+		fs.key.fontmap.report(diagnostics.Diagnostic{
+			Category: diagnostics.InvalidFont,
+			Message:  err.Error(),
+			File:     file,
+		})
 	}
 
 	return font
@@ -7,6 +7,7 @@ import (
 
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 	fc "github.com/benoitkugler/textprocessing/fontconfig"
 	"github.com/benoitkugler/textprocessing/pango"
 )
@@ -110,13 +111,36 @@ type FontMap struct {
 	// a fontconfig scan (or a cache).
 	// This value is initialised at the start and should not be mutated,
 	// to avoid caching misuse.
-	// This is a readonly property, see SetConfig.
+	// This is a readonly property, see SetConfig: in particular,
+	// a database assigned directly is only used for the queries
+	// which are not already cached.
 	Database fc.Fontset
 
+	matcher *fc.Matcher // lazily built from Database, see getMatcher
+
+	diagnostics diagnostics.Sink // optional, see SetDiagnostics
+
 	dpiX, dpiY float32
 	serial     uint
 }
 
+// SetDiagnostics sets the sink receiving the problems found when using the font map,
+// instead of the pango package sink (see pango.SetDiagnostics).
+// Passing nil restores the package sink.
+func (fontmap *FontMap) SetDiagnostics(sink diagnostics.Sink) { fontmap.diagnostics = sink }
+
+// Diagnostics returns the sink set by `SetDiagnostics`, or nil.
+func (fontmap *FontMap) Diagnostics() diagnostics.Sink { return fontmap.diagnostics }
+
+func (fontmap *FontMap) report(d diagnostics.Diagnostic) {
+	d.Package = "pango/fcfonts"
+	if fontmap.diagnostics != nil {
+		fontmap.diagnostics.Report(d)
+	} else if sink := pango.Diagnostics(); sink != nil {
+		sink.Report(d)
+	}
+}
+
 type faceDataKey = fonts.FaceID
 
 // FaceLoader is implemented by client application
@@ -181,10 +205,32 @@ func (fontmap *FontMap) SetFaceLoader(loader FaceLoader) {
 }
 
 // SetConfig updates the config and database, and clears the internal cache.
+// The serial of the font map is incremented, so that the contexts
+// using it are updated.
 func (fontmap *FontMap) SetConfig(config *fc.Config, database fc.Fontset) {
 	fontmap.Config = config
 	fontmap.Database = database
+	fontmap.matcher = nil
 	fontmap.clearCache()
+	fontmap.serial++
+	if fontmap.serial == 0 { // 0 is reserved for invalid serials
+		fontmap.serial++
+	}
+}
+
+// getMatcher returns the indexed version of the database,
+// building it if needed, or if the database has been assigned
+// directly (instead of using SetConfig)
+func (fontmap *FontMap) getMatcher() *fc.Matcher {
+	if fontmap.matcher == nil || !sameFontset(fontmap.matcher.Fontset(), fontmap.Database) {
+		fontmap.matcher = fc.NewMatcher(fontmap.Database)
+	}
+	return fontmap.matcher
+}
+
+// sameFontset returns true if `a` and `b` share the same storage
+func sameFontset(a, b fc.Fontset) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
 }
 
 // Clear all cached information and fontsets for this font map.
@@ -457,7 +503,7 @@ func (fontmap *FontMap) newCachedPattern(pat fc.Pattern) *cachedPattern {
 func (pats *cachedPattern) getFontPattern(i int) (fc.Pattern, bool) {
 	if i == 0 {
 		if pats.match == nil && pats.fontset == nil {
-			pats.match = pats.fontmap.Database.Match(pats.pattern, pats.fontmap.Config)
+			pats.match = pats.fontmap.getMatcher().Match(pats.pattern, pats.fontmap.Config)
 		}
 
 		if pats.match != nil {
@@ -466,10 +512,8 @@ func (pats *cachedPattern) getFontPattern(i int) (fc.Pattern, bool) {
 	}
 
 	if pats.fontset == nil {
-		fonts := pats.fontmap.Database
 		// we actually supports more formats than Harfbuzz, no need to filter
-
-		pats.fontset, _ = fonts.Sort(pats.pattern, true)
+		pats.fontset, _ = pats.fontmap.getMatcher().Sort(pats.pattern, true)
 
 		if pats.match != nil {
 			pats.match = nil
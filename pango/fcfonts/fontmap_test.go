@@ -0,0 +1,33 @@
+package fcfonts
+
+import (
+	"testing"
+
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+func TestMatcherDatabase(t *testing.T) {
+	newFont := func(family string) fc.Pattern {
+		p := fc.NewPattern()
+		p.AddString(fc.FAMILY, family)
+		return p
+	}
+	query := newFont("B")
+
+	fm := NewFontMap(fc.NewConfig(), fc.Fontset{newFont("A")})
+	if m := fm.getMatcher(); m != fm.getMatcher() {
+		t.Fatal("matcher should be cached")
+	}
+
+	// the database is assigned directly
+	fm.Database = fc.Fontset{newFont("A"), newFont("B")}
+	match := fm.getMatcher().Match(query, fm.Config)
+	if family, _ := match.GetString(fc.FAMILY); family != "B" {
+		t.Fatalf("stale matcher: unexpected match %s", match)
+	}
+
+	fm.SetConfig(fm.Config, fc.Fontset{newFont("C")})
+	if set := fm.getMatcher().Fontset(); len(set) != 1 {
+		t.Fatalf("stale matcher: unexpected fontset %v", set)
+	}
+}
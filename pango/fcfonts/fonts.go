@@ -380,24 +380,7 @@ func (font *Font) loadHBFont() error {
 			return nil
 		}
 
-		coords := fvar.GetDesignCoordsDefault(nil)
-
-		if index, ok := key.pattern.GetInt(fc.INDEX); ok && index != 0 {
-			if instance := (index >> 16) - 1; instance >= 0 && int(instance) < len(fvar.Instances) {
-				coords = fvar.Instances[instance].Coords
-			}
-		}
-
-		if variations, ok := key.pattern.GetString(fc.FONT_VARIATIONS); ok {
-			vars := parseVariations(variations)
-			fvar.GetDesignCoords(vars, coords)
-		}
-
-		if key.variations != "" {
-			vars := parseVariations(key.variations)
-			fvar.GetDesignCoords(vars, coords)
-		}
-
+		coords := variationCoords(fvar, key.pattern, key.variations)
 		font.hbFont.SetVarCoordsDesign(coords)
 	}
 
@@ -420,6 +403,29 @@ func (font *Font) isTransformed() bool {
 	return mat != fc.Identity
 }
 
+// variationCoords returns the design coordinates of the instance
+// selected by `pattern`, overridden by the variations
+// resolved by fontconfig (see fc.FONT_VARIATIONS), and then by `variations`
+func variationCoords(fvar truetype.TableFvar, pattern fc.Pattern, variations string) []float32 {
+	coords := fvar.GetDesignCoordsDefault(nil)
+
+	if index, ok := pattern.GetInt(fc.INDEX); ok && index != 0 {
+		if instance := (index >> 16) - 1; instance >= 0 && int(instance) < len(fvar.Instances) {
+			copy(coords, fvar.Instances[instance].Coords)
+		}
+	}
+
+	if resolved, ok := pattern.GetString(fc.FONT_VARIATIONS); ok {
+		fvar.GetDesignCoords(parseVariations(resolved), coords)
+	}
+
+	if variations != "" {
+		fvar.GetDesignCoords(parseVariations(variations), coords)
+	}
+
+	return coords
+}
+
 // len(axes) == len(coords)
 func parseVariations(variations string) (parsedVars []truetype.Variation) {
 	varis := strings.Split(variations, ",")
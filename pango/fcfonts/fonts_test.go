@@ -0,0 +1,50 @@
+package fcfonts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+func TestVariationCoords(t *testing.T) {
+	fvar := truetype.TableFvar{
+		Axis: []truetype.VarAxis{
+			{Tag: truetype.MustNewTag("wght"), Minimum: 100, Default: 400, Maximum: 900},
+			{Tag: truetype.MustNewTag("wdth"), Minimum: 75, Default: 100, Maximum: 100},
+			{Tag: truetype.MustNewTag("slnt"), Minimum: -10, Default: 0, Maximum: 0},
+		},
+		Instances: []truetype.VarInstance{
+			{Coords: []float32{400, 100, 0}},
+			{Coords: []float32{700, 100, 0}},
+		},
+	}
+
+	pattern := fc.NewPattern()
+	if got := variationCoords(fvar, pattern, ""); !reflect.DeepEqual(got, []float32{400, 100, 0}) {
+		t.Fatalf("unexpected default coordinates %v", got)
+	}
+
+	// as resolved by fontconfig
+	pattern.AddString(fc.FONT_VARIATIONS, "slnt=-10,wght=530,wdth=80,opsz=12")
+	if got := variationCoords(fvar, pattern, ""); !reflect.DeepEqual(got, []float32{530, 80, -10}) {
+		t.Fatalf("unexpected coordinates %v", got)
+	}
+
+	// explicit variations have precedence
+	if got := variationCoords(fvar, pattern, "wght=650"); !reflect.DeepEqual(got, []float32{650, 80, -10}) {
+		t.Fatalf("unexpected coordinates %v", got)
+	}
+
+	// named instance
+	pattern = fc.NewPattern()
+	pattern.AddInt(fc.INDEX, 2<<16)
+	pattern.AddString(fc.FONT_VARIATIONS, "wdth=90")
+	if got := variationCoords(fvar, pattern, ""); !reflect.DeepEqual(got, []float32{700, 90, 0}) {
+		t.Fatalf("unexpected coordinates %v", got)
+	}
+	if fvar.Instances[1].Coords[1] != 100 {
+		t.Fatal("instance should not be modified")
+	}
+}
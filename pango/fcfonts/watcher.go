@@ -0,0 +1,234 @@
+package fcfonts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+// FontChanges lists the font files which have changed
+// between two polls of a `Watcher`.
+type FontChanges struct {
+	Added, Removed, Modified []string
+}
+
+// IsEmpty returns true if no file has changed.
+func (c FontChanges) IsEmpty() bool {
+	return len(c.Added)+len(c.Removed)+len(c.Modified) == 0
+}
+
+// watchedFile is the state of a file, as seen on the last poll
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher detects the font files added, removed or modified in
+// a list of directories, and updates the database of a font map accordingly.
+// It does not rely on file system notifications: the directories are
+// walked at each call to `Poll`.
+type Watcher struct {
+	fontmap *FontMap
+	dirs    []string
+	files   map[string]watchedFile // by path
+}
+
+// NewWatcher starts watching `dirs`, which are expected to have been scanned to
+// build the database of `fontmap`. The patterns of the database whose FILE is not found in
+// `dirs` (for instance fonts loaded from memory) are left untouched by the watcher.
+// As `Config.ScanFontDirectories` does, the files rejected by `Config.AcceptFontFile`
+// are ignored.
+// An error is returned if the walk of a directory fails.
+func NewWatcher(fontmap *FontMap, dirs ...string) (*Watcher, error) {
+	w := &Watcher{fontmap: fontmap, dirs: dirs}
+	files, err := w.walk(fontmap.Config)
+	if err != nil {
+		return nil, err
+	}
+	w.files = files
+	return w, nil
+}
+
+// walk returns the current state of the font files in the watched directories
+func (w *Watcher) walk(config *fc.Config) (map[string]watchedFile, error) {
+	files := make(map[string]watchedFile)
+	for _, dir := range w.dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				// as fontconfig does, use the target of the link
+				path, err = filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+				info, err = os.Stat(path)
+				if err != nil {
+					return err
+				}
+			}
+			if info.IsDir() || !config.AcceptFontFile(path) {
+				return nil
+			}
+			files[path] = watchedFile{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Poll walks the watched directories, and if font files have changed
+// since the previous call, scans them with `Config.ScanFontFile` and updates the font map
+// with `FontMap.SetConfig`, using the current configuration.
+// The patterns of the unchanged files are kept, so that only
+// the changed files are scanned.
+// Since `SetConfig` increments the serial of the font map, the layouts using
+// it are updated on their next use.
+// The files which are not valid fonts are reported as diagnostics.
+//
+// Like the other methods of `FontMap`, the update is not safe for concurrent use:
+// see `Run` to poll from a separate goroutine.
+func (w *Watcher) Poll() (FontChanges, error) {
+	config := w.fontmap.Config
+	changes, fonts, err := w.scan(config)
+	if err != nil || changes.IsEmpty() {
+		return changes, err
+	}
+	w.fontmap.SetConfig(config, mergeChanges(w.fontmap.Database, changes, fonts))
+	return changes, nil
+}
+
+// scan performs the walk and the scan of the changed files with `config`,
+// without modifying the font map.
+func (w *Watcher) scan(config *fc.Config) (FontChanges, fc.Fontset, error) {
+	files, err := w.walk(config)
+	if err != nil {
+		return FontChanges{}, nil, err
+	}
+
+	var changes FontChanges
+	for path, file := range files {
+		previous, ok := w.files[path]
+		if !ok {
+			changes.Added = append(changes.Added, path)
+		} else if previous != file {
+			changes.Modified = append(changes.Modified, path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+		}
+	}
+	w.files = files
+
+	if changes.IsEmpty() {
+		return changes, nil, nil
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+
+	return changes, w.scanFiles(config, changes), nil
+}
+
+// scanFiles returns the fonts of the added and modified files
+func (w *Watcher) scanFiles(config *fc.Config, changes FontChanges) fc.Fontset {
+	var out fc.Fontset
+	for _, paths := range [2][]string{changes.Modified, changes.Added} {
+		for _, path := range paths {
+			fonts, err := config.ScanFontFile(path)
+			if err != nil {
+				w.fontmap.report(diagnostics.Diagnostic{
+					Category: diagnostics.InvalidFont,
+					Message:  err.Error(),
+					File:     path,
+				})
+				continue
+			}
+			out = append(out, fonts...)
+		}
+	}
+	return out
+}
+
+// mergeChanges returns a new database, where the patterns of the removed and modified
+// files are replaced by `fonts`, preserving the order of the others.
+func mergeChanges(database fc.Fontset, changes FontChanges, fonts fc.Fontset) fc.Fontset {
+	outdated := make(map[string]bool, len(changes.Removed)+len(changes.Modified))
+	for _, path := range changes.Removed {
+		outdated[path] = true
+	}
+	for _, path := range changes.Modified {
+		outdated[path] = true
+	}
+	var out fc.Fontset
+	for _, pattern := range database {
+		if file, _ := pattern.GetString(fc.FILE); !outdated[file] {
+			out = append(out, pattern)
+		}
+	}
+	return append(out, fonts...)
+}
+
+// Run calls `Poll` every `interval`, until `ctx` is done.
+// The directories are walked and the font files scanned without holding `lock`,
+// which is only held while the configuration of the font map is read, and
+// while its database is updated.
+// Thus, `lock` should also be held by the application when using or modifying the font map
+// (or the pango.Context and layouts created from it).
+// The changes are merged into the current database, so that the updates
+// made by the application in the meantime are preserved. If the configuration
+// has been replaced, the changed files are scanned again with the new one.
+// `onChange`, if not nil, is called after each update.
+// The errors returned when walking the directories are reported as diagnostics,
+// and do not stop the watch.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, lock sync.Locker, onChange func(FontChanges)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		lock.Lock()
+		config := w.fontmap.Config
+		lock.Unlock()
+
+		changes, fonts, err := w.scan(config)
+		if err != nil {
+			w.fontmap.report(diagnostics.Diagnostic{
+				Category: diagnostics.MissingFontDir,
+				Message:  err.Error(),
+			})
+			continue
+		}
+		if changes.IsEmpty() {
+			continue
+		}
+
+		lock.Lock()
+		if current := w.fontmap.Config; current != config { // modified by the application
+			config = current
+			fonts = w.scanFiles(config, changes)
+		}
+		w.fontmap.SetConfig(config, mergeChanges(w.fontmap.Database, changes, fonts))
+		lock.Unlock()
+
+		if onChange != nil {
+			onChange(changes)
+		}
+	}
+}
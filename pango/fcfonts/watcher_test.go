@@ -0,0 +1,187 @@
+package fcfonts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
+	fc "github.com/benoitkugler/textprocessing/fontconfig"
+)
+
+func copyFile(t *testing.T, src, dst string) {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(dst, data, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "../../fontconfig/test/DejaVuSerif-Italic.ttf", filepath.Join(dir, "a.ttf"))
+
+	config := fc.NewConfig()
+	database, err := config.ScanFontDirectories(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fontmap := NewFontMap(config, database)
+	watcher, err := NewWatcher(fontmap, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serial := fontmap.GetSerial()
+	if changes, err := watcher.Poll(); err != nil || !changes.IsEmpty() {
+		t.Fatalf("unexpected changes %v (%v)", changes, err)
+	}
+	if fontmap.GetSerial() != serial {
+		t.Fatal("serial should not change")
+	}
+
+	// add a font
+	copyFile(t, "../../pango/test/weasyprint.otf", filepath.Join(dir, "b.otf"))
+	changes, err := watcher.Poll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (FontChanges{Added: []string{filepath.Join(dir, "b.otf")}}); !reflect.DeepEqual(changes, exp) {
+		t.Fatalf("expected %v, got %v", exp, changes)
+	}
+	if len(fontmap.Database) != 2 || fontmap.GetSerial() == serial {
+		t.Fatalf("unexpected database %v", fontmap.Database)
+	}
+	serial = fontmap.GetSerial()
+
+	// replace the first font
+	copyFile(t, "../../pango/test/weasyprint.otf", filepath.Join(dir, "a.ttf"))
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(filepath.Join(dir, "a.ttf"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err = watcher.Poll(); err != nil || len(changes.Modified) != 1 {
+		t.Fatalf("unexpected changes %v (%v)", changes, err)
+	}
+	for _, pattern := range fontmap.Database {
+		if family, _ := pattern.GetString(fc.FAMILY); family != "Ahem" {
+			t.Fatalf("unexpected family %s", family)
+		}
+	}
+	if fontmap.GetSerial() == serial {
+		t.Fatal("serial should change")
+	}
+
+	// remove a font
+	if err = os.Remove(filepath.Join(dir, "b.otf")); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err = watcher.Poll(); err != nil || len(changes.Removed) != 1 {
+		t.Fatalf("unexpected changes %v (%v)", changes, err)
+	}
+	if len(fontmap.Database) != 1 {
+		t.Fatalf("unexpected database %v", fontmap.Database)
+	}
+}
+
+func TestWatcherSelectors(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, "../../fontconfig/test/DejaVuSerif-Italic.ttf", filepath.Join(dir, "a.ttf"))
+
+	config := fc.NewConfig()
+	config.RejectGlob(filepath.Join(dir, "rejected*"))
+	database, err := config.ScanFontDirectories(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fontmap := NewFontMap(config, database)
+	var reported []diagnostics.Diagnostic
+	fontmap.SetDiagnostics(diagnostics.Func(func(d diagnostics.Diagnostic) { reported = append(reported, d) }))
+	watcher, err := NewWatcher(fontmap, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ignored files
+	copyFile(t, "../../pango/test/weasyprint.otf", filepath.Join(dir, "rejected.otf"))
+	copyFile(t, "../../pango/test/weasyprint.otf", filepath.Join(dir, ".hidden.otf"))
+	for _, metadata := range []string{"fonts.dir", "fonts.scale", "a.afm"} {
+		if err = ioutil.WriteFile(filepath.Join(dir, metadata), []byte("metadata"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changes, err := watcher.Poll(); err != nil || !changes.IsEmpty() {
+		t.Fatalf("unexpected changes %v (%v)", changes, err)
+	}
+
+	// modifying a rejected file has no effect either
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(filepath.Join(dir, "rejected.otf"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := watcher.Poll(); err != nil || !changes.IsEmpty() {
+		t.Fatalf("unexpected changes %v (%v)", changes, err)
+	}
+	if len(fontmap.Database) != 1 || len(reported) != 0 {
+		t.Fatalf("unexpected database %v (diagnostics: %v)", fontmap.Database, reported)
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	config := fc.NewConfig()
+	fontmap := NewFontMap(config, nil)
+	watcher, err := NewWatcher(fontmap, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	updates := make(chan FontChanges, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx, time.Millisecond, &lock, func(c FontChanges) {
+		select {
+		case updates <- c:
+		default:
+		}
+	})
+
+	// the application updates the font map while the watcher is running
+	memoryFonts, err := config.ScanFontFile("../../fontconfig/test/DejaVuSerif-Italic.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	fontmap.SetConfig(fontmap.Config, memoryFonts)
+	lock.Unlock()
+
+	// move the file at once, so that it is not seen partially written
+	tmp := filepath.Join(t.TempDir(), "b.otf")
+	copyFile(t, "../../pango/test/weasyprint.otf", tmp)
+	if err = os.Rename(tmp, filepath.Join(dir, "b.otf")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case changes := <-updates:
+		if len(changes.Added) != 1 {
+			t.Fatalf("unexpected changes %v", changes)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the watcher")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	// the fonts added by the application are preserved
+	if len(fontmap.Database) != 2 || fontmap.Database[0].Hash() != memoryFonts[0].Hash() {
+		t.Fatalf("unexpected database %v", fontmap.Database)
+	}
+}
@@ -2,13 +2,13 @@ package pango
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"unicode"
 
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // contains acceptable strings value
@@ -386,7 +386,11 @@ func NewFontDescriptionFrom(str string) FontDescription {
 		if err != nil {
 			// just ignore invalid floats: they maybe do not refers to a size
 		} else if size < 0 || size > 1000000 {
-			log.Println("invalid size value:", size)
+			report(nil, diagnostics.Diagnostic{
+				Category: diagnostics.InvalidValue,
+				Message:  fmt.Sprintf("invalid size value %g", size),
+				Font:     str,
+			})
 		} else { // word is a valid float
 			desc.Size = int32(size*Scale + 0.5)
 			desc.SizeIsAbsolute = sizeIsAbsolute
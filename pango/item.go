@@ -2,13 +2,13 @@ package pango
 
 import (
 	"container/list"
-	"log"
 	"unicode"
 
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 	"github.com/benoitkugler/textlayout/harfbuzz"
 	"github.com/benoitkugler/textlayout/language"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 	"github.com/benoitkugler/textprocessing/fribidi"
 )
 
@@ -417,8 +417,8 @@ func (context *Context) applyFontScale(items *ItemList) {
 		prev = item
 	}
 
-	if stack.Len() != 0 && debugMode {
-		log.Println("Leftover font scales")
+	if stack.Len() != 0 {
+		report(context.fontMap, diagnostics.Diagnostic{Category: diagnostics.Internal, Message: "leftover font scales"})
 	}
 }
 
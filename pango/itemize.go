@@ -2,8 +2,9 @@ package pango
 
 import (
 	"fmt"
-	"log"
 	"unicode"
+
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // itemizeWithBaseDir is like `Itemize`, but the base direction to use when
@@ -81,7 +82,11 @@ func (state *itemizeState) processRun() {
 		if !ok {
 			// only warn once per fontmap/script pair
 			if shouldWarn(state.context.fontMap, state.script) {
-				log.Printf("failed to choose a font for script %s: expect ugly output", state.script)
+				report(state.context.fontMap, diagnostics.Diagnostic{
+					Category: diagnostics.MissingFont,
+					Message:  fmt.Sprintf("failed to choose a font for script %s: expect ugly output", state.script),
+					Script:   state.script.String(),
+				})
 			}
 		}
 		state.fillFont(font.font)
@@ -3,10 +3,10 @@ package pango
 import (
 	"container/list"
 	"fmt"
-	"log"
 	"math"
 
 	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 	"github.com/benoitkugler/textprocessing/fribidi"
 )
 
@@ -1395,8 +1395,8 @@ func (state *paraBreakState) collectBaselineShift(item, prev *Item) (startXOffse
 					state.baselineShifts.Remove(t)
 					break
 				}
-				if t == nil && debugMode {
-					log.Println("Baseline attributes mismatch")
+				if t == nil {
+					report(nil, diagnostics.Diagnostic{Category: diagnostics.Internal, Message: "baseline attributes mismatch"})
 				}
 			}
 		}
@@ -1,13 +1,14 @@
 package pango
 
 import (
-	"log"
+	"fmt"
 	"strings"
 	"sync"
 	"unicode"
 
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textprocessing/diagnostics"
 )
 
 // These are the default ignorables that we render as hexboxes
@@ -262,12 +263,13 @@ func (glyphs *GlyphString) shapeInternal(paragraphText []rune, itemOffset, itemL
 		glyphs.harfbuzzShape(analysis, paragraphText, itemOffset, itemLength, logAttrs, numChars)
 
 		if len(glyphs.Glyphs) == 0 {
-			if debugMode {
-				// If a font has been correctly chosen, but no glyphs are output,
-				// there's probably something wrong with the font.
-				log.Printf("shaping failure, expect ugly output. font='%s', text='%s' : %v",
-					analysis.Font.Describe(false), string(itemText), itemText)
-			}
+			// If a font has been correctly chosen, but no glyphs are output,
+			// there's probably something wrong with the font.
+			report(analysis.Font.GetFontMap(), diagnostics.Diagnostic{
+				Category: diagnostics.ShapingFailure,
+				Message:  fmt.Sprintf("no glyphs for text '%s' (%v): expect ugly output", string(itemText), itemText),
+				Font:     analysis.Font.Describe(false).String(),
+			})
 		}
 	}
 
@@ -299,7 +301,11 @@ func (glyphs *GlyphString) shapeInternal(paragraphText []rune, itemOffset, itemL
 
 	// Make sure glyphstring direction conforms to analysis.level
 	if lc := glyphs.LogClusters; (analysis.Level&1) != 0 && lc[0] < lc[len(lc)-1] {
-		log.Println("pango: expected RTL run but got LTR. Fixing.")
+		var fontmap FontMap
+		if analysis.Font != nil {
+			fontmap = analysis.Font.GetFontMap()
+		}
+		report(fontmap, diagnostics.Diagnostic{Category: diagnostics.Internal, Message: "expected RTL run but got LTR: fixing"})
 
 		// *Fix* it so we don't crash later
 		glyphs.reverse()